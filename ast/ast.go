@@ -2,7 +2,6 @@ package ast
 
 import (
 	"bytes"
-	"github.com/btrobot/mydsl/token"
 )
 
 // Node 表示 AST 中的节点
@@ -2,6 +2,7 @@ package ast
 
 import (
 	"bytes"
+	"strings"
 	"github.com/btrobot/mydsl/token"
 )
 
@@ -33,6 +34,32 @@ func (ie *IfExpression) String() string {
 	return out.String()
 }
 
+// ConditionalExpression 表示三元条件表达式 cond ? a : b
+type ConditionalExpression struct {
+	Token       token.Token // ? 词法单元
+	Condition   Expression
+	Consequence Expression
+	Alternative Expression
+}
+
+func (ce *ConditionalExpression) expressionNode() {}
+func (ce *ConditionalExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *ConditionalExpression) Position() (int, int) { return ce.Token.Line, ce.Token.Column }
+
+func (ce *ConditionalExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(ce.Condition.String())
+	out.WriteString(" ? ")
+	out.WriteString(ce.Consequence.String())
+	out.WriteString(" : ")
+	out.WriteString(ce.Alternative.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
 // WhileExpression 表示循环表达式
 type WhileExpression struct {
 	Token     token.Token // WHILE 词法单元
@@ -8,8 +8,9 @@ import (
 
 // OpenExpression 表示网页打开操作
 type OpenExpression struct {
-	Token token.Token // OPEN 词法单元
-	URL   Expression  // URL 表达式
+	Token   token.Token // OPEN 词法单元
+	URL     Expression  // URL 表达式，管道形式中为 nil；没有 Options 时其值为哈希则表示选项
+	Options Expression  // 请求选项（方法、请求头、请求体等），可以为 nil
 }
 
 func (oe *OpenExpression) expressionNode() {}
@@ -19,10 +20,14 @@ func (oe *OpenExpression) Position() (int, int) { return oe.Token.Line, oe.Token
 func (oe *OpenExpression) String() string {
 	var out bytes.Buffer
 	
-	out.WriteString("open(")
-	if oe.URL != nil {
-		out.WriteString(oe.URL.String())
+	var args []string
+	for _, arg := range []Expression{oe.URL, oe.Options} {
+		if arg != nil {
+			args = append(args, arg.String())
+		}
 	}
+	out.WriteString("open(")
+	out.WriteString(strings.Join(args, ", "))
 	out.WriteString(")")
 	
 	return out.String()
@@ -45,8 +50,8 @@ func (ee *ExtractExpression) String() string {
 	out.WriteString("extract(")
 	if ee.Source != nil {
 		out.WriteString(ee.Source.String())
+		out.WriteString(", ")
 	}
-	out.WriteString(", ")
 	if ee.Selector != nil {
 		out.WriteString(ee.Selector.String())
 	}
@@ -79,7 +84,9 @@ func (ce *CollectExpression) String() string {
 		for _, sel := range ce.Selectors {
 			selectors = append(selectors, sel.String())
 		}
-		out.WriteString(", ")
+		if ce.Source != nil {
+			out.WriteString(", ")
+		}
 		out.WriteString(strings.Join(selectors, ", "))
 	}
 	
@@ -88,9 +95,32 @@ func (ce *CollectExpression) String() string {
 	return out.String()
 }
 
+// CrawlExpression 表示爬取操作
+type CrawlExpression struct {
+	Token    token.Token // CRAWL 词法单元
+	Seeds    Expression  // 种子 URL 表达式，管道形式中为 nil；没有 Options 时其值为哈希则表示选项
+	Options  Expression  // 选项表达式，可以为 nil
+	Callback Expression  // 处理每个页面的函数
+}
+
+func (ce *CrawlExpression) expressionNode() {}
+func (ce *CrawlExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CrawlExpression) Position() (int, int) { return ce.Token.Line, ce.Token.Column }
+
+func (ce *CrawlExpression) String() string {
+	var args []string
+	for _, arg := range []Expression{ce.Seeds, ce.Options, ce.Callback} {
+		if arg != nil {
+			args = append(args, arg.String())
+		}
+	}
+	return "crawl(" + strings.Join(args, ", ") + ")"
+}
+
 // AtExpression 表示选择器表达式
 type AtExpression struct {
 	Token    token.Token // AT 词法单元
+	Kind     string      // 选择器语言前缀，如 "xpath"；为空表示 CSS
 	Selector Expression  // 选择器表达式
 }
 
@@ -102,6 +132,9 @@ func (ae *AtExpression) String() string {
 	var out bytes.Buffer
 	
 	out.WriteString("@")
+	if ae.Kind != "" {
+		out.WriteString(ae.Kind + ":")
+	}
 	if ae.Selector != nil {
 		out.WriteString(ae.Selector.String())
 	}
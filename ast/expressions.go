@@ -170,6 +170,7 @@ func (ie *IndexExpression) String() string {
 type ObjectLiteral struct {
 	Token token.Token // { 词法单元
 	Pairs map[Expression]Expression
+	Keys  []Expression // Pairs 的键，按源码中出现的顺序
 }
 
 func (ol *ObjectLiteral) expressionNode() {}
@@ -180,8 +181,8 @@ func (ol *ObjectLiteral) String() string {
 	var out bytes.Buffer
 	
 	pairs := []string{}
-	for key, value := range ol.Pairs {
-		pairs = append(pairs, key.String()+": "+value.String())
+	for _, key := range ol.Keys {
+		pairs = append(pairs, key.String()+": "+ol.Pairs[key].String())
 	}
 	
 	out.WriteString("{")
@@ -190,3 +191,50 @@ func (ol *ObjectLiteral) String() string {
 	
 	return out.String()
 }
+
+// AssignExpression 表示赋值表达式，目标可以是标识符、索引或成员访问
+type AssignExpression struct {
+	Token  token.Token // = 词法单元
+	Target Expression
+	Value  Expression
+}
+
+func (ae *AssignExpression) expressionNode() {}
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignExpression) Position() (int, int) { return ae.Token.Line, ae.Token.Column }
+
+func (ae *AssignExpression) String() string {
+	var out bytes.Buffer
+	
+	out.WriteString(ae.Target.String())
+	out.WriteString(" = ")
+	out.WriteString(ae.Value.String())
+	
+	return out.String()
+}
+
+// MemberExpression 表示成员访问表达式 obj.name 或 obj?.name
+type MemberExpression struct {
+	Token    token.Token // . 或 ?. 词法单元
+	Object   Expression
+	Property *Identifier
+	Optional bool // 是否为可选链访问 ?.
+}
+
+func (me *MemberExpression) expressionNode() {}
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MemberExpression) Position() (int, int) { return me.Token.Line, me.Token.Column }
+
+func (me *MemberExpression) String() string {
+	var out bytes.Buffer
+	
+	out.WriteString(me.Object.String())
+	if me.Optional {
+		out.WriteString("?.")
+	} else {
+		out.WriteString(".")
+	}
+	out.WriteString(me.Property.String())
+	
+	return out.String()
+}
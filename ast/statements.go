@@ -97,3 +97,23 @@ func (bs *BlockStatement) String() string {
 	
 	return out.String()
 }
+
+// BreakStatement 表示 break 语句
+type BreakStatement struct {
+	Token token.Token // BREAK 词法单元
+}
+
+func (bs *BreakStatement) statementNode() {}
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BreakStatement) Position() (int, int) { return bs.Token.Line, bs.Token.Column }
+func (bs *BreakStatement) String() string { return "break;" }
+
+// ContinueStatement 表示 continue 语句
+type ContinueStatement struct {
+	Token token.Token // CONTINUE 词法单元
+}
+
+func (cs *ContinueStatement) statementNode() {}
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ContinueStatement) Position() (int, int) { return cs.Token.Line, cs.Token.Column }
+func (cs *ContinueStatement) String() string { return "continue;" }
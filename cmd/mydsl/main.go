@@ -1,55 +1,171 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"io"
 	"os"
-	
+	"os/signal"
+	"time"
+
+	"github.com/btrobot/mydsl/crawler/frontier"
+	"github.com/btrobot/mydsl/errors"
+	"github.com/btrobot/mydsl/eval"
 	"github.com/btrobot/mydsl/internal/debug"
+	"github.com/btrobot/mydsl/lexer"
+	"github.com/btrobot/mydsl/parser"
 )
 
-var (
-	debugMode = flag.Bool("debug", false, "Enable debug mode")
-	version   = flag.Bool("version", false, "Show version information")
+const (
+	VERSION = "0.1.0"
 )
 
+// 退出码
+// 脚本错误的退出码为 errorExitBase + errors.ErrorType：
+// 2 语法错误，3 运行时错误，4 网络错误，5 选择器错误，6 类型错误，7 引用错误
 const (
-	VERSION = "0.1.0"
+	exitOK        = 0
+	exitUsage     = 1 // 参数错误或无法读取文件
+	errorExitBase = 2
 )
 
+// exitCode 返回错误类型对应的退出码
+func exitCode(t errors.ErrorType) int {
+	return errorExitBase + int(t)
+}
+
 func main() {
-	flag.Parse()
-	
+	os.Exit(cli(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// cli 解析命令行参数并运行脚本文件，返回进程退出码
+func cli(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("mydsl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	debugMode := flags.Bool("debug", false, "Enable debug mode")
+	version := flags.Bool("version", false, "Show version information")
+	resume := flags.String("resume", "", "Save crawl state to this file and resume from it (created if missing)")
+	checkpointInterval := flags.Duration("checkpoint-interval", 5*time.Second, "How often crawl state is written to the resume file")
+	cookieFile := flags.String("cookies", "", "Load cookies of the default session from this file and save them back on exit")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
 	if *version {
-		fmt.Printf("MyDSL version %s\n", VERSION)
-		return
+		fmt.Fprintf(stdout, "MyDSL version %s\n", VERSION)
+		return exitOK
 	}
-	
+
 	// 设置调试模式
 	debug.SetDebugMode(*debugMode)
-	
+
 	// 获取输入文件
-	args := flag.Args()
+	args = flags.Args()
 	if len(args) < 1 {
-		fmt.Println("Usage: mydsl [options] <filename>")
-		flag.PrintDefaults()
-		os.Exit(1)
+		fmt.Fprintln(stdout, "Usage: mydsl [options] <filename>")
+		flags.PrintDefaults()
+		return exitUsage
 	}
-	
+
 	filename := args[0]
-	
+
 	// 读取文件内容
 	content, err := os.ReadFile(filename)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(stderr, "Error reading file: %v\n", err)
+		return exitUsage
 	}
-	
-	// 这里将来会添加词法分析、语法分析和解释执行的代码
-	fmt.Printf("Read %d bytes from %s\n", len(content), filename)
-	
+
 	if *debugMode {
 		debug.Print("Debug mode enabled")
-		debug.Print("File content: %s", string(content))
+		debug.Print("Read %d bytes from %s", len(content), filename)
+	}
+
+	// 收到中断信号时取消正在进行的网络请求
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	rt := eval.NewRuntime(ctx, nil)
+
+	// 指定 --resume 时 crawl 的状态写入该文件，重新运行脚本时跳过已完成的 URL
+	if *resume != "" {
+		cp, err := frontier.OpenCheckpoint(*resume, *checkpointInterval)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error opening resume file: %v\n", err)
+			return exitUsage
+		}
+		rt.Checkpoint = cp
 	}
+
+	// 指定 --cookies 时默认会话的 cookie 在运行之间保留，文件不存在时从空白开始
+	jar := rt.Fetcher.Jar()
+	if *cookieFile != "" {
+		if err := jar.Load(*cookieFile); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(stderr, "Error loading cookies: %v\n", err)
+			if rt.Checkpoint != nil {
+				rt.Checkpoint.Close()
+			}
+			return exitUsage
+		}
+	}
+
+	code := run(rt, filename, string(content), args[1:], stderr)
+	stop()
+	if *cookieFile != "" {
+		if err := jar.Save(*cookieFile); err != nil {
+			fmt.Fprintf(stderr, "Error saving cookies: %v\n", err)
+			if code == exitOK {
+				code = exitCode(errors.RuntimeError)
+			}
+		}
+	}
+	if rt.Checkpoint != nil {
+		if err := rt.Checkpoint.Close(); err != nil {
+			fmt.Fprintf(stderr, "Error saving resume file: %v\n", err)
+			if code == exitOK {
+				code = exitCode(errors.RuntimeError)
+			}
+		}
+	}
+	return code
+}
+
+// run 对脚本进行词法分析、语法分析并执行，返回进程退出码
+func run(rt *eval.Runtime, filename, source string, scriptArgs []string, stderr io.Writer) int {
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+
+	// 解析阶段的错误包括语法错误和字面量选择器的错误，退出码取第一个错误的类型
+	if errs := p.Errors(); len(errs) > 0 {
+		for _, e := range errs {
+			reportError(stderr, filename, e)
+		}
+		return exitCode(errs[0].Type)
+	}
+
+	if debug.DebugMode {
+		debug.Print("AST: %s", program.String())
+	}
+
+	env := eval.NewEnvironment()
+	env.SetRuntime(rt)
+	env.Set("$file", &eval.String{Value: filename})
+	argv := make([]eval.Object, len(scriptArgs))
+	for i, a := range scriptArgs {
+		argv[i] = &eval.String{Value: a}
+	}
+	env.Set("$args", &eval.Array{Elements: argv})
+
+	result := eval.Eval(program, env)
+	if errObj, ok := result.(*eval.Error); ok {
+		reportError(stderr, filename, errObj.ToError())
+		return exitCode(errObj.Kind)
+	}
+
+	return exitOK
+}
+
+// reportError 以 file:line:col 格式输出错误
+func reportError(w io.Writer, filename string, e *errors.Error) {
+	fmt.Fprintf(w, "%s:%d:%d: %s: %s\n", filename, e.Line, e.Column, e.TypeString(), e.Message)
 }
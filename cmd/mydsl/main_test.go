@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/btrobot/mydsl/crawler/fetch"
+	"github.com/btrobot/mydsl/eval"
+)
+
+// errorLine 匹配 reportError 输出的 file:line:col: Kind: message 格式
+var errorLine = regexp.MustCompile(`^script\.mydsl:(\d+):(\d+): ([A-Za-z ]+): .+\n$`)
+
+func TestRun(t *testing.T) {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	tests := []struct {
+		name   string
+		source string
+		args   []string
+		code   int
+		kind   string // stderr 中的错误类型，为空表示没有输出
+		pos    string // 错误位置 line:col，为空时不检查
+	}{
+		{"success", `let x = 1; x + 1`, nil, 0, "", ""},
+		{"script arguments", `if (len($args) != 2 || $args[1] != "b") { undefinedName }`, []string{"a", "b"}, 0, "", ""},
+		{"syntax error", "let x = 1;\nlet = 2;", nil, 2, "Syntax Error", "2:5"},
+		{"runtime error", `crawl("not a url", function(p) { p })`, nil, 3, "Runtime Error", ""},
+		{"network error", `open("` + closed.URL + `/")`, nil, 4, "Network Error", ""},
+		{"selector error at parse time", `extract("<a></a>", @"p::bogus")`, nil, 5, "Selector Error", ""},
+		{"selector error at run time", `extract("<a></a>", @"a[")`, nil, 5, "Selector Error", ""},
+		{"type error", `len(1)`, nil, 6, "Type Error", ""},
+		{"reference error", "let a = 1;\nundefinedName", nil, 7, "Reference Error", "2:1"},
+	}
+
+	options := fetch.DefaultOptions()
+	options.IgnoreRobots = true
+	options.MaxRetries = 0
+	for _, tt := range tests {
+		rt := eval.NewRuntime(context.Background(), fetch.NewFetcher(options))
+		var stderr bytes.Buffer
+		code := run(rt, "script.mydsl", tt.source, tt.args, &stderr)
+		if code != tt.code {
+			t.Errorf("%s: exit code = %d, want %d (stderr %q)", tt.name, code, tt.code, stderr.String())
+		}
+
+		if tt.kind == "" {
+			if stderr.Len() != 0 {
+				t.Errorf("%s: unexpected stderr %q", tt.name, stderr.String())
+			}
+			continue
+		}
+		m := errorLine.FindStringSubmatch(stderr.String())
+		if m == nil {
+			t.Errorf("%s: stderr %q is not file:line:col: Kind: message", tt.name, stderr.String())
+			continue
+		}
+		if m[3] != tt.kind {
+			t.Errorf("%s: error kind = %q, want %q", tt.name, m[3], tt.kind)
+		}
+		if pos := m[1] + ":" + m[2]; tt.pos != "" && pos != tt.pos {
+			t.Errorf("%s: error position = %s, want %s", tt.name, pos, tt.pos)
+		}
+	}
+}
+
+// writeScript 把脚本写入临时目录并返回其路径
+func writeScript(t *testing.T, source string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "script.mydsl")
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCLI_Usage(t *testing.T) {
+	script := writeScript(t, `1`)
+
+	tests := []struct {
+		args   []string
+		code   int
+		output string // stdout 或 stderr 中应包含的内容
+	}{
+		{[]string{"--version"}, 0, "MyDSL version " + VERSION},
+		{nil, 1, "Usage: mydsl"},
+		{[]string{"--bogus", script}, 1, "flag provided but not defined"},
+		{[]string{"--checkpoint-interval", "soon", script}, 1, "invalid value"},
+		{[]string{filepath.Join(t.TempDir(), "missing.mydsl")}, 1, "Error reading file"},
+		{[]string{script}, 0, ""},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		code := cli(tt.args, &stdout, &stderr)
+		if code != tt.code {
+			t.Errorf("%q: exit code = %d, want %d", tt.args, code, tt.code)
+		}
+		if output := stdout.String() + stderr.String(); !strings.Contains(output, tt.output) {
+			t.Errorf("%q: output %q does not contain %q", tt.args, output, tt.output)
+		}
+	}
+}
+
+func TestCLI_Resume(t *testing.T) {
+	var mu sync.Mutex
+	hits := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<a href="/a">a</a>`))
+		case "/a":
+			w.Write([]byte(`done`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	script := writeScript(t, `let pages = crawl($args[0], function(p) { p.url }); if (len(pages) != int($args[1])) { undefinedName }`)
+	state := filepath.Join(t.TempDir(), "state.log")
+	args := func(pages string) []string {
+		return []string{"--resume", state, "--checkpoint-interval", "1ms", script, server.URL + "/", pages}
+	}
+
+	var stderr bytes.Buffer
+	if code := cli(args("2"), &bytes.Buffer{}, &stderr); code != 0 {
+		t.Fatalf("first run: exit code = %d, stderr %q", code, stderr.String())
+	}
+	if info, err := os.Stat(state); err != nil || info.Size() == 0 {
+		t.Fatalf("resume file not written: %v", err)
+	}
+
+	// 再次运行时已完成的页面不再抓取
+	if code := cli(args("0"), &bytes.Buffer{}, &stderr); code != 0 {
+		t.Fatalf("resumed run: exit code = %d, stderr %q", code, stderr.String())
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if hits["/"] != 1 || hits["/a"] != 1 {
+		t.Errorf("pages fetched %v, want / and /a once each", hits)
+	}
+
+	// 无法解析的状态文件
+	if err := os.WriteFile(state, []byte("not json\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stderr.Reset()
+	if code := cli(args("0"), &bytes.Buffer{}, &stderr); code != 1 || !strings.Contains(stderr.String(), "Error opening resume file") {
+		t.Errorf("corrupt resume file: exit code = %d, stderr %q", code, stderr.String())
+	}
+}
+
+func TestCLI_Cookies(t *testing.T) {
+	jar := filepath.Join(t.TempDir(), "cookies.json")
+	set := writeScript(t, `set_cookie("https://example.com/", "sid", "abc", {max_age: 3600})`)
+	check := writeScript(t, `if (cookies("https://example.com/")["sid"] != "abc") { undefinedName }`)
+
+	var stderr bytes.Buffer
+	if code := cli([]string{"--cookies", jar, set}, &bytes.Buffer{}, &stderr); code != 0 {
+		t.Fatalf("set run: exit code = %d, stderr %q", code, stderr.String())
+	}
+	// 没有 --cookies 时不读取文件
+	if code := cli([]string{check}, &bytes.Buffer{}, &stderr); code != 7 {
+		t.Errorf("run without --cookies: exit code = %d, want 7", code)
+	}
+	stderr.Reset()
+	if code := cli([]string{"--cookies", jar, check}, &bytes.Buffer{}, &stderr); code != 0 {
+		t.Errorf("run with saved cookies: exit code = %d, stderr %q", code, stderr.String())
+	}
+
+	if err := os.WriteFile(jar, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	stderr.Reset()
+	if code := cli([]string{"--cookies", jar, check}, &bytes.Buffer{}, &stderr); code != 1 || !strings.Contains(stderr.String(), "Error loading cookies") {
+		t.Errorf("corrupt cookie file: exit code = %d, stderr %q", code, stderr.String())
+	}
+}
@@ -0,0 +1,456 @@
+package extract
+
+import (
+	"math"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Article 表示从页面中识别出的正文
+type Article struct {
+	Title       string
+	Byline      string
+	ContentHTML string // 正文片段的 HTML，已移除脚本、导航等无关元素
+	Text        string // 正文的规范化文本
+	Published   string // 发布时间，保持页面中的原始格式
+}
+
+// 参考 Mozilla Readability 的类名/ID 规则
+var (
+	unlikelyCandidates = regexp.MustCompile(`(?i)-ad-|ai2html|banner|breadcrumbs|combx|comment|community|cover-wrap|disqus|extra|footer|gdpr|header|legends|menu|related|remark|replies|rss|shoutbox|sidebar|skyscraper|social|sponsor|supplemental|ad-break|agegate|pagination|pager|popup|yom-remote`)
+	maybeCandidate     = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveWeight     = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|pagination|post|text|blog|story`)
+	negativeWeight     = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|foot|footer|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+	bylineHint         = regexp.MustCompile(`(?i)byline|author|dateline|writtenby|p-author`)
+)
+
+// 评分和输出时整体跳过的元素
+var articleSkipTags = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "iframe": true,
+	"nav": true, "aside": true, "footer": true, "form": true, "button": true,
+	"select": true, "input": true, "textarea": true, "object": true, "embed": true,
+}
+
+// 含有这些子元素的 div 不作为段落评分
+var divBlockTags = map[string]bool{
+	"a": true, "blockquote": true, "dl": true, "div": true, "img": true, "ol": true,
+	"p": true, "pre": true, "table": true, "ul": true, "section": true, "article": true,
+}
+
+const (
+	minParagraphLength = 25 // 参与评分的段落最少字符数
+	minSiblingScore    = 10 // 兄弟节点并入正文的最低分数
+)
+
+// ExtractArticle 使用 Readability 风格的评分算法识别正文
+// 段落按长度和逗号数量计分，分数传递给祖先节点，再按类名权重和链接密度修正；
+// 得分最高的节点及其高分兄弟节点组成正文。
+// 标题、作者和发布时间优先取自 JSON-LD 和 meta 标签。文档树不会被修改
+func ExtractArticle(root *html.Node) *Article {
+	meta := ExtractMetadata(root)
+	a := &Article{
+		Title:     articleTitle(root, meta),
+		Byline:    articleByline(root, meta),
+		Published: articlePublished(root, meta),
+	}
+
+	content := &html.Node{Type: html.ElementNode, Data: "div"}
+	for _, n := range articleNodes(root) {
+		if c := cleanClone(n, true); c != nil {
+			content.AppendChild(c)
+		}
+	}
+
+	var b strings.Builder
+	for c := content.FirstChild; c != nil; c = c.NextSibling {
+		html.Render(&b, c)
+	}
+	a.ContentHTML = b.String()
+	a.Text = InnerText(content)
+	return a
+}
+
+// articleNodes 返回组成正文的节点
+func articleNodes(root *html.Node) []*html.Node {
+	scores := map[*html.Node]float64{}
+	var candidates []*html.Node
+
+	addScore := func(n *html.Node, score float64) {
+		if _, ok := scores[n]; !ok {
+			scores[n] = initialScore(n)
+			candidates = append(candidates, n)
+		}
+		scores[n] += score
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if articleSkipTags[n.Data] || isUnlikely(n) {
+				return
+			}
+			if isParagraph(n) {
+				text := InnerText(n)
+				length := len([]rune(text))
+				if length >= minParagraphLength {
+					score := 1 + float64(strings.Count(text, ",")+strings.Count(text, "，"))
+					score += math.Min(float64(length/100), 3)
+
+					level := 0
+					for p := n.Parent; p != nil && level < 5; p = p.Parent {
+						if p.Type != html.ElementNode || p.Data == "html" {
+							break
+						}
+						divider := 1.0
+						switch {
+						case level == 1:
+							divider = 2
+						case level > 1:
+							divider = float64(level * 3)
+						}
+						addScore(p, score/divider)
+						level++
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+
+	var top *html.Node
+	best := math.Inf(-1)
+	for _, n := range candidates {
+		scores[n] *= 1 - linkDensity(n)
+		if scores[n] > best {
+			top, best = n, scores[n]
+		}
+	}
+	if top == nil {
+		if body := findElement(root, "body"); body != nil {
+			return []*html.Node{body}
+		}
+		return []*html.Node{root}
+	}
+	if top.Parent == nil {
+		return []*html.Node{top}
+	}
+
+	// 合并得分接近的兄弟节点和较长的独立段落
+	threshold := math.Max(minSiblingScore, best*0.2)
+	var nodes []*html.Node
+	for s := top.Parent.FirstChild; s != nil; s = s.NextSibling {
+		if s.Type != html.ElementNode {
+			continue
+		}
+		if s == top {
+			nodes = append(nodes, s)
+			continue
+		}
+		if score, ok := scores[s]; ok && score >= threshold {
+			nodes = append(nodes, s)
+			continue
+		}
+		if s.Data == "p" {
+			text := InnerText(s)
+			density := linkDensity(s)
+			length := len([]rune(text))
+			if (length > 80 && density < 0.25) ||
+				(length > 0 && density == 0 && strings.HasSuffix(text, ".")) {
+				nodes = append(nodes, s)
+			}
+		}
+	}
+	return nodes
+}
+
+// initialScore 按标签和类名权重给出候选节点的初始分数
+func initialScore(n *html.Node) float64 {
+	score := classWeight(n)
+	switch n.Data {
+	case "div", "article", "main":
+		score += 5
+	case "pre", "td", "blockquote":
+		score += 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		score -= 3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score -= 5
+	}
+	return score
+}
+
+func classWeight(n *html.Node) float64 {
+	weight := 0.0
+	for _, name := range []string{"class", "id"} {
+		v, ok := attr(n, name)
+		if !ok || v == "" {
+			continue
+		}
+		if negativeWeight.MatchString(v) {
+			weight -= 25
+		}
+		if positiveWeight.MatchString(v) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// isUnlikely 判断节点是否明显不属于正文，例如侧栏、评论和页脚
+func isUnlikely(n *html.Node) bool {
+	switch n.Data {
+	case "html", "body", "article", "main", "a":
+		return false
+	}
+	class, _ := attr(n, "class")
+	id, _ := attr(n, "id")
+	match := class + " " + id
+	if role, _ := attr(n, "role"); role == "complementary" || role == "navigation" || role == "dialog" {
+		return true
+	}
+	return unlikelyCandidates.MatchString(match) && !maybeCandidate.MatchString(match)
+}
+
+// isParagraph 判断节点是否作为段落参与评分
+func isParagraph(n *html.Node) bool {
+	switch n.Data {
+	case "p", "pre", "td", "blockquote", "section", "h2", "h3", "h4", "h5", "h6":
+		return true
+	case "div":
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && divBlockTags[c.Data] {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// linkDensity 返回链接文本占节点文本的比例
+func linkDensity(n *html.Node) float64 {
+	total := len([]rune(InnerText(n)))
+	if total == 0 {
+		return 0
+	}
+	links := 0
+	var walk func(c *html.Node)
+	walk = func(c *html.Node) {
+		if c.Type == html.ElementNode && c.Data == "a" {
+			links += len([]rune(InnerText(c)))
+			return
+		}
+		for cc := c.FirstChild; cc != nil; cc = cc.NextSibling {
+			walk(cc)
+		}
+	}
+	walk(n)
+	return float64(links) / float64(total)
+}
+
+// cleanClone 复制节点子树，去掉注释、跳过的元素和不太可能属于正文的元素
+// 正文内部的分享栏、链接列表等容器也会被去掉，top 表示正文的顶层节点，不做此检查
+func cleanClone(n *html.Node, top bool) *html.Node {
+	switch n.Type {
+	case html.CommentNode, html.DoctypeNode:
+		return nil
+	case html.ElementNode:
+		if articleSkipTags[n.Data] || isUnlikely(n) || (!top && isClutter(n)) {
+			return nil
+		}
+	}
+
+	clone := &html.Node{
+		Type:      n.Type,
+		DataAtom:  n.DataAtom,
+		Data:      n.Data,
+		Namespace: n.Namespace,
+		Attr:      append([]html.Attribute(nil), n.Attr...),
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if cc := cleanClone(c, false); cc != nil {
+			clone.AppendChild(cc)
+		}
+	}
+	return clone
+}
+
+// isClutter 判断正文中的容器是否为类名权重为负或以链接为主的短内容
+func isClutter(n *html.Node) bool {
+	switch n.Data {
+	case "div", "section", "ul", "ol", "table":
+	default:
+		return false
+	}
+	if classWeight(n) < 0 {
+		return true
+	}
+	return linkDensity(n) > 0.5 && len([]rune(InnerText(n))) < 200
+}
+
+func findElement(n *html.Node, tag string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == tag {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// jsonLDString 返回第一个包含 key 的 JSON-LD 条目中的字符串值
+// 值为对象时取其 name 属性，值为数组时取第一个元素
+func jsonLDString(meta *Metadata, key string) string {
+	for _, item := range meta.JSONLD {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if s := ldValue(obj[key]); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+func ldValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case map[string]interface{}:
+		return ldValue(v["name"])
+	case []interface{}:
+		if len(v) > 0 {
+			return ldValue(v[0])
+		}
+	}
+	return ""
+}
+
+// metaString 按顺序返回第一个非空的 meta 或 OpenGraph 值
+func metaString(meta *Metadata, keys ...string) string {
+	for _, key := range keys {
+		var v interface{}
+		if strings.HasPrefix(key, "og:") {
+			v = meta.OpenGraph[strings.TrimPrefix(key, "og:")]
+		} else if s, ok := meta.Meta[key]; ok {
+			v = s
+		}
+		if s := ldValue(v); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+func articleTitle(root *html.Node, meta *Metadata) string {
+	if title := jsonLDString(meta, "headline"); title != "" {
+		return title
+	}
+	if title := metaString(meta, "og:title", "twitter:title"); title != "" {
+		return title
+	}
+	if t := findElement(root, "title"); t != nil {
+		if title := cleanTitle(InnerText(t)); title != "" {
+			return title
+		}
+	}
+	if h1 := findElement(root, "h1"); h1 != nil {
+		return InnerText(h1)
+	}
+	return ""
+}
+
+// cleanTitle 去掉标题中 "文章标题 | 站点名" 形式的站点名后缀
+// 剩余部分少于三个词时保留完整标题
+func cleanTitle(title string) string {
+	title = strings.Join(strings.Fields(title), " ")
+	for _, sep := range []string{" | ", " - ", " – ", " — ", " » ", " :: "} {
+		if i := strings.LastIndex(title, sep); i > 0 {
+			if head := title[:i]; len(strings.Fields(head)) >= 3 {
+				return head
+			}
+		}
+	}
+	return title
+}
+
+func articleByline(root *html.Node, meta *Metadata) string {
+	if byline := jsonLDString(meta, "author"); byline != "" {
+		return byline
+	}
+	if byline := metaString(meta, "author", "article:author", "dc.creator"); byline != "" &&
+		!strings.Contains(byline, "://") {
+		return byline
+	}
+
+	var found string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if found != "" {
+			return
+		}
+		if n.Type == html.ElementNode {
+			if articleSkipTags[n.Data] {
+				return
+			}
+			rel, _ := attr(n, "rel")
+			itemprop, _ := attr(n, "itemprop")
+			class, _ := attr(n, "class")
+			id, _ := attr(n, "id")
+			if rel == "author" || containsWord(itemprop, "author") || bylineHint.MatchString(class+" "+id) {
+				if text := InnerText(n); text != "" && len([]rune(text)) < 100 {
+					found = text
+					return
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+	return found
+}
+
+func articlePublished(root *html.Node, meta *Metadata) string {
+	if published := jsonLDString(meta, "datePublished"); published != "" {
+		return published
+	}
+	if published := metaString(meta, "article:published_time", "og:published_time", "datepublished",
+		"date", "pubdate", "publishdate", "publish-date", "dc.date.issued", "dc.date"); published != "" {
+		return published
+	}
+
+	var found string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if found != "" {
+			return
+		}
+		if n.Type == html.ElementNode {
+			if itemprop, _ := attr(n, "itemprop"); containsWord(itemprop, "datePublished") {
+				found = microdataValue(n)
+				return
+			}
+			if n.Data == "time" {
+				if v, ok := attr(n, "datetime"); ok && strings.TrimSpace(v) != "" {
+					found = strings.TrimSpace(v)
+					return
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+	return found
+}
@@ -0,0 +1,119 @@
+package extract
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+const articleHTML = `<html><head><title>Rivers rise across the valley | Daily News</title>
+<meta name="author" content="Jane Doe">
+<meta property="article:published_time" content="2024-05-01T08:00:00Z">
+</head><body>
+<header class="site-header"><nav><a href="/">Home</a> <a href="/world">World</a></nav></header>
+<div id="sidebar" class="sidebar"><p>Subscribe to our newsletter, get the best stories, every single day.</p>
+<ul><li><a href="/1">Other story one</a></li><li><a href="/2">Other story two</a></li></ul></div>
+<div class="article-content">
+<h1>Rivers rise</h1>
+<p>Heavy rain over the weekend pushed rivers across the valley to their highest levels in a decade, officials said on Monday.</p>
+<p>Residents in low-lying areas were told to move to higher ground, and several roads were closed as water spilled over the banks.</p>
+<script>trackPageView();</script>
+<p>Forecasters expect the rain to ease by Wednesday, although more storms are possible later in the week.</p>
+<div class="share-tools"><a href="/share">Share this story on social media</a></div>
+</div>
+<div class="comments"><p>Great article, thanks for writing it, really informative and well written.</p></div>
+<footer><p>Copyright 2024 Daily News, all rights reserved, no reproduction.</p></footer>
+</body></html>`
+
+func TestExtractArticle(t *testing.T) {
+	doc, err := Parse(articleHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := ExtractArticle(doc.Root)
+
+	if a.Title != "Rivers rise across the valley" {
+		t.Errorf("Title = %q", a.Title)
+	}
+	if a.Byline != "Jane Doe" {
+		t.Errorf("Byline = %q", a.Byline)
+	}
+	if a.Published != "2024-05-01T08:00:00Z" {
+		t.Errorf("Published = %q", a.Published)
+	}
+
+	for _, want := range []string{"Heavy rain over the weekend", "Residents in low-lying areas", "Forecasters expect"} {
+		if !strings.Contains(a.Text, want) {
+			t.Errorf("Text missing %q:\n%s", want, a.Text)
+		}
+	}
+	for _, unwanted := range []string{"newsletter", "Great article", "Copyright", "Home", "trackPageView", "Share this story"} {
+		if strings.Contains(a.Text, unwanted) || strings.Contains(a.ContentHTML, unwanted) {
+			t.Errorf("content should not contain %q:\n%s", unwanted, a.ContentHTML)
+		}
+	}
+	if !strings.HasPrefix(a.ContentHTML, `<div class="article-content">`) {
+		t.Errorf("ContentHTML = %q", a.ContentHTML)
+	}
+
+	// 文档树不应被修改
+	var b strings.Builder
+	html.Render(&b, doc.Root)
+	if !strings.Contains(b.String(), "trackPageView") {
+		t.Errorf("ExtractArticle modified the document tree")
+	}
+}
+
+func TestExtractArticle_Metadata(t *testing.T) {
+	doc, err := Parse(`<html><head><title>Short | Site</title>
+<script type="application/ld+json">{"@type": "NewsArticle", "headline": "From JSON-LD",
+ "author": [{"@type": "Person", "name": "Ann Lee"}], "datePublished": "2024-01-02"}</script>
+</head><body><article><p>Some article text that is long enough to be scored as content.</p></article></body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := ExtractArticle(doc.Root)
+	if a.Title != "From JSON-LD" || a.Byline != "Ann Lee" || a.Published != "2024-01-02" {
+		t.Errorf("got title=%q byline=%q published=%q", a.Title, a.Byline, a.Published)
+	}
+	if a.Text != "Some article text that is long enough to be scored as content." {
+		t.Errorf("Text = %q", a.Text)
+	}
+}
+
+func TestExtractArticle_DOMFallbacks(t *testing.T) {
+	doc, err := Parse(`<html><head><title>Short | Site</title></head><body>
+<div class="post"><h1>Heading</h1><p class="byline">By Sam Roe</p><time datetime="2023-12-24">Dec 24</time>
+<p>First paragraph of the post, with enough words to be counted by the scorer.</p></div></body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := ExtractArticle(doc.Root)
+	if a.Title != "Short | Site" {
+		t.Errorf("Title = %q", a.Title)
+	}
+	if a.Byline != "By Sam Roe" {
+		t.Errorf("Byline = %q", a.Byline)
+	}
+	if a.Published != "2023-12-24" {
+		t.Errorf("Published = %q", a.Published)
+	}
+}
+
+func TestCleanTitle(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"A long article title | Site", "A long article title"},
+		{"A long article title - Section - Site", "A long article title - Section"},
+		{"Short - Site", "Short - Site"},
+		{"  No   separator  ", "No separator"},
+	}
+	for _, tt := range tests {
+		if got := cleanTitle(tt.input); got != tt.expected {
+			t.Errorf("cleanTitle(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package extract
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+)
+
+// Document 表示解析后的 HTML 文档
+// 按标签、id 和 class 的索引在第一次查询时构建，之后的查询复用同一棵节点树
+type Document struct {
+	Root *html.Node
+	URL  string // 文档地址，用于解析相对链接，可以为空
+
+	indexOnce sync.Once
+	order     map[*html.Node]int // 节点的文档顺序
+	byTag     map[string][]*html.Node
+	byID      map[string][]*html.Node
+	byClass   map[string][]*html.Node
+}
+
+// Parse 解析 HTML 并返回文档
+func Parse(htmlContent string) (*Document, error) {
+	root, err := html.Parse(strings.NewReader(htmlContent))
+	if err != nil {
+		return nil, err
+	}
+	return NewDocument(root), nil
+}
+
+// NewDocument 使用已解析的节点树创建文档
+func NewDocument(root *html.Node) *Document {
+	return &Document{Root: root}
+}
+
+// BaseURL 返回解析相对链接所用的基准地址
+// 文档中的第一个 <base href> 相对于 URL 解析后优先使用；两者都无法构成绝对地址时返回 nil
+func (d *Document) BaseURL() *url.URL {
+	var base *url.URL
+	if d.URL != "" {
+		if u, err := url.Parse(d.URL); err == nil {
+			base = u
+		}
+	}
+	for _, n := range d.ElementsByTag("base") {
+		href, ok := attr(n, "href")
+		if !ok {
+			continue
+		}
+		ref, err := url.Parse(strings.TrimSpace(href))
+		if err != nil {
+			break
+		}
+		if base != nil {
+			ref = base.ResolveReference(ref)
+		}
+		base = ref
+		break
+	}
+	if base == nil || !base.IsAbs() {
+		return nil
+	}
+	return base
+}
+
+// Extract 使用选择器从已解析的文档中提取数据，选择器无效时返回 *SelectorError
+func Extract(doc *Document, selector string) ([]*Result, error) {
+	sel, err := compileSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	return doc.resultsFor(doc.Select(sel)), nil
+}
+
+// resultsFor 将节点转换为提取结果
+func (d *Document) resultsFor(nodes []*html.Node) []*Result {
+	results := make([]*Result, 0, len(nodes))
+	for _, node := range nodes {
+		results = append(results, nodeToResult(d, node))
+	}
+	return results
+}
+
+// Select 按文档顺序返回文档中匹配选择器的节点
+// CSS 选择器最右侧的复合选择器含有 id、class 或标签名时从索引中取候选元素
+func (d *Document) Select(sel nodeSelector) []*html.Node {
+	switch sel := sel.(type) {
+	case *Selector:
+		if candidates, ok := d.candidates(sel); ok {
+			var matches []*html.Node
+			for _, n := range candidates {
+				if sel.Match(n) {
+					matches = append(matches, n)
+				}
+			}
+			return matches
+		}
+	case *XPath:
+		d.buildIndex()
+		return sel.selectIn(d.Root, &docOrder{root: d.Root, index: d.order})
+	}
+	return sel.Select(d.Root)
+}
+
+// SelectIn 以 scope 为作用域返回匹配选择器的节点
+// CSS 选择器只匹配 scope 的后代，XPath 表达式以 scope 为上下文节点
+func (d *Document) SelectIn(sel nodeSelector, scope *html.Node) []*html.Node {
+	if scope == d.Root {
+		return d.Select(sel)
+	}
+	if x, ok := sel.(*XPath); ok && d.contains(scope) {
+		d.buildIndex()
+		return x.selectIn(scope, &docOrder{root: d.Root, index: d.order})
+	}
+	return sel.Select(scope)
+}
+
+// contains 判断节点是否属于文档
+func (d *Document) contains(n *html.Node) bool {
+	d.buildIndex()
+	_, ok := d.order[n]
+	return ok
+}
+
+// ElementsByTag 返回指定标签名的所有元素
+func (d *Document) ElementsByTag(tag string) []*html.Node {
+	d.buildIndex()
+	return d.byTag[strings.ToLower(tag)]
+}
+
+// ElementsByID 返回 id 为指定值的所有元素
+func (d *Document) ElementsByID(id string) []*html.Node {
+	d.buildIndex()
+	return d.byID[id]
+}
+
+// ElementsByClass 返回 class 中包含指定类名的所有元素
+func (d *Document) ElementsByClass(class string) []*html.Node {
+	d.buildIndex()
+	return d.byClass[class]
+}
+
+// buildIndex 遍历一次节点树，记录文档顺序并建立索引
+func (d *Document) buildIndex() {
+	d.indexOnce.Do(func() {
+		d.order = make(map[*html.Node]int)
+		d.byTag = make(map[string][]*html.Node)
+		d.byID = make(map[string][]*html.Node)
+		d.byClass = make(map[string][]*html.Node)
+
+		var walk func(*html.Node)
+		walk = func(n *html.Node) {
+			d.order[n] = len(d.order)
+			if n.Type == html.ElementNode {
+				// SVG 中的 clipPath 等标签保留大小写，索引与选择器一样不区分大小写
+				tag := strings.ToLower(n.Data)
+				d.byTag[tag] = append(d.byTag[tag], n)
+				if id, ok := attr(n, "id"); ok {
+					d.byID[id] = append(d.byID[id], n)
+				}
+				if class, ok := attr(n, "class"); ok {
+					for _, c := range uniqueFields(class) {
+						d.byClass[c] = append(d.byClass[c], n)
+					}
+				}
+			}
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				walk(c)
+			}
+		}
+		walk(d.Root)
+	})
+}
+
+// candidates 返回可能匹配选择器的元素，按文档顺序排列且无重复
+// 选择器组中任一选择器无法使用索引时返回 false
+func (d *Document) candidates(sel *Selector) ([]*html.Node, bool) {
+	d.buildIndex()
+
+	var all []*html.Node
+	for _, c := range sel.selectors {
+		last := c.compounds[len(c.compounds)-1]
+		switch {
+		case last.id != "":
+			all = append(all, d.byID[last.id]...)
+		case len(last.classes) > 0:
+			all = append(all, d.byClass[last.classes[0]]...)
+		case last.tag != "":
+			all = append(all, d.byTag[last.tag]...)
+		default:
+			return nil, false
+		}
+	}
+
+	if len(sel.selectors) > 1 {
+		sort.Slice(all, func(i, j int) bool { return d.order[all[i]] < d.order[all[j]] })
+		unique := all[:0]
+		for i, n := range all {
+			if i == 0 || n != all[i-1] {
+				unique = append(unique, n)
+			}
+		}
+		all = unique
+	}
+	return all, true
+}
+
+// uniqueFields 返回以空白分隔的不重复字段
+func uniqueFields(s string) []string {
+	fields := strings.Fields(s)
+	out := fields[:0]
+	seen := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		if !seen[f] {
+			seen[f] = true
+			out = append(out, f)
+		}
+	}
+	return out
+}
@@ -0,0 +1,194 @@
+package extract
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDocument_SelectUsesIndex(t *testing.T) {
+	doc, err := Parse(selectorHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	selectors := []string{
+		"p", "#main", ".intro", "p.intro.last", "div.container > p", "#p1 ~ p",
+		"li:nth-child(odd)", "p, span", "#l3, li:first-child, .intro", "#missing",
+		"*", "#main > :first-of-type", "a[href^='http']", ":root",
+	}
+
+	for _, s := range selectors {
+		sel := MustCompile(s)
+		indexed := doc.Select(sel)
+		walked := sel.Select(doc.Root)
+
+		if len(indexed) != len(walked) {
+			t.Errorf("selector %q: indexed %d nodes, walked %d", s, len(indexed), len(walked))
+			continue
+		}
+		for i := range indexed {
+			if indexed[i] != walked[i] {
+				t.Errorf("selector %q: node %d differs between index and walk", s, i)
+			}
+		}
+	}
+}
+
+func TestDocument_Indexes(t *testing.T) {
+	doc, err := Parse(`<div id="a" class="x y x"><p class="y">1</p><P id="a">2</P></div>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(doc.ElementsByTag("P")); got != 2 {
+		t.Errorf("ElementsByTag(P) = %d, want 2", got)
+	}
+	if got := len(doc.ElementsByID("a")); got != 2 {
+		t.Errorf("ElementsByID(a) = %d, want 2", got)
+	}
+	if got := len(doc.ElementsByClass("x")); got != 1 {
+		t.Errorf("ElementsByClass(x) = %d, want 1", got)
+	}
+	if got := len(doc.ElementsByClass("y")); got != 2 {
+		t.Errorf("ElementsByClass(y) = %d, want 2", got)
+	}
+}
+
+func TestExtract_CamelCaseSVGTags(t *testing.T) {
+	doc, err := Parse(`<svg><defs><clipPath id="c"><rect/></clipPath><linearGradient/></defs></svg>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		selector string
+		want     int
+	}{
+		{"clipPath", 1},
+		{"clippath", 1},
+		{"svg clipPath", 1},
+		{"defs > *", 2},
+		{"clipPath, linearGradient", 2},
+		{"xpath://*[local-name()='clipPath']", 1},
+	}
+	for _, tt := range tests {
+		results, err := Extract(doc, tt.selector)
+		if err != nil {
+			t.Errorf("Extract(%q) returned error: %v", tt.selector, err)
+			continue
+		}
+		if len(results) != tt.want {
+			t.Errorf("Extract(%q) = %d results, want %d", tt.selector, len(results), tt.want)
+		}
+	}
+}
+
+func TestExtract_Document(t *testing.T) {
+	doc, err := Parse(xpathHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 同一文档上的多次提取复用同一棵节点树
+	for _, tt := range []struct {
+		selector string
+		count    int
+	}{
+		{"tr", 3},
+		{"div p", 2},
+		{"xpath://tr[td[3] > 100]", 2},
+		{"css:a[title]", 1},
+	} {
+		results, err := Extract(doc, tt.selector)
+		if err != nil {
+			t.Fatalf("Extract(%q) returned error: %v", tt.selector, err)
+		}
+		if len(results) != tt.count {
+			t.Errorf("Extract(%q) returned %d results, want %d", tt.selector, len(results), tt.count)
+		}
+	}
+
+	if _, err := Extract(doc, "div["); err == nil {
+		t.Errorf("expected error for invalid selector")
+	}
+}
+
+const productsHTML = `<html><body>
+<div class="list">
+	<div class="product" id="c1"><h2>One</h2><span class="price">10</span></div>
+	<div class="product" id="c2"><h2>Two</h2></div>
+	<div class="product" id="c3"><h2>Three</h2><span class="price">30</span>
+		<div class="product" id="c4"><h2>Nested</h2></div>
+	</div>
+</div>
+<h2 id="outside">Outside</h2>
+</body></html>`
+
+func TestResult_ExtractScoped(t *testing.T) {
+	doc, err := Parse(productsHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cards, err := Extract(doc, ".product")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cards) != 4 {
+		t.Fatalf("Got %d cards, want 4", len(cards))
+	}
+
+	tests := []struct {
+		card     int
+		selector string
+		expected []string
+	}{
+		{0, "h2", []string{"One"}},
+		{1, ".price", nil},
+		{2, "h2", []string{"Three", "Nested"}},
+		{2, ":scope > h2", []string{"Three"}},
+		{2, ".product h2", []string{"Three", "Nested"}},
+		{2, ".product .product h2", []string{"Nested"}},
+		{3, ".list h2", nil},
+		{3, "div h2", []string{"Nested"}},
+		{0, ":scope", nil},
+		{0, "xpath:./h2", []string{"One"}},
+		{2, "xpath:.//h2", []string{"Three", "Nested"}},
+		{0, "xpath:../div[last()]/h2", []string{"Three"}},
+		{0, "xpath://h2[@id]", []string{"Outside"}},
+	}
+
+	for _, tt := range tests {
+		results, err := cards[tt.card].Extract(tt.selector)
+		if err != nil {
+			t.Errorf("card %d, selector %q: unexpected error %v", tt.card, tt.selector, err)
+			continue
+		}
+		var texts []string
+		for _, r := range results {
+			texts = append(texts, r.Text)
+		}
+		if strings.Join(texts, "|") != strings.Join(tt.expected, "|") {
+			t.Errorf("card %d, selector %q: expected=%v, got=%v", tt.card, tt.selector, tt.expected, texts)
+		}
+	}
+
+	if _, err := cards[0].Extract("h2["); err == nil {
+		t.Errorf("expected error for invalid selector")
+	}
+}
+
+func TestSelector_ScopeAtDocumentLevel(t *testing.T) {
+	doc, err := Parse(productsHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := Extract(doc, ":scope > body > h2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Attr["id"] != "outside" {
+		t.Errorf(":scope at document level should match the root element")
+	}
+}
@@ -4,15 +4,67 @@ import (
 	"bytes"
 	"strings"
 	
+	"github.com/btrobot/mydsl/crawler/selector"
 	"golang.org/x/net/html"
 )
 
 // Result 表示提取结果
+// 文本和 HTML 只为匹配的节点计算；子节点的结果由 Children 在调用时构造，
+// 避免为每个匹配的整棵子树重复计算文本
 type Result struct {
-	Text     string
-	HTML     string
-	Attr     map[string]string
-	Children []*Result
+	Tag     string // 元素标签名，非元素节点为空
+	Text    string // 规范化后的内部文本
+	OwnText string // 直接文本子节点的原始内容
+	HTML    string
+	Attr    map[string]string
+	
+	node     *html.Node // 对应的节点，用于相对提取
+	doc      *Document  // 节点所属的文档
+	children []*Result
+}
+
+// Node 返回结果对应的 HTML 节点
+func (r *Result) Node() *html.Node {
+	return r.node
+}
+
+// Children 返回子节点（包括文本节点）的结果，第一次调用时构造
+func (r *Result) Children() []*Result {
+	if r.children == nil {
+		r.children = make([]*Result, 0)
+		if r.node != nil {
+			for c := r.node.FirstChild; c != nil; c = c.NextSibling {
+				r.children = append(r.children, nodeToResult(r.doc, c))
+			}
+		}
+	}
+	return r.children
+}
+
+// Document 返回结果所属的文档，直接由节点构造的结果返回 nil
+func (r *Result) Document() *Document {
+	return r.doc
+}
+
+// Extract 以当前结果对应的元素为作用域提取数据
+// CSS 选择器只在该元素的子树内匹配，:scope 表示该元素本身；
+// XPath 表达式以该元素为上下文节点，例如 "xpath:.//h2"
+func (r *Result) Extract(selector string) ([]*Result, error) {
+	sel, err := compileSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	if r.node == nil {
+		return []*Result{}, nil
+	}
+	
+	var nodes []*html.Node
+	if r.doc != nil {
+		nodes = r.doc.SelectIn(sel, r.node)
+	} else {
+		nodes = sel.Select(r.node)
+	}
+	return r.doc.resultsFor(nodes), nil
 }
 
 // Extractor 表示数据提取器
@@ -25,74 +77,61 @@ func NewExtractor() *Extractor {
 	return &Extractor{}
 }
 
-// Extract 从 HTML 中提取数据
+// Extract 解析 HTML 并使用选择器提取数据，选择器无效时返回 *SelectorError
+// 对同一页面多次提取时应使用 Parse 与 Extract(doc, selector) 以避免重复解析
+// 以 XPathPrefix 开头的选择器按 XPath 1.0 求值，其余按 CSS 选择器处理
 func (e *Extractor) Extract(htmlContent string, selector string) ([]*Result, error) {
-	doc, err := html.Parse(strings.NewReader(htmlContent))
+	doc, err := Parse(htmlContent)
 	if err != nil {
 		return nil, err
 	}
 	
-	// 解析选择器
-	sel := parseSelector(selector)
-	
-	// 查找匹配的节点
-	nodes := findNodes(doc, sel)
-	
-	// 转换为结果
-	results := make([]*Result, 0, len(nodes))
-	for _, node := range nodes {
-		results = append(results, nodeToResult(node))
-	}
-	
-	return results, nil
+	return Extract(doc, selector)
 }
 
-// 解析选择器（简化版）
-func parseSelector(selector string) []string {
-	return strings.Split(selector, " ")
+// 选择器语言前缀
+const (
+	XPathPrefix = selector.XPathPrefix
+	CSSPrefix   = selector.CSSPrefix
+)
+
+// nodeSelector 是 CSS 选择器与 XPath 表达式共同的查询接口
+type nodeSelector interface {
+	Select(root *html.Node) []*html.Node
 }
 
-// 查找匹配的节点（简化版）
-func findNodes(n *html.Node, sel []string) []*html.Node {
-	if len(sel) == 0 {
-		return []*html.Node{n}
-	}
-	
-	var matches []*html.Node
-	
-	// 简化版的选择器匹配逻辑
-	// 实际实现需要更复杂的选择器解析和匹配
-	
-	// 这里仅作为示例，实际需要实现完整的 CSS 选择器
-	if n.Type == html.ElementNode && n.Data == sel[0] {
-		if len(sel) == 1 {
-			matches = append(matches, n)
-		} else {
-			for c := n.FirstChild; c != nil; c = c.NextSibling {
-				matches = append(matches, findNodes(c, sel[1:])...)
-			}
+// compileSelector 根据前缀编译 CSS 选择器或 XPath 表达式
+func compileSelector(selector string) (nodeSelector, error) {
+	if strings.HasPrefix(selector, XPathPrefix) {
+		expr := strings.TrimPrefix(selector, XPathPrefix)
+		x, err := CompileXPath(expr)
+		if err != nil {
+			return nil, err
 		}
+		if !x.ReturnsNodeSet() {
+			return nil, &SelectorError{Selector: expr, Message: "expression does not select nodes"}
+		}
+		return x, nil
 	}
-	
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		matches = append(matches, findNodes(c, sel)...)
-	}
-	
-	return matches
+	return Compile(strings.TrimPrefix(selector, CSSPrefix))
 }
 
-// 将 HTML 节点转换为结果
-func nodeToResult(n *html.Node) *Result {
+// 将 HTML 节点转换为结果，子节点的结果在调用 Children 时构造
+func nodeToResult(doc *Document, n *html.Node) *Result {
 	result := &Result{
-		Attr:     make(map[string]string),
-		Children: make([]*Result, 0),
+		Attr: make(map[string]string, len(n.Attr)),
+		node: n,
+		doc:  doc,
 	}
 	
-	// 提取文本
-	if n.Type == html.TextNode {
-		result.Text = n.Data
+	if n.Type == html.ElementNode {
+		result.Tag = n.Data
 	}
 	
+	// 提取文本
+	result.Text = InnerText(n)
+	result.OwnText = OwnText(n)
+	
 	// 提取属性
 	for _, attr := range n.Attr {
 		result.Attr[attr.Key] = attr.Val
@@ -103,10 +142,5 @@ func nodeToResult(n *html.Node) *Result {
 	html.Render(&buf, n)
 	result.HTML = buf.String()
 	
-	// 提取子节点
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		result.Children = append(result.Children, nodeToResult(c))
-	}
-	
 	return result
 }
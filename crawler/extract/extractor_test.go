@@ -152,3 +152,57 @@ func TestExtractor_ExtractWithAttributes(t *testing.T) {
 			results[0].Attr["data-id"], "123")
 	}
 }
+
+func TestExtractor_ExtractText(t *testing.T) {
+	html := `<html><body>
+	<article>
+		<h1>  The <em>Title</em>  </h1>
+		<p>First line<br>second line</p>
+		<script>ignored()</script>
+	</article>
+</body></html>`
+
+	extractor := NewExtractor()
+
+	results, err := extractor.Extract(html, "article")
+	if err != nil {
+		t.Fatalf("Extract returned error: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Got %d results, want 1", len(results))
+	}
+
+	if want := "The Title\nFirst line\nsecond line"; results[0].Text != want {
+		t.Errorf("Text wrong. got=%q, want=%q", results[0].Text, want)
+	}
+
+	results, err = extractor.Extract(html, "h1")
+	if err != nil {
+		t.Fatalf("Extract returned error: %v", err)
+	}
+	if want := "  The   "; results[0].OwnText != want {
+		t.Errorf("OwnText wrong. got=%q, want=%q", results[0].OwnText, want)
+	}
+}
+
+func TestResultChildren(t *testing.T) {
+	results, err := NewExtractor().Extract(`<ul id="list"><li>a</li><li class="b">b</li></ul>`, "ul")
+	if err != nil {
+		t.Fatalf("Extract returned error: %v", err)
+	}
+	r := results[0]
+
+	if r.children != nil {
+		t.Fatalf("Result built children before they were requested")
+	}
+	if got, want := r.HTML, `<ul id="list"><li>a</li><li class="b">b</li></ul>`; got != want {
+		t.Errorf("HTML wrong. got=%q, want=%q", got, want)
+	}
+	children := r.Children()
+	if len(children) != 2 || children[1].Tag != "li" || children[1].Attr["class"] != "b" {
+		t.Fatalf("Children wrong. got=%v", children)
+	}
+	if children[1].Text != "b" || children[1].Document() != r.Document() {
+		t.Errorf("child Text or Document wrong. got=%q", children[1].Text)
+	}
+}
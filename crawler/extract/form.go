@@ -0,0 +1,224 @@
+package extract
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// FormField 是表单提交时发送的一个字段
+type FormField struct {
+	Name  string
+	Value string
+}
+
+// Form 表示 HTML 表单及其默认提交的字段
+type Form struct {
+	Action  string // 提交地址，相对于文档地址解析；没有 action 时为文档地址
+	Method  string // GET 或 POST
+	Enctype string // application/x-www-form-urlencoded、multipart/form-data 或 text/plain
+	Fields  []FormField
+}
+
+// FindForm 返回包含节点的 form 元素、节点本身，或节点的第一个 form 后代
+func FindForm(n *html.Node) *html.Node {
+	for p := n; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && p.Data == "form" {
+			return p
+		}
+	}
+	return findFormDescendant(n)
+}
+
+func findFormDescendant(n *html.Node) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "form" {
+			return c
+		}
+		if f := findFormDescendant(c); f != nil {
+			return f
+		}
+	}
+	return nil
+}
+
+// ParseForm 读取表单的提交地址、方法、编码和默认字段
+// 字段按浏览器提交表单的规则收集：包括隐藏字段和文档中以 form 属性关联到该表单的控件，
+// 跳过禁用的控件、未选中的复选框和单选框、提交按钮和文件输入；base 为 nil 时相对的 action 保持原样
+func ParseForm(form *html.Node, base *url.URL) *Form {
+	f := &Form{
+		Method:  "GET",
+		Enctype: "application/x-www-form-urlencoded",
+	}
+
+	action, _ := attr(form, "action")
+	action = strings.TrimSpace(action)
+	f.Action = action
+	if base != nil {
+		if u, err := url.Parse(action); err == nil {
+			f.Action = base.ResolveReference(u).String()
+		}
+	}
+
+	if method, _ := attr(form, "method"); strings.EqualFold(strings.TrimSpace(method), "post") {
+		f.Method = "POST"
+	}
+	if enctype, _ := attr(form, "enctype"); enctype != "" {
+		switch e := strings.ToLower(strings.TrimSpace(enctype)); e {
+		case "multipart/form-data", "text/plain":
+			f.Enctype = e
+		}
+	}
+
+	// 带 form 属性的控件可以位于表单之外，因此从文档根节点开始查找
+	root := form
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	id, _ := attr(form, "id")
+	c := &formCollector{Form: f, form: form, id: id}
+	c.visit(root, nil, false)
+	return f
+}
+
+// formCollector 按文档顺序收集属于 form 的控件
+type formCollector struct {
+	*Form
+	form *html.Node
+	id   string
+}
+
+// visit 访问 n 及其后代，owner 为最近的 form 祖先；
+// disabled 表示位于禁用的 fieldset 中，但 fieldset 的第一个 legend 内的控件仍然可用
+func (c *formCollector) visit(n, owner *html.Node, disabled bool) {
+	if n.Type == html.ElementNode {
+		switch n.Data {
+		case "input", "select", "textarea":
+			if _, off := attr(n, "disabled"); !off && !disabled && c.owns(n, owner) {
+				c.add(n)
+			}
+			return
+		case "form":
+			owner = n
+		case "fieldset":
+			if _, off := attr(n, "disabled"); off && !disabled {
+				legend := false
+				for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+					isLegend := ch.Type == html.ElementNode && ch.Data == "legend"
+					c.visit(ch, owner, !isLegend || legend)
+					legend = legend || isLegend
+				}
+				return
+			}
+		}
+	}
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		c.visit(ch, owner, disabled)
+	}
+}
+
+// owns 判断控件是否属于表单：有 form 属性时按 id 关联，否则属于最近的 form 祖先
+func (c *formCollector) owns(control, owner *html.Node) bool {
+	if ref, ok := attr(control, "form"); ok {
+		return c.id != "" && ref == c.id
+	}
+	return owner == c.form
+}
+
+func (c *formCollector) add(n *html.Node) {
+	switch n.Data {
+	case "input":
+		c.addInput(n)
+	case "select":
+		c.addSelect(n)
+	case "textarea":
+		if name, _ := attr(n, "name"); name != "" {
+			c.Fields = append(c.Fields, FormField{Name: name, Value: rawText(n)})
+		}
+	}
+}
+
+func (f *Form) addInput(n *html.Node) {
+	name, _ := attr(n, "name")
+	if name == "" {
+		return
+	}
+	typ, _ := attr(n, "type")
+	value, hasValue := attr(n, "value")
+
+	switch strings.ToLower(typ) {
+	case "submit", "button", "reset", "image", "file":
+		return
+	case "checkbox", "radio":
+		if _, checked := attr(n, "checked"); !checked {
+			return
+		}
+		if !hasValue {
+			value = "on"
+		}
+	}
+	f.Fields = append(f.Fields, FormField{Name: name, Value: value})
+}
+
+// addSelect 添加选中的选项；单选列表没有选中项时使用第一个可用选项
+func (f *Form) addSelect(n *html.Node) {
+	name, _ := attr(n, "name")
+	if name == "" {
+		return
+	}
+	_, multiple := attr(n, "multiple")
+
+	var options []*html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			if c.Data == "option" {
+				options = append(options, c)
+			} else if c.Data == "optgroup" {
+				walk(c)
+			}
+		}
+	}
+	walk(n)
+
+	selected := 0
+	for _, o := range options {
+		if _, ok := attr(o, "selected"); ok {
+			f.Fields = append(f.Fields, FormField{Name: name, Value: optionValue(o)})
+			selected++
+			if !multiple {
+				break
+			}
+		}
+	}
+	if selected > 0 || multiple {
+		return
+	}
+	for _, o := range options {
+		if _, disabled := attr(o, "disabled"); !disabled {
+			f.Fields = append(f.Fields, FormField{Name: name, Value: optionValue(o)})
+			return
+		}
+	}
+}
+
+// optionValue 返回选项的 value 属性，没有时返回其文本
+func optionValue(o *html.Node) string {
+	if v, ok := attr(o, "value"); ok {
+		return v
+	}
+	return strings.TrimSpace(collapseSpace(rawText(o)))
+}
+
+// Values 返回字段的名称到值的映射，同名字段保持出现顺序
+func (f *Form) Values() url.Values {
+	values := url.Values{}
+	for _, field := range f.Fields {
+		values.Add(field.Name, field.Value)
+	}
+	return values
+}
@@ -0,0 +1,131 @@
+package extract
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+const loginForm = `<form action="/session?next=1" method="post" enctype="multipart/form-data">
+<input type="hidden" name="csrf" value="tok123">
+<input name="user" value="guest">
+<input type="password" name="pass">
+<input type="checkbox" name="remember" checked>
+<input type="checkbox" name="newsletter" value="yes">
+<input type="radio" name="plan" value="free">
+<input type="radio" name="plan" value="pro" checked>
+<select name="lang"><option value="en">English</option><option selected>  Chinese  </option></select>
+<select name="tz"><option disabled>pick</option><optgroup label="x"><option>UTC</option></optgroup></select>
+<select name="tags" multiple><option selected>a</option><option>b</option><option selected>c</option></select>
+<textarea name="bio">hello
+world</textarea>
+<input name="off" value="x" disabled>
+<fieldset disabled><input name="inner" value="y"></fieldset>
+<input type="submit" name="go" value="Go">
+<input type="file" name="avatar">
+<input value="no name">
+</form>`
+
+func TestParseForm(t *testing.T) {
+	base, _ := url.Parse("https://example.com/login/")
+	form := ParseForm(FindForm(parseFragment(t, loginForm)), base)
+
+	if form.Action != "https://example.com/session?next=1" {
+		t.Errorf("Action wrong. got=%q", form.Action)
+	}
+	if form.Method != "POST" || form.Enctype != "multipart/form-data" {
+		t.Errorf("Method/Enctype wrong. got=%q %q", form.Method, form.Enctype)
+	}
+
+	want := []FormField{
+		{"csrf", "tok123"},
+		{"user", "guest"},
+		{"pass", ""},
+		{"remember", "on"},
+		{"plan", "pro"},
+		{"lang", "Chinese"},
+		{"tz", "UTC"},
+		{"tags", "a"},
+		{"tags", "c"},
+		{"bio", "hello\nworld"},
+	}
+	if !reflect.DeepEqual(form.Fields, want) {
+		t.Errorf("Fields wrong.\ngot=%v\nwant=%v", form.Fields, want)
+	}
+	if got := form.Values()["tags"]; !reflect.DeepEqual(got, []string{"a", "c"}) {
+		t.Errorf("Values()[tags] = %v", got)
+	}
+}
+
+func TestParseForm_Defaults(t *testing.T) {
+	base, _ := url.Parse("https://example.com/search?q=old")
+
+	tests := []struct {
+		html    string
+		action  string
+		method  string
+		enctype string
+	}{
+		{`<form><input name="q"></form>`, "https://example.com/search?q=old", "GET", "application/x-www-form-urlencoded"},
+		{`<form action="find" method="GET" enctype="bogus"></form>`, "https://example.com/find", "GET", "application/x-www-form-urlencoded"},
+		{`<form method="dialog" enctype="TEXT/PLAIN"></form>`, "https://example.com/search?q=old", "GET", "text/plain"},
+	}
+	for _, tt := range tests {
+		form := ParseForm(parseFragment(t, tt.html), base)
+		if form.Action != tt.action || form.Method != tt.method || form.Enctype != tt.enctype {
+			t.Errorf("%s: got %q %q %q, want %q %q %q", tt.html, form.Action, form.Method, form.Enctype, tt.action, tt.method, tt.enctype)
+		}
+	}
+
+	// 没有基准地址时相对的 action 保持原样
+	if form := ParseForm(parseFragment(t, `<form action="/x"></form>`), nil); form.Action != "/x" {
+		t.Errorf("Action without base = %q, want /x", form.Action)
+	}
+}
+
+func TestParseForm_ControlOwnership(t *testing.T) {
+	div := parseFragment(t, `<div>
+<input name="before" form="f">
+<form id="f">
+<input name="a">
+<div disabled><input name="b"></div>
+<fieldset disabled>
+<input name="c">
+<legend><input name="d"><fieldset disabled><input name="e"></fieldset></legend>
+<legend><input name="g"></legend>
+</fieldset>
+<input name="h" form="other">
+</form>
+<form id="other"><input name="i"></form>
+<textarea name="after" form="f">t</textarea>
+<input name="unowned">
+</div>`)
+
+	form := ParseForm(FindForm(div), nil)
+	want := []FormField{{"before", ""}, {"a", ""}, {"b", ""}, {"d", ""}, {"after", "t"}}
+	if !reflect.DeepEqual(form.Fields, want) {
+		t.Errorf("Fields wrong.\ngot=%v\nwant=%v", form.Fields, want)
+	}
+
+	// 没有 id 的表单不会关联带 form 属性的控件
+	form = ParseForm(parseFragment(t, `<form><input name="x" form=""><input name="y"></form>`), nil)
+	if want := []FormField{{"y", ""}}; !reflect.DeepEqual(form.Fields, want) {
+		t.Errorf("Fields without id = %v, want %v", form.Fields, want)
+	}
+}
+
+func TestFindForm(t *testing.T) {
+	div := parseFragment(t, `<div><p>intro</p><form id="a"><input name="x"></form><form id="b"></form></div>`)
+
+	form := FindForm(div)
+	if id, _ := attr(form, "id"); id != "a" {
+		t.Fatalf("FindForm(div) returned form %q, want a", id)
+	}
+	input := form.FirstChild
+	if FindForm(input) != form {
+		t.Errorf("FindForm(input) did not return the enclosing form")
+	}
+	if FindForm(div.FirstChild) != nil {
+		t.Errorf("FindForm(p) should return nil")
+	}
+}
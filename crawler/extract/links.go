@@ -0,0 +1,119 @@
+package extract
+
+import (
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// LinkOptions 控制链接的过滤和规范化
+type LinkOptions struct {
+	Schemes   []string // 保留的协议，为空时只保留 http 和 https
+	SortQuery bool     // 是否按参数名排序查询参数
+}
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"ftp":   "21",
+	"ws":    "80",
+	"wss":   "443",
+}
+
+// Links 返回节点中 a 和 area 元素链接到的绝对地址，按文档顺序去重
+// 节点本身带有 href 时也计入。地址相对于 base 解析并经过 NormalizeURL 规范化；
+// base 为 nil 时相对地址被忽略
+func Links(nodes []*html.Node, base *url.URL, opts LinkOptions) []string {
+	schemes := opts.Schemes
+	if len(schemes) == 0 {
+		schemes = []string{"http", "https"}
+	}
+	allowed := make(map[string]bool, len(schemes))
+	for _, s := range schemes {
+		allowed[strings.ToLower(s)] = true
+	}
+
+	seen := map[string]bool{}
+	var links []string
+	add := func(n *html.Node) {
+		href, ok := attr(n, "href")
+		if !ok {
+			return
+		}
+		u, err := url.Parse(strings.TrimSpace(href))
+		if err != nil {
+			return
+		}
+		if base != nil {
+			u = base.ResolveReference(u)
+		}
+		if !u.IsAbs() || !allowed[strings.ToLower(u.Scheme)] {
+			return
+		}
+		link := NormalizeURL(u, opts.SortQuery).String()
+		if !seen[link] {
+			seen[link] = true
+			links = append(links, link)
+		}
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && (n.Data == "a" || n.Data == "area") {
+			add(n)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	for _, n := range nodes {
+		walk(n)
+	}
+	return links
+}
+
+// NormalizeURL 返回规范化后的地址副本
+// 协议和主机名转为小写，去掉默认端口和片段，http(s) 的空路径补为 "/"，
+// 空查询串被去掉；sortQuery 为真时查询参数按参数名稳定排序，参数编码保持不变
+func NormalizeURL(u *url.URL, sortQuery bool) *url.URL {
+	n := *u
+	n.Scheme = strings.ToLower(n.Scheme)
+	n.Fragment = ""
+	n.RawFragment = ""
+	n.ForceQuery = false
+
+	if n.Host != "" {
+		host, port := strings.ToLower(n.Hostname()), n.Port()
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		if port != "" && port != defaultPorts[n.Scheme] {
+			host = net.JoinHostPort(strings.Trim(host, "[]"), port)
+		}
+		n.Host = host
+	}
+	if n.Path == "" && n.Opaque == "" && (n.Scheme == "http" || n.Scheme == "https") {
+		n.Path = "/"
+		n.RawPath = ""
+	}
+
+	if sortQuery && n.RawQuery != "" {
+		params := strings.Split(n.RawQuery, "&")
+		sort.SliceStable(params, func(i, j int) bool {
+			return queryKey(params[i]) < queryKey(params[j])
+		})
+		n.RawQuery = strings.Join(params, "&")
+	}
+	n.RawQuery = strings.Trim(n.RawQuery, "&")
+	return &n
+}
+
+func queryKey(param string) string {
+	if i := strings.IndexByte(param, '='); i >= 0 {
+		return param[:i]
+	}
+	return param
+}
@@ -0,0 +1,85 @@
+package extract
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		input     string
+		sortQuery bool
+		expected  string
+	}{
+		{"HTTP://Example.COM:80/a/b#frag", false, "http://example.com/a/b"},
+		{"https://example.com:443", false, "https://example.com/"},
+		{"https://example.com:8443/x?", false, "https://example.com:8443/x"},
+		{"http://[::1]:80/", false, "http://[::1]/"},
+		{"http://[::1]:8080/", false, "http://[::1]:8080/"},
+		{"http://example.com/?b=2&a=1&a=0", false, "http://example.com/?b=2&a=1&a=0"},
+		{"http://example.com/?b=2&a=1&a=0", true, "http://example.com/?a=1&a=0&b=2"},
+		{"http://example.com/?q=a%20b&flag", true, "http://example.com/?flag&q=a%20b"},
+		{"mailto:Someone@Example.com", false, "mailto:Someone@Example.com"},
+	}
+
+	for _, tt := range tests {
+		u, err := url.Parse(tt.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := NormalizeURL(u, tt.sortQuery).String(); got != tt.expected {
+			t.Errorf("NormalizeURL(%q, %v) = %q, want %q", tt.input, tt.sortQuery, got, tt.expected)
+		}
+	}
+}
+
+func TestLinks(t *testing.T) {
+	doc, err := Parse(`<html><body>
+<a href="../p/2">two</a>
+<a href="/p/3#comments">three</a>
+<a href="/p/3">three again</a>
+<a href="https://Other.org:443/x?b=1&a=2">other</a>
+<a href="mailto:a@example.com">mail</a>
+<a href="javascript:void(0)">js</a>
+<a>no href</a>
+<map><area href="/area"></map>
+<link href="/style.css">
+</body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := url.Parse("http://example.com/blog/p/1")
+
+	tests := []struct {
+		name     string
+		base     *url.URL
+		opts     LinkOptions
+		expected []string
+	}{
+		{"resolved and normalized", base, LinkOptions{}, []string{
+			"http://example.com/blog/p/2",
+			"http://example.com/p/3",
+			"https://other.org/x?b=1&a=2",
+			"http://example.com/area",
+		}},
+		{"sorted query", base, LinkOptions{SortQuery: true, Schemes: []string{"https"}}, []string{
+			"https://other.org/x?a=2&b=1",
+		}},
+		{"mailto scheme", base, LinkOptions{Schemes: []string{"mailto"}}, []string{
+			"mailto:a@example.com",
+		}},
+		{"no base keeps absolute links only", nil, LinkOptions{}, []string{
+			"https://other.org/x?b=1&a=2",
+		}},
+	}
+
+	for _, tt := range tests {
+		got := Links([]*html.Node{doc.Root}, tt.base, tt.opts)
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%s: Links() = %q, want %q", tt.name, got, tt.expected)
+		}
+	}
+}
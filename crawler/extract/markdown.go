@@ -0,0 +1,435 @@
+package extract
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// 作为块处理的元素，其余元素按行内内容处理
+var markdownBlockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "body": true,
+	"dd": true, "details": true, "div": true, "dl": true, "dt": true, "fieldset": true,
+	"figcaption": true, "figure": true, "footer": true, "form": true, "h1": true,
+	"h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "header": true,
+	"hr": true, "html": true, "li": true, "main": true, "nav": true, "ol": true,
+	"p": true, "pre": true, "section": true, "summary": true, "table": true, "ul": true,
+}
+
+// 转换时忽略的元素
+var markdownSkipTags = map[string]bool{
+	"head": true, "script": true, "style": true, "noscript": true, "template": true,
+	"iframe": true, "object": true, "embed": true, "button": true, "select": true,
+	"input": true, "textarea": true,
+}
+
+var (
+	mdLineStart   = regexp.MustCompile(`^(#{1,6}|[-+=]|-{2,}|={2,})(\s|$)`)
+	mdOrderedItem = regexp.MustCompile(`^(\d+)([.)])(\s|$)`)
+	mdSpaces      = regexp.MustCompile(` {2,}`)
+)
+
+// Markdown 将节点子树转换为 CommonMark
+// 表格使用 GFM 管道表格语法，删除线使用 ~~；base 不为 nil 时链接和图片地址解析为绝对地址
+func Markdown(n *html.Node, base *url.URL) string {
+	c := &markdownConverter{base: base}
+	var blocks []string
+	if n.Type == html.ElementNode && markdownBlockTags[n.Data] {
+		blocks = c.block(n)
+	} else if n.Type == html.ElementNode && !markdownSkipTags[n.Data] {
+		// 行内元素本身作为一个段落
+		var b strings.Builder
+		c.inline(&b, n)
+		if p := cleanInline(b.String()); p != "" {
+			blocks = []string{escapeLineStart(p)}
+		}
+	} else {
+		blocks = c.blocks(n)
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+type markdownConverter struct {
+	base *url.URL
+}
+
+// blocks 转换节点的子节点，连续的行内内容合并为段落
+func (c *markdownConverter) blocks(n *html.Node) []string {
+	var out []string
+	var inline strings.Builder
+	flush := func() {
+		if p := cleanInline(inline.String()); p != "" {
+			out = append(out, escapeLineStart(p))
+		}
+		inline.Reset()
+	}
+
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		if ch.Type == html.ElementNode && markdownBlockTags[ch.Data] {
+			flush()
+			out = append(out, c.block(ch)...)
+		} else {
+			c.inline(&inline, ch)
+		}
+	}
+	flush()
+	return out
+}
+
+// block 转换块元素，容器元素展开为其子块
+func (c *markdownConverter) block(n *html.Node) []string {
+	switch n.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		text := strings.ReplaceAll(c.inlineString(n), "\\\n", " ")
+		if text == "" {
+			return nil
+		}
+		level := int(n.Data[1] - '0')
+		return []string{strings.Repeat("#", level) + " " + text}
+	case "p":
+		if p := c.inlineString(n); p != "" {
+			return []string{escapeLineStart(p)}
+		}
+		return nil
+	case "hr":
+		return []string{"---"}
+	case "pre":
+		return []string{codeBlock(n)}
+	case "blockquote":
+		inner := c.blocks(n)
+		if len(inner) == 0 {
+			return nil
+		}
+		return []string{prefixLines(strings.Join(inner, "\n\n"), "> ", ">")}
+	case "ul", "ol":
+		return c.list(n)
+	case "table":
+		return tableMarkdown(n)
+	case "dt":
+		if term := c.inlineString(n); term != "" {
+			return []string{"**" + term + "**"}
+		}
+		return nil
+	}
+	return c.blocks(n)
+}
+
+// list 转换列表，列表项的后续行按标记宽度缩进
+func (c *markdownConverter) list(n *html.Node) []string {
+	ordered := n.Data == "ol"
+	number := 1
+	if start, ok := attr(n, "start"); ok {
+		if v, err := strconv.Atoi(strings.TrimSpace(start)); err == nil && v >= 0 {
+			number = v
+		}
+	}
+
+	var items []string
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || li.Data != "li" {
+			continue
+		}
+		marker := "- "
+		if ordered {
+			marker = strconv.Itoa(number) + ". "
+			number++
+		}
+
+		blocks := c.blocks(li)
+		// 含有段落的列表项为松散列表项，块之间保留空行
+		sep := "\n"
+		if findElement(li, "p") != nil {
+			sep = "\n\n"
+		}
+		content := strings.Join(blocks, sep)
+		indent := strings.Repeat(" ", len(marker))
+		items = append(items, marker+strings.TrimPrefix(prefixLines(content, indent, ""), indent))
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	return []string{strings.Join(items, "\n")}
+}
+
+// inlineString 转换元素的行内内容并整理空白
+func (c *markdownConverter) inlineString(n *html.Node) string {
+	return cleanInline(c.inlineRaw(n))
+}
+
+// inlineRaw 转换元素的行内内容，保留首尾空白
+func (c *markdownConverter) inlineRaw(n *html.Node) string {
+	var b strings.Builder
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		c.inline(&b, ch)
+	}
+	return b.String()
+}
+
+func (c *markdownConverter) inline(b *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		b.WriteString(escapeMarkdown(collapseSpace(n.Data)))
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+	if markdownSkipTags[n.Data] {
+		return
+	}
+
+	switch n.Data {
+	case "br":
+		b.WriteString("\\\n")
+	case "strong", "b":
+		wrapInline(b, "**", c.inlineRaw(n))
+	case "em", "i":
+		wrapInline(b, "*", c.inlineRaw(n))
+	case "del", "s", "strike":
+		wrapInline(b, "~~", c.inlineRaw(n))
+	case "code", "kbd", "samp", "tt":
+		b.WriteString(codeSpan(rawText(n)))
+	case "img":
+		src, _ := attr(n, "src")
+		alt, _ := attr(n, "alt")
+		title, _ := attr(n, "title")
+		if src == "" {
+			return
+		}
+		b.WriteString("![" + escapeMarkdown(collapseSpace(alt)) + "](" + c.destination(src, title) + ")")
+	case "a":
+		text := strings.ReplaceAll(c.inlineString(n), "\\\n", " ")
+		href, ok := attr(n, "href")
+		href = strings.TrimSpace(href)
+		if !ok || href == "" || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+			b.WriteString(text)
+			return
+		}
+		title, _ := attr(n, "title")
+		if text == "" {
+			b.WriteString("<" + c.resolve(href) + ">")
+			return
+		}
+		b.WriteString("[" + text + "](" + c.destination(href, title) + ")")
+	default:
+		for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+			c.inline(b, ch)
+		}
+	}
+}
+
+// resolve 将地址解析为相对于 base 的绝对地址
+func (c *markdownConverter) resolve(ref string) string {
+	ref = strings.TrimSpace(ref)
+	if c.base == nil {
+		return ref
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return c.base.ResolveReference(u).String()
+}
+
+// destination 返回链接目标，地址含空格或括号时使用尖括号形式
+func (c *markdownConverter) destination(ref, title string) string {
+	dest := c.resolve(ref)
+	if strings.ContainsAny(dest, " ()<>") {
+		dest = "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(dest) + ">"
+	}
+	if title = collapseSpace(strings.TrimSpace(title)); title != "" {
+		dest += ` "` + strings.ReplaceAll(title, `"`, `\"`) + `"`
+	}
+	return dest
+}
+
+// wrapInline 用强调标记包裹文本，首尾空白移到标记之外
+func wrapInline(b *strings.Builder, marker, text string) {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		b.WriteString(text)
+		return
+	}
+	if strings.HasPrefix(text, " ") {
+		b.WriteString(" ")
+	}
+	b.WriteString(marker + trimmed + marker)
+	if strings.HasSuffix(text, " ") {
+		b.WriteString(" ")
+	}
+}
+
+// codeSpan 生成行内代码，反引号分隔符比内容中最长的反引号串多一个
+func codeSpan(text string) string {
+	text = collapseSpace(text)
+	if strings.TrimSpace(text) == "" {
+		return ""
+	}
+	fence := strings.Repeat("`", longestRun(text, '`')+1)
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		text = " " + text + " "
+	}
+	return fence + text + fence
+}
+
+// codeBlock 将 pre 元素转换为围栏代码块，语言取自 code 元素的 language-/lang- 类名
+func codeBlock(pre *html.Node) string {
+	lang := ""
+	if code := findElement(pre, "code"); code != nil {
+		class, _ := attr(code, "class")
+		for _, name := range strings.Fields(class) {
+			if strings.HasPrefix(name, "language-") {
+				lang = strings.TrimPrefix(name, "language-")
+				break
+			}
+			if strings.HasPrefix(name, "lang-") {
+				lang = strings.TrimPrefix(name, "lang-")
+				break
+			}
+		}
+	}
+
+	text := strings.TrimRight(rawText(pre), "\n")
+	n := longestRun(text, '`') + 1
+	if n < 3 {
+		n = 3
+	}
+	fence := strings.Repeat("`", n)
+	return fence + lang + "\n" + text + "\n" + fence
+}
+
+// tableMarkdown 将表格转换为 GFM 管道表格，没有表头时第一行作为表头
+func tableMarkdown(n *html.Node) []string {
+	t := ParseTable(n)
+	headers, rows := t.Headers, t.Rows
+	if findElement(n, "th") == nil && len(rows) > 0 {
+		headers, rows = rows[0], rows[1:]
+	}
+	if len(headers) == 0 {
+		return nil
+	}
+
+	row := func(cells []string) string {
+		escaped := make([]string, len(cells))
+		for i, cell := range cells {
+			cell = strings.Join(strings.Fields(cell), " ")
+			escaped[i] = strings.ReplaceAll(escapeMarkdown(cell), "|", `\|`)
+		}
+		return "| " + strings.Join(escaped, " | ") + " |"
+	}
+
+	lines := []string{row(headers)}
+	sep := make([]string, len(headers))
+	for i := range sep {
+		sep[i] = "---"
+	}
+	lines = append(lines, "| "+strings.Join(sep, " | ")+" |")
+	for _, r := range rows {
+		lines = append(lines, row(r))
+	}
+	return []string{strings.Join(lines, "\n")}
+}
+
+// prefixLines 为每一行添加前缀，空行使用 empty
+func prefixLines(s, prefix, empty string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = empty
+		} else {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// cleanInline 整理行内内容：合并空格、去掉行首尾空白和末尾的硬换行
+func cleanInline(s string) string {
+	lines := strings.Split(mdSpaces.ReplaceAllString(s, " "), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	s = strings.TrimSpace(strings.Join(lines, "\n"))
+	for strings.HasSuffix(s, "\\") && !strings.HasSuffix(s, "\\\\") {
+		s = strings.TrimSpace(strings.TrimSuffix(s, "\\"))
+	}
+	return s
+}
+
+// escapeLineStart 转义段落各行行首会被解析为标题、列表或分隔线的字符
+func escapeLineStart(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if mdOrderedItem.MatchString(line) {
+			lines[i] = mdOrderedItem.ReplaceAllString(line, `$1\$2$3`)
+		} else if mdLineStart.MatchString(line) {
+			lines[i] = `\` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// escapeMarkdown 转义文本中的 Markdown 标记字符
+func escapeMarkdown(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune("\\`*_[]<>", r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// collapseSpace 将连续空白合并为一个空格
+func collapseSpace(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			if !space {
+				b.WriteByte(' ')
+			}
+			space = true
+			continue
+		}
+		space = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// rawText 返回子树中所有文本节点的原始内容
+func rawText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "br" {
+			b.WriteByte('\n')
+			continue
+		}
+		b.WriteString(rawText(c))
+	}
+	return b.String()
+}
+
+func longestRun(s string, ch byte) int {
+	longest, run := 0, 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == ch {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	return longest
+}
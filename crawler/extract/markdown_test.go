@@ -0,0 +1,108 @@
+package extract
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestMarkdown(t *testing.T) {
+	base, _ := url.Parse("https://example.com/docs/page.html")
+
+	tests := []struct {
+		name     string
+		html     string
+		expected string
+	}{
+		{"headings and paragraphs",
+			`<div><h1>Title</h1><p>Hello   <b>bold </b>and <em>em</em>.</p><h3>Sub <i>x</i></h3></div>`,
+			"# Title\n\nHello **bold** and *em*.\n\n### Sub *x*"},
+		{"links resolved",
+			`<p>See <a href="../guide/">the guide</a>, <a href="#top" title="Top">top</a> and <a href="https://other.org/x">x</a>.</p>`,
+			"See [the guide](https://example.com/guide/), [top](https://example.com/docs/page.html#top \"Top\") and [x](https://other.org/x)."},
+		{"link without text and javascript link",
+			`<p><a href="/a"></a> <a href="javascript:void(0)">click</a></p>`,
+			"<https://example.com/a> click"},
+		{"images",
+			`<p><img src="img/a.png" alt="An [image]"> <img src="/b c.png"></p>`,
+			"![An \\[image\\]](https://example.com/docs/img/a.png) ![](https://example.com/b%20c.png)"},
+		{"unordered and nested lists",
+			`<ul><li>One</li><li>Two<ul><li>Two A</li></ul></li></ul>`,
+			"- One\n- Two\n  - Two A"},
+		{"ordered list with start",
+			`<ol start="3"><li>Three</li><li><p>Four</p><p>More</p></li></ol>`,
+			"3. Three\n4. Four\n\n   More"},
+		{"code block with language",
+			"<pre><code class=\"language-go\">func main() {\n\tprintln(\"*\")\n}\n</code></pre>",
+			"```go\nfunc main() {\n\tprintln(\"*\")\n}\n```"},
+		{"code block containing fences",
+			"<pre>a\n```\nb</pre>",
+			"````\na\n```\nb\n````"},
+		{"inline code",
+			"<p>Use <code>a*b</code> or <code>x`y</code></p>",
+			"Use `a*b` or ``x`y``"},
+		{"table",
+			`<table><tr><th>Name</th><th>Note</th></tr><tr><td>a|b</td><td colspan="1"><b>x</b></td></tr></table>`,
+			"| Name | Note |\n| --- | --- |\n| a\\|b | x |"},
+		{"table without header",
+			`<table><tr><td>1</td><td>2</td></tr><tr><td>3</td><td>4</td></tr></table>`,
+			"| 1 | 2 |\n| --- | --- |\n| 3 | 4 |"},
+		{"blockquote",
+			`<blockquote><p>One</p><p>Two</p></blockquote>`,
+			"> One\n>\n> Two"},
+		{"line breaks and rules",
+			`<div><p>a<br>b<br></p><hr><p>c</p></div>`,
+			"a\\\nb\n\n---\n\nc"},
+		{"escaping",
+			`<div><p>1. not a list</p><p># not a heading *or emphasis*</p><p>- dash</p></div>`,
+			"1\\. not a list\n\n\\# not a heading \\*or emphasis\\*\n\n\\- dash"},
+		{"skipped elements",
+			`<div><script>var x = 1;</script><p>kept</p><style>p {}</style></div>`,
+			"kept"},
+		{"inline root element",
+			`<span>just <s>old</s> text</span>`,
+			"just ~~old~~ text"},
+	}
+
+	for _, tt := range tests {
+		n := parseFragment(t, tt.html)
+		if got := Markdown(n, base); got != tt.expected {
+			t.Errorf("%s:\ngot:\n%s\nwant:\n%s", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestMarkdown_NoBase(t *testing.T) {
+	n := parseFragment(t, `<p><a href="../x">x</a></p>`)
+	if got := Markdown(n, nil); got != "[x](../x)" {
+		t.Errorf("Markdown() = %q", got)
+	}
+}
+
+func TestDocument_BaseURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		html     string
+		expected string
+	}{
+		{"https://example.com/a/b", `<p>x</p>`, "https://example.com/a/b"},
+		{"https://example.com/a/b", `<head><base href="/root/"></head>`, "https://example.com/root/"},
+		{"", `<head><base href="https://cdn.example.com/"></head>`, "https://cdn.example.com/"},
+		{"", `<p>x</p>`, ""},
+		{"", `<head><base href="/relative/"></head>`, ""},
+	}
+
+	for _, tt := range tests {
+		doc, err := Parse(tt.html)
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc.URL = tt.url
+		got := ""
+		if u := doc.BaseURL(); u != nil {
+			got = u.String()
+		}
+		if got != tt.expected {
+			t.Errorf("BaseURL() with url %q and %q = %q, want %q", tt.url, tt.html, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package extract
+
+import (
+	"encoding/json"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Metadata 表示页面中嵌入的结构化数据
+// 值的类型与 encoding/json 解码结果一致：string、float64、bool、nil、
+// []interface{} 和 map[string]interface{}
+type Metadata struct {
+	JSONLD    []interface{}            // JSON-LD 条目，@graph 已展开为独立条目
+	Microdata []map[string]interface{} // 顶层 itemscope 条目，嵌套条目作为属性值
+	OpenGraph map[string]interface{}   // og: 属性，键去掉 "og:" 前缀，重复属性合并为数组
+	Meta      map[string]string        // 其他 meta 标签，按 name、property 或 http-equiv 索引
+}
+
+// ExtractMetadata 从文档树中提取 JSON-LD、Microdata、OpenGraph 和 meta 标签
+// 无法解析的 JSON-LD 脚本会被忽略
+func ExtractMetadata(root *html.Node) *Metadata {
+	m := &Metadata{
+		JSONLD:    []interface{}{},
+		Microdata: []map[string]interface{}{},
+		OpenGraph: map[string]interface{}{},
+		Meta:      map[string]string{},
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch {
+			case n.Data == "script" && isJSONLD(n):
+				m.addJSONLD(OwnText(n))
+			case n.Data == "meta":
+				m.addMeta(n)
+			}
+			if _, ok := attr(n, "itemscope"); ok {
+				if _, isProp := attr(n, "itemprop"); !isProp {
+					m.Microdata = append(m.Microdata, microdataItem(n))
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+	return m
+}
+
+// Map 将元数据转换为嵌套映射，键为 jsonld、microdata、opengraph 和 meta
+func (m *Metadata) Map() map[string]interface{} {
+	items := make([]interface{}, len(m.Microdata))
+	for i, item := range m.Microdata {
+		items[i] = item
+	}
+	meta := make(map[string]interface{}, len(m.Meta))
+	for k, v := range m.Meta {
+		meta[k] = v
+	}
+	return map[string]interface{}{
+		"jsonld":    m.JSONLD,
+		"microdata": items,
+		"opengraph": m.OpenGraph,
+		"meta":      meta,
+	}
+}
+
+func isJSONLD(n *html.Node) bool {
+	typ, _ := attr(n, "type")
+	typ = strings.ToLower(strings.TrimSpace(typ))
+	if i := strings.IndexByte(typ, ';'); i >= 0 {
+		typ = strings.TrimSpace(typ[:i])
+	}
+	return typ == "application/ld+json"
+}
+
+// addJSONLD 解析 JSON-LD 脚本内容
+// 顶层数组的每个元素和 @graph 中的每个节点都作为独立条目，
+// 缺少 @context 的图节点继承外层对象的 @context
+func (m *Metadata) addJSONLD(text string) {
+	var data interface{}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(text)), &data); err != nil {
+		return
+	}
+
+	var add func(v interface{}, context interface{})
+	add = func(v interface{}, context interface{}) {
+		switch v := v.(type) {
+		case []interface{}:
+			for _, item := range v {
+				add(item, context)
+			}
+		case map[string]interface{}:
+			if ctx, ok := v["@context"]; ok {
+				context = ctx
+			} else if context != nil {
+				v["@context"] = context
+			}
+
+			graph, ok := v["@graph"]
+			if !ok {
+				m.JSONLD = append(m.JSONLD, v)
+				return
+			}
+			delete(v, "@graph")
+			// 除 @context 外还有其他属性时保留外层对象本身
+			if len(v) > 1 || (len(v) == 1 && v["@context"] == nil) {
+				m.JSONLD = append(m.JSONLD, v)
+			}
+			add(graph, context)
+		}
+	}
+	add(data, nil)
+}
+
+// addMeta 记录 meta 标签，og: 属性进入 OpenGraph，其余进入 Meta
+func (m *Metadata) addMeta(n *html.Node) {
+	content, ok := attr(n, "content")
+	if !ok {
+		return
+	}
+
+	if property, ok := attr(n, "property"); ok {
+		property = strings.ToLower(strings.TrimSpace(property))
+		if strings.HasPrefix(property, "og:") {
+			addValue(m.OpenGraph, strings.TrimPrefix(property, "og:"), content)
+			return
+		}
+	}
+
+	for _, name := range []string{"name", "property", "http-equiv"} {
+		key, ok := attr(n, name)
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !ok || key == "" {
+			continue
+		}
+		// 部分站点把 og: 属性写在 name 中
+		if strings.HasPrefix(key, "og:") {
+			addValue(m.OpenGraph, strings.TrimPrefix(key, "og:"), content)
+		} else if _, exists := m.Meta[key]; !exists {
+			m.Meta[key] = content
+		}
+		return
+	}
+}
+
+// addValue 向映射中添加值，同名键出现多次时合并为数组
+func addValue(m map[string]interface{}, key string, value interface{}) {
+	existing, ok := m[key]
+	if !ok {
+		m[key] = value
+		return
+	}
+	if list, ok := existing.([]interface{}); ok {
+		m[key] = append(list, value)
+		return
+	}
+	m[key] = []interface{}{existing, value}
+}
+
+// microdataItem 将 itemscope 元素转换为条目
+// 类型和标识分别记录在 @type 和 @id 中，嵌套的 itemscope 属性值递归转换
+func microdataItem(scope *html.Node) map[string]interface{} {
+	item := map[string]interface{}{}
+	if typ, ok := attr(scope, "itemtype"); ok && strings.TrimSpace(typ) != "" {
+		item["@type"] = strings.TrimSpace(typ)
+	}
+	if id, ok := attr(scope, "itemid"); ok && strings.TrimSpace(id) != "" {
+		item["@id"] = strings.TrimSpace(id)
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			_, nested := attr(c, "itemscope")
+			if props, ok := attr(c, "itemprop"); ok {
+				var value interface{}
+				if nested {
+					value = microdataItem(c)
+				} else {
+					value = microdataValue(c)
+				}
+				for _, name := range strings.Fields(props) {
+					addValue(item, name, value)
+				}
+			}
+			// 嵌套条目的属性属于该条目本身
+			if !nested {
+				walk(c)
+			}
+		}
+	}
+	walk(scope)
+	return item
+}
+
+// microdataValue 按 HTML 规范返回属性元素的值
+func microdataValue(n *html.Node) string {
+	var name string
+	switch n.Data {
+	case "meta":
+		name = "content"
+	case "audio", "embed", "iframe", "img", "source", "track", "video":
+		name = "src"
+	case "a", "area", "link":
+		name = "href"
+	case "object":
+		name = "data"
+	case "data", "meter":
+		name = "value"
+	case "time":
+		if v, ok := attr(n, "datetime"); ok {
+			return strings.TrimSpace(v)
+		}
+	}
+	if name != "" {
+		v, _ := attr(n, name)
+		return strings.TrimSpace(v)
+	}
+	return InnerText(n)
+}
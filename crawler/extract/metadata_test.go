@@ -0,0 +1,111 @@
+package extract
+
+import (
+	"reflect"
+	"testing"
+)
+
+const metadataHTML = `<html><head>
+<meta property="og:title" content="Widget">
+<meta property="og:image" content="/a.png">
+<meta property="og:image" content="/b.png">
+<meta name="og:site_name" content="Shop">
+<meta name="Description" content="A widget">
+<meta name="description" content="ignored duplicate">
+<meta property="article:author" content="Ann">
+<meta http-equiv="Content-Language" content="en">
+<meta charset="utf-8">
+<script type="application/ld+json">
+{"@context": "https://schema.org", "@graph": [
+  {"@type": "Product", "name": "Widget", "offers": {"price": 9.5}},
+  {"@type": "Organization", "name": "Acme", "@context": "https://example.org"}
+]}
+</script>
+<script type="application/ld+json">[{"@type": "BreadcrumbList"}, {"@type": "WebPage"}]</script>
+<script type="application/ld+json">{not json</script>
+<script type="text/javascript">{"@type": "Ignored"}</script>
+</head><body>
+<div itemscope itemtype="https://schema.org/Product" itemid="urn:1">
+  <span itemprop="name">Widget</span>
+  <img itemprop="image" src="/w.png">
+  <a itemprop="url" href="/widget">link</a>
+  <time itemprop="releaseDate" datetime="2024-01-02">Jan 2</time>
+  <span itemprop="color tone">red</span>
+  <span itemprop="color">blue</span>
+  <div itemprop="brand" itemscope itemtype="https://schema.org/Brand">
+    <meta itemprop="name" content="Acme">
+  </div>
+</div>
+<div itemscope><data itemprop="count" value="3">three</data></div>
+</body></html>`
+
+func TestExtractMetadata(t *testing.T) {
+	doc, err := Parse(metadataHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := ExtractMetadata(doc.Root)
+
+	wantLD := []interface{}{
+		map[string]interface{}{"@context": "https://schema.org", "@type": "Product", "name": "Widget",
+			"offers": map[string]interface{}{"price": 9.5}},
+		map[string]interface{}{"@context": "https://example.org", "@type": "Organization", "name": "Acme"},
+		map[string]interface{}{"@type": "BreadcrumbList"},
+		map[string]interface{}{"@type": "WebPage"},
+	}
+	if !reflect.DeepEqual(m.JSONLD, wantLD) {
+		t.Errorf("JSONLD = %v, want %v", m.JSONLD, wantLD)
+	}
+
+	wantOG := map[string]interface{}{
+		"title":     "Widget",
+		"image":     []interface{}{"/a.png", "/b.png"},
+		"site_name": "Shop",
+	}
+	if !reflect.DeepEqual(m.OpenGraph, wantOG) {
+		t.Errorf("OpenGraph = %v, want %v", m.OpenGraph, wantOG)
+	}
+
+	wantMeta := map[string]string{
+		"description":      "A widget",
+		"article:author":   "Ann",
+		"content-language": "en",
+	}
+	if !reflect.DeepEqual(m.Meta, wantMeta) {
+		t.Errorf("Meta = %v, want %v", m.Meta, wantMeta)
+	}
+
+	wantItems := []map[string]interface{}{
+		{
+			"@type":       "https://schema.org/Product",
+			"@id":         "urn:1",
+			"name":        "Widget",
+			"image":       "/w.png",
+			"url":         "/widget",
+			"releaseDate": "2024-01-02",
+			"color":       []interface{}{"red", "blue"},
+			"tone":        "red",
+			"brand":       map[string]interface{}{"@type": "https://schema.org/Brand", "name": "Acme"},
+		},
+		{"count": "3"},
+	}
+	if !reflect.DeepEqual(m.Microdata, wantItems) {
+		t.Errorf("Microdata = %v, want %v", m.Microdata, wantItems)
+	}
+}
+
+func TestExtractMetadata_GraphWithProperties(t *testing.T) {
+	doc, err := Parse(`<script type="application/ld+json; charset=utf-8">` +
+		`{"@id": "#site", "@graph": [{"@type": "Person"}]}</script>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []interface{}{
+		map[string]interface{}{"@id": "#site"},
+		map[string]interface{}{"@type": "Person"},
+	}
+	if got := ExtractMetadata(doc.Root).JSONLD; !reflect.DeepEqual(got, want) {
+		t.Errorf("JSONLD = %v, want %v", got, want)
+	}
+}
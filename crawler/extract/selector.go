@@ -0,0 +1,899 @@
+package extract
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/btrobot/mydsl/crawler/selector"
+	"golang.org/x/net/html"
+)
+
+// SelectorError 表示选择器语法错误
+type SelectorError = selector.Error
+
+// Selector 表示编译后的 CSS 选择器组（以逗号分隔的多个选择器）
+type Selector struct {
+	source    string
+	selectors []*complexSelector
+}
+
+// String 返回原始选择器文本
+func (s *Selector) String() string {
+	return s.source
+}
+
+// Match 判断节点是否匹配选择器组中的任意一个选择器
+func (s *Selector) Match(n *html.Node) bool {
+	return s.MatchScoped(n, nil)
+}
+
+// MatchScoped 在以 scope 为根的子树内判断节点是否匹配
+// 组合符不会越过 scope 向外查找祖先或兄弟，:scope 匹配 scope 本身；
+// scope 为 nil 时不限制范围，:scope 等同于 :root
+func (s *Selector) MatchScoped(n, scope *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	for _, c := range s.selectors {
+		if c.match(n, scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// Select 按文档顺序返回 root 的后代中所有匹配的元素，不包含 root 本身
+// root 为元素时选择器相对于 root 求值，参见 MatchScoped
+func (s *Selector) Select(root *html.Node) []*html.Node {
+	var scope *html.Node
+	if root.Type == html.ElementNode {
+		scope = root
+	}
+
+	var matches []*html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if s.MatchScoped(c, scope) {
+				matches = append(matches, c)
+			}
+			walk(c)
+		}
+	}
+	walk(root)
+	return matches
+}
+
+// Compile 编译 CSS Selectors Level 3 选择器，语法错误时返回 *SelectorError
+func Compile(selector string) (*Selector, error) {
+	p := &selectorParser{input: selector}
+	selectors, err := p.parseGroup()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.input) {
+		return nil, p.errorf("unexpected %q", p.input[p.pos:])
+	}
+	return &Selector{source: selector, selectors: selectors}, nil
+}
+
+// MustCompile 与 Compile 相同，但在出错时 panic
+func MustCompile(selector string) *Selector {
+	s, err := Compile(selector)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// 组合符
+const (
+	combinatorDescendant = ' '
+	combinatorChild      = '>'
+	combinatorAdjacent   = '+'
+	combinatorSibling    = '~'
+)
+
+// complexSelector 表示由组合符连接的复合选择器序列
+// combinators[i] 连接 compounds[i] 与 compounds[i+1]
+type complexSelector struct {
+	compounds   []*compoundSelector
+	combinators []byte
+}
+
+func (c *complexSelector) match(n, scope *html.Node) bool {
+	return c.matchAt(n, len(c.compounds)-1, scope)
+}
+
+// matchAt 从右向左匹配：节点 n 需匹配第 i 个复合选择器，其余部分由组合符决定的关系节点匹配
+// 到达作用域元素 scope 后不再继续向外匹配
+func (c *complexSelector) matchAt(n *html.Node, i int, scope *html.Node) bool {
+	if !c.compounds[i].match(n, scope) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	if n == scope {
+		return false
+	}
+
+	switch c.combinators[i-1] {
+	case combinatorDescendant:
+		for p := parentElement(n); p != nil; p = parentElement(p) {
+			if c.matchAt(p, i-1, scope) {
+				return true
+			}
+			if p == scope {
+				break
+			}
+		}
+	case combinatorChild:
+		if p := parentElement(n); p != nil {
+			return c.matchAt(p, i-1, scope)
+		}
+	case combinatorAdjacent:
+		if s := prevElement(n); s != nil {
+			return c.matchAt(s, i-1, scope)
+		}
+	case combinatorSibling:
+		for s := prevElement(n); s != nil; s = prevElement(s) {
+			if c.matchAt(s, i-1, scope) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matcher 判断元素是否满足某个简单选择器
+// scope 为相对选择时的作用域元素，参见 Selector.MatchScoped
+type matcher func(n, scope *html.Node) bool
+
+// ignoreScope 将不依赖作用域的判断函数转换为 matcher
+func ignoreScope(fn func(*html.Node) bool) matcher {
+	return func(n, scope *html.Node) bool { return fn(n) }
+}
+
+// compoundSelector 表示类型选择器加若干简单选择器，例如 div.item[href]:first-child
+type compoundSelector struct {
+	tag      string   // 空字符串表示任意元素
+	id       string   // id 选择器的值，用于索引查找
+	classes  []string // class 选择器的值，用于索引查找
+	matchers []matcher
+}
+
+func (c *compoundSelector) match(n, scope *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	if c.tag != "" && !strings.EqualFold(n.Data, c.tag) {
+		return false
+	}
+	for _, m := range c.matchers {
+		if !m(n, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// selectorParser 是选择器的递归下降解析器
+type selectorParser struct {
+	input string
+	pos   int
+}
+
+func (p *selectorParser) errorf(format string, a ...interface{}) error {
+	return &SelectorError{Selector: p.input, Offset: p.pos, Message: fmt.Sprintf(format, a...)}
+}
+
+func (p *selectorParser) eof() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *selectorParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+// skipSpace 跳过空白字符，返回是否跳过了至少一个
+func (p *selectorParser) skipSpace() bool {
+	start := p.pos
+	for !p.eof() && isSpace(p.input[p.pos]) {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+// parseGroup 解析以逗号分隔的选择器列表
+func (p *selectorParser) parseGroup() ([]*complexSelector, error) {
+	var selectors []*complexSelector
+	for {
+		p.skipSpace()
+		c, err := p.parseComplex()
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, c)
+
+		p.skipSpace()
+		if p.peek() != ',' {
+			return selectors, nil
+		}
+		p.pos++
+	}
+}
+
+// parseComplex 解析由组合符连接的复合选择器序列
+func (p *selectorParser) parseComplex() (*complexSelector, error) {
+	first, err := p.parseCompound()
+	if err != nil {
+		return nil, err
+	}
+	c := &complexSelector{compounds: []*compoundSelector{first}}
+
+	for {
+		space := p.skipSpace()
+		if p.eof() || p.peek() == ',' || p.peek() == ')' {
+			return c, nil
+		}
+
+		combinator := byte(combinatorDescendant)
+		switch p.peek() {
+		case combinatorChild, combinatorAdjacent, combinatorSibling:
+			combinator = p.peek()
+			p.pos++
+			p.skipSpace()
+		default:
+			if !space {
+				return nil, p.errorf("unexpected %q", p.peek())
+			}
+		}
+
+		compound, err := p.parseCompound()
+		if err != nil {
+			return nil, err
+		}
+		c.combinators = append(c.combinators, combinator)
+		c.compounds = append(c.compounds, compound)
+	}
+}
+
+// parseCompound 解析复合选择器
+func (p *selectorParser) parseCompound() (*compoundSelector, error) {
+	c := &compoundSelector{}
+	start := p.pos
+
+	if p.peek() == '*' {
+		p.pos++
+	} else if isNameStart(p.peek()) {
+		name, err := p.parseIdent()
+		if err != nil {
+			return nil, err
+		}
+		c.tag = strings.ToLower(name)
+	}
+
+	for !p.eof() {
+		var m matcher
+		var err error
+
+		switch p.peek() {
+		case '#':
+			p.pos++
+			var id string
+			if id, err = p.parseName(); err == nil {
+				c.id = id
+				m = idMatcher(id)
+			}
+		case '.':
+			p.pos++
+			var class string
+			if class, err = p.parseIdent(); err == nil {
+				c.classes = append(c.classes, class)
+				m = classMatcher(class)
+			}
+		case '[':
+			p.pos++
+			m, err = p.parseAttribute()
+		case ':':
+			p.pos++
+			if p.peek() == ':' {
+				return nil, p.errorf("unsupported pseudo-element")
+			}
+			m, err = p.parsePseudo()
+		default:
+			if p.pos == start {
+				if p.eof() {
+					return nil, p.errorf("expected selector")
+				}
+				return nil, p.errorf("unexpected %q", p.peek())
+			}
+			return c, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+		c.matchers = append(c.matchers, m)
+	}
+
+	if p.pos == start {
+		return nil, p.errorf("expected selector")
+	}
+	return c, nil
+}
+
+// parseIdent 解析 CSS 标识符，支持反斜杠转义
+func (p *selectorParser) parseIdent() (string, error) {
+	var sb strings.Builder
+	start := p.pos
+
+	if p.peek() == '-' {
+		sb.WriteByte('-')
+		p.pos++
+	}
+	if p.eof() || !(isNameStart(p.peek()) || p.peek() == '\\') {
+		p.pos = start
+		return "", p.errorf("expected identifier")
+	}
+
+	for !p.eof() {
+		ch := p.peek()
+		switch {
+		case ch == '\\':
+			r, err := p.parseEscape()
+			if err != nil {
+				return "", err
+			}
+			sb.WriteRune(r)
+		case isNameChar(ch):
+			sb.WriteByte(ch)
+			p.pos++
+		default:
+			return sb.String(), nil
+		}
+	}
+	return sb.String(), nil
+}
+
+// parseEscape 解析反斜杠转义：\xx（最多 6 位十六进制，后随可选空白）或 \c
+func (p *selectorParser) parseEscape() (rune, error) {
+	p.pos++ // 跳过 '\'
+	if p.eof() {
+		return 0, p.errorf("incomplete escape sequence")
+	}
+
+	hexEnd := p.pos
+	for hexEnd < len(p.input) && hexEnd-p.pos < 6 && isHex(p.input[hexEnd]) {
+		hexEnd++
+	}
+	if hexEnd > p.pos {
+		code, _ := strconv.ParseUint(p.input[p.pos:hexEnd], 16, 32)
+		p.pos = hexEnd
+		if !p.eof() && isSpace(p.peek()) {
+			p.pos++
+		}
+		if code == 0 || code > utf8.MaxRune {
+			return utf8.RuneError, nil
+		}
+		return rune(code), nil
+	}
+
+	r, size := utf8.DecodeRuneInString(p.input[p.pos:])
+	p.pos += size
+	return r, nil
+}
+
+// parseString 解析单引号或双引号字符串
+func (p *selectorParser) parseString() (string, error) {
+	quote := p.peek()
+	p.pos++
+
+	var sb strings.Builder
+	for !p.eof() {
+		ch := p.peek()
+		switch ch {
+		case quote:
+			p.pos++
+			return sb.String(), nil
+		case '\\':
+			if p.pos+1 < len(p.input) && p.input[p.pos+1] == '\n' {
+				p.pos += 2
+				continue
+			}
+			r, err := p.parseEscape()
+			if err != nil {
+				return "", err
+			}
+			sb.WriteRune(r)
+		default:
+			sb.WriteByte(ch)
+			p.pos++
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func idMatcher(id string) matcher {
+	return func(n, scope *html.Node) bool {
+		val, ok := attr(n, "id")
+		return ok && val == id
+	}
+}
+
+func classMatcher(class string) matcher {
+	return func(n, scope *html.Node) bool {
+		val, ok := attr(n, "class")
+		return ok && containsWord(val, class)
+	}
+}
+
+// parseName 解析 id 选择器中的名称，允许以数字开头
+func (p *selectorParser) parseName() (string, error) {
+	var sb strings.Builder
+	for !p.eof() {
+		ch := p.peek()
+		if ch == '\\' {
+			r, err := p.parseEscape()
+			if err != nil {
+				return "", err
+			}
+			sb.WriteRune(r)
+			continue
+		}
+		if !isNameChar(ch) {
+			break
+		}
+		sb.WriteByte(ch)
+		p.pos++
+	}
+	if sb.Len() == 0 {
+		return "", p.errorf("expected name")
+	}
+	return sb.String(), nil
+}
+
+// parseAttribute 解析属性选择器 [name]、[name op value] 和 [name op value i]
+func (p *selectorParser) parseAttribute() (matcher, error) {
+	p.skipSpace()
+	name, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	name = strings.ToLower(name)
+	p.skipSpace()
+
+	if p.peek() == ']' {
+		p.pos++
+		return func(n, scope *html.Node) bool {
+			_, ok := attr(n, name)
+			return ok
+		}, nil
+	}
+
+	var op string
+	switch p.peek() {
+	case '=':
+		op = "="
+		p.pos++
+	case '~', '|', '^', '$', '*':
+		if p.pos+1 >= len(p.input) || p.input[p.pos+1] != '=' {
+			return nil, p.errorf("expected attribute operator")
+		}
+		op = p.input[p.pos : p.pos+2]
+		p.pos += 2
+	default:
+		if p.eof() {
+			return nil, p.errorf("unterminated attribute selector")
+		}
+		return nil, p.errorf("expected attribute operator, got %q", p.peek())
+	}
+
+	p.skipSpace()
+	var value string
+	switch {
+	case p.peek() == '"' || p.peek() == '\'':
+		value, err = p.parseString()
+	default:
+		value, err = p.parseIdent()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+	ignoreCase := false
+	if ch := p.peek(); ch == 'i' || ch == 'I' {
+		ignoreCase = true
+		p.pos++
+		p.skipSpace()
+	}
+	if p.peek() != ']' {
+		return nil, p.errorf("expected ']'")
+	}
+	p.pos++
+
+	match := attributeMatcher(op, value, ignoreCase)
+	return func(n, scope *html.Node) bool {
+		val, ok := attr(n, name)
+		return ok && match(val)
+	}, nil
+}
+
+// attributeMatcher 根据运算符返回属性值的比较函数
+func attributeMatcher(op, value string, ignoreCase bool) func(string) bool {
+	if ignoreCase {
+		value = strings.ToLower(value)
+	}
+	normalize := func(s string) string {
+		if ignoreCase {
+			return strings.ToLower(s)
+		}
+		return s
+	}
+
+	switch op {
+	case "=":
+		return func(s string) bool { return normalize(s) == value }
+	case "~=":
+		return func(s string) bool { return containsWord(normalize(s), value) }
+	case "|=":
+		return func(s string) bool {
+			s = normalize(s)
+			return s == value || strings.HasPrefix(s, value+"-")
+		}
+	case "^=":
+		return func(s string) bool { return value != "" && strings.HasPrefix(normalize(s), value) }
+	case "$=":
+		return func(s string) bool { return value != "" && strings.HasSuffix(normalize(s), value) }
+	default: // "*="
+		return func(s string) bool { return value != "" && strings.Contains(normalize(s), value) }
+	}
+}
+
+// parsePseudo 解析伪类
+func (p *selectorParser) parsePseudo() (matcher, error) {
+	start := p.pos
+	name, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	name = strings.ToLower(name)
+
+	if p.peek() == '(' {
+		p.pos++
+		return p.parseFunctionalPseudo(name, start)
+	}
+
+	switch name {
+	case "first-child":
+		return func(n, scope *html.Node) bool { return prevElement(n) == nil }, nil
+	case "last-child":
+		return func(n, scope *html.Node) bool { return nextElement(n) == nil }, nil
+	case "only-child":
+		return func(n, scope *html.Node) bool { return prevElement(n) == nil && nextElement(n) == nil }, nil
+	case "first-of-type":
+		return func(n, scope *html.Node) bool { return prevOfType(n) == nil }, nil
+	case "last-of-type":
+		return func(n, scope *html.Node) bool { return nextOfType(n) == nil }, nil
+	case "only-of-type":
+		return func(n, scope *html.Node) bool { return prevOfType(n) == nil && nextOfType(n) == nil }, nil
+	case "root":
+		return ignoreScope(isRoot), nil
+	case "scope":
+		return func(n, scope *html.Node) bool {
+			if scope == nil {
+				return isRoot(n)
+			}
+			return n == scope
+		}, nil
+	case "empty":
+		return ignoreScope(isEmpty), nil
+	case "link", "any-link":
+		return func(n, scope *html.Node) bool {
+			_, ok := attr(n, "href")
+			return ok && (n.Data == "a" || n.Data == "area" || n.Data == "link")
+		}, nil
+	case "checked":
+		return func(n, scope *html.Node) bool {
+			if n.Data == "option" {
+				_, ok := attr(n, "selected")
+				return ok
+			}
+			_, ok := attr(n, "checked")
+			return ok && n.Data == "input"
+		}, nil
+	case "disabled":
+		return ignoreScope(isDisabled), nil
+	case "enabled":
+		return func(n, scope *html.Node) bool { return isFormControl(n) && !isDisabled(n) }, nil
+	case "visited", "hover", "active", "focus", "target":
+		// 动态伪类在静态文档中永不匹配
+		return func(n, scope *html.Node) bool { return false }, nil
+	}
+
+	p.pos = start
+	return nil, p.errorf("unknown pseudo-class :%s", name)
+}
+
+// parseFunctionalPseudo 解析带参数的伪类，调用时已跳过 '('
+func (p *selectorParser) parseFunctionalPseudo(name string, start int) (matcher, error) {
+	switch name {
+	case "not":
+		p.skipSpace()
+		selectors, err := p.parseGroup()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectCloseParen(); err != nil {
+			return nil, err
+		}
+		return func(n, scope *html.Node) bool {
+			for _, s := range selectors {
+				if s.match(n, scope) {
+					return false
+				}
+			}
+			return true
+		}, nil
+
+	case "nth-child", "nth-last-child", "nth-of-type", "nth-last-of-type":
+		a, b, err := p.parseNth()
+		if err != nil {
+			return nil, err
+		}
+		last := strings.HasPrefix(name, "nth-last-")
+		ofType := strings.HasSuffix(name, "-of-type")
+		return func(n, scope *html.Node) bool {
+			return matchNth(a, b, siblingIndex(n, last, ofType))
+		}, nil
+
+	case "lang":
+		p.skipSpace()
+		lang, err := p.parseIdent()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectCloseParen(); err != nil {
+			return nil, err
+		}
+		lang = strings.ToLower(lang)
+		return func(n, scope *html.Node) bool {
+			for e := n; e != nil; e = parentElement(e) {
+				if val, ok := attr(e, "lang"); ok {
+					val = strings.ToLower(val)
+					return val == lang || strings.HasPrefix(val, lang+"-")
+				}
+			}
+			return false
+		}, nil
+	}
+
+	p.pos = start
+	return nil, p.errorf("unknown pseudo-class :%s()", name)
+}
+
+func (p *selectorParser) expectCloseParen() error {
+	p.skipSpace()
+	if p.peek() != ')' {
+		return p.errorf("expected ')'")
+	}
+	p.pos++
+	return nil
+}
+
+// parseNth 解析 an+b 表达式（包括 odd 与 even），调用时已跳过 '('
+func (p *selectorParser) parseNth() (int, int, error) {
+	end := strings.IndexByte(p.input[p.pos:], ')')
+	if end < 0 {
+		return 0, 0, p.errorf("expected ')'")
+	}
+	raw := p.input[p.pos : p.pos+end]
+	a, b, ok := parseAnB(raw)
+	if !ok {
+		return 0, 0, p.errorf("invalid nth expression %q", strings.TrimSpace(raw))
+	}
+	p.pos += end + 1
+	return a, b, nil
+}
+
+// parseAnB 解析 an+b 语法
+func parseAnB(s string) (int, int, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "odd":
+		return 2, 1, true
+	case "even":
+		return 2, 0, true
+	case "":
+		return 0, 0, false
+	}
+
+	idx := strings.IndexByte(s, 'n')
+	if idx < 0 {
+		b, err := strconv.Atoi(s)
+		return 0, b, err == nil
+	}
+
+	var a int
+	switch coef := s[:idx]; coef {
+	case "", "+":
+		a = 1
+	case "-":
+		a = -1
+	default:
+		v, err := strconv.Atoi(coef)
+		if err != nil {
+			return 0, 0, false
+		}
+		a = v
+	}
+
+	rest := strings.TrimSpace(s[idx+1:])
+	if rest == "" {
+		return a, 0, true
+	}
+	sign := rest[0]
+	if sign != '+' && sign != '-' {
+		return 0, 0, false
+	}
+	digits := strings.TrimSpace(rest[1:])
+	if digits == "" || digits[0] == '+' || digits[0] == '-' {
+		return 0, 0, false
+	}
+	b, err := strconv.Atoi(digits)
+	if err != nil {
+		return 0, 0, false
+	}
+	if sign == '-' {
+		b = -b
+	}
+	return a, b, true
+}
+
+// matchNth 判断位置 index（从 1 开始）是否满足 an+b，n 取非负整数
+func matchNth(a, b, index int) bool {
+	if a == 0 {
+		return index == b
+	}
+	diff := index - b
+	return diff%a == 0 && diff/a >= 0
+}
+
+// siblingIndex 返回元素在兄弟元素中的位置（从 1 开始）
+func siblingIndex(n *html.Node, fromEnd, ofType bool) int {
+	index := 1
+	next := prevElement
+	if fromEnd {
+		next = nextElement
+	}
+	for s := next(n); s != nil; s = next(s) {
+		if !ofType || s.Data == n.Data {
+			index++
+		}
+	}
+	return index
+}
+
+func parentElement(n *html.Node) *html.Node {
+	if p := n.Parent; p != nil && p.Type == html.ElementNode {
+		return p
+	}
+	return nil
+}
+
+func prevElement(n *html.Node) *html.Node {
+	for s := n.PrevSibling; s != nil; s = s.PrevSibling {
+		if s.Type == html.ElementNode {
+			return s
+		}
+	}
+	return nil
+}
+
+func nextElement(n *html.Node) *html.Node {
+	for s := n.NextSibling; s != nil; s = s.NextSibling {
+		if s.Type == html.ElementNode {
+			return s
+		}
+	}
+	return nil
+}
+
+func prevOfType(n *html.Node) *html.Node {
+	for s := prevElement(n); s != nil; s = prevElement(s) {
+		if s.Data == n.Data {
+			return s
+		}
+	}
+	return nil
+}
+
+func nextOfType(n *html.Node) *html.Node {
+	for s := nextElement(n); s != nil; s = nextElement(s) {
+		if s.Data == n.Data {
+			return s
+		}
+	}
+	return nil
+}
+
+func isRoot(n *html.Node) bool {
+	return n.Parent != nil && n.Parent.Type == html.DocumentNode
+}
+
+// isEmpty 判断元素是否没有子元素和非空文本
+func isEmpty(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch c.Type {
+		case html.ElementNode:
+			return false
+		case html.TextNode:
+			if c.Data != "" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isFormControl(n *html.Node) bool {
+	switch n.Data {
+	case "button", "input", "select", "textarea", "option", "optgroup", "fieldset":
+		return true
+	}
+	return false
+}
+
+func isDisabled(n *html.Node) bool {
+	if !isFormControl(n) {
+		return false
+	}
+	_, ok := attr(n, "disabled")
+	return ok
+}
+
+// attr 返回元素的属性值
+func attr(n *html.Node, name string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == name {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// containsWord 判断以空白分隔的列表中是否包含 word
+func containsWord(list, word string) bool {
+	if word == "" {
+		return false
+	}
+	for _, w := range strings.Fields(list) {
+		if w == word {
+			return true
+		}
+	}
+	return false
+}
+
+func isSpace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' || ch == '\f'
+}
+
+func isHex(ch byte) bool {
+	return '0' <= ch && ch <= '9' || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+func isNameStart(ch byte) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' || ch >= 0x80
+}
+
+func isNameChar(ch byte) bool {
+	return isNameStart(ch) || '0' <= ch && ch <= '9' || ch == '-'
+}
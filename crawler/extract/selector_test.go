@@ -0,0 +1,189 @@
+package extract
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+const selectorHTML = `<!DOCTYPE html>
+<html lang="en-US">
+<body>
+	<div id="main" class="container wide">
+		<p id="p1" class="intro">One</p>
+		<p id="p2">Two</p>
+		<span id="s1">Three</span>
+		<p id="p3" class="intro last">Four</p>
+	</div>
+	<ul id="list">
+		<li id="l1">1</li>
+		<li id="l2">2</li>
+		<li id="l3">3</li>
+		<li id="l4">4</li>
+		<li id="l5"></li>
+	</ul>
+	<a id="a1" href="https://example.com/a.pdf" hreflang="en-GB">A</a>
+	<a id="a2" href="/local" data-tags="news sports">B</a>
+	<a id="a3" title="no href">C</a>
+	<form>
+		<input id="i1" type="checkbox" checked>
+		<input id="i2" type="text" disabled>
+		<select><option id="o1">x</option><option id="o2" selected>y</option></select>
+	</form>
+</body>
+</html>`
+
+func selectIDs(t *testing.T, doc *html.Node, selector string) string {
+	t.Helper()
+
+	sel, err := Compile(selector)
+	if err != nil {
+		t.Fatalf("Compile(%q) returned error: %v", selector, err)
+	}
+
+	var ids []string
+	for _, n := range sel.Select(doc) {
+		id, _ := attr(n, "id")
+		if id == "" {
+			id = n.Data
+		}
+		ids = append(ids, id)
+	}
+	return strings.Join(ids, " ")
+}
+
+func TestSelector_Select(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(selectorHTML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		selector string
+		expected string
+	}{
+		{"p", "p1 p2 p3"},
+		{"P", "p1 p2 p3"},
+		{"#main", "main"},
+		{".intro", "p1 p3"},
+		{"p.intro.last", "p3"},
+		{"div.container > p", "p1 p2 p3"},
+		{"body p", "p1 p2 p3"},
+		{"body > p", ""},
+		{"#p1 + p", "p2"},
+		{"#p1 ~ p", "p2 p3"},
+		{"#p2 + span", "s1"},
+		{"p, span", "p1 p2 s1 p3"},
+		{"span , #main", "main s1"},
+		{"div.container > p:nth-child(2)", "p2"},
+		{"a[href]", "a1 a2"},
+		{`a[href^="http"]`, "a1"},
+		{`a[href$=".pdf"]`, "a1"},
+		{`a[href*=local]`, "a2"},
+		{`a[href='/local']`, "a2"},
+		{`a[data-tags~=sports]`, "a2"},
+		{`a[hreflang|=en]`, "a1"},
+		{`a[href^=""]`, ""},
+		{`a[title="NO HREF" i]`, "a3"},
+		{"li:first-child", "l1"},
+		{"li:last-child", "l5"},
+		{"li:nth-child(odd)", "l1 l3 l5"},
+		{"li:nth-child(even)", "l2 l4"},
+		{"li:nth-child(-n+2)", "l1 l2"},
+		{"li:nth-child(3n + 1)", "l1 l4"},
+		{"li:nth-last-child(1)", "l5"},
+		{"#main > :nth-of-type(2)", "p2"},
+		{"#main > p:nth-last-of-type(1)", "p3"},
+		{"#main > :first-of-type", "p1 s1"},
+		{"#main > :last-of-type", "s1 p3"},
+		{"#main > :only-of-type", "s1"},
+		{"p:not(.intro)", "p2"},
+		{"#main > :not(p, #s9)", "s1"},
+		{"li:empty", "l5"},
+		{":root", "html"},
+		{"a:link", "a1 a2"},
+		{":checked", "i1 o2"},
+		{"input:disabled", "i2"},
+		{"input:enabled", "i1"},
+		{"p:lang(en)", "p1 p2 p3"},
+		{"a:hover", ""},
+		{"ul *", "l1 l2 l3 l4 l5"},
+		{`#l\31`, "l1"},
+		{`[id=l\31]`, "l1"},
+	}
+
+	for _, tt := range tests {
+		if got := selectIDs(t, doc, tt.selector); got != tt.expected {
+			t.Errorf("selector %q: expected=%q, got=%q", tt.selector, tt.expected, got)
+		}
+	}
+}
+
+func TestSelector_Errors(t *testing.T) {
+	tests := []struct {
+		selector string
+		message  string
+	}{
+		{"", "expected selector"},
+		{"div,", "expected selector"},
+		{"div >", "expected selector"},
+		{"div > > p", "unexpected '>'"},
+		{"a[href", "unterminated attribute selector"},
+		{"a[href=x", "expected ']'"},
+		{`a[href="x]`, "unterminated string"},
+		{"a[href!=x]", "expected attribute operator"},
+		{"li:nth-child(2n+)", "invalid nth expression"},
+		{"li:nth-child(2", "expected ')'"},
+		{"p:unknown", "unknown pseudo-class :unknown"},
+		{"p:not(.a", "expected ')'"},
+		{"p::before", "unsupported pseudo-element"},
+		{".1a", "expected identifier"},
+		{"div)", "unexpected"},
+	}
+
+	for _, tt := range tests {
+		_, err := Compile(tt.selector)
+		if err == nil {
+			t.Errorf("selector %q: expected error", tt.selector)
+			continue
+		}
+
+		var selErr *SelectorError
+		if !errors.As(err, &selErr) {
+			t.Errorf("selector %q: error is not *SelectorError. got=%T", tt.selector, err)
+			continue
+		}
+		if !strings.Contains(selErr.Message, tt.message) {
+			t.Errorf("selector %q: expected message containing %q, got=%q", tt.selector, tt.message, selErr.Message)
+		}
+	}
+}
+
+func TestParseAnB(t *testing.T) {
+	tests := []struct {
+		input string
+		a, b  int
+		ok    bool
+	}{
+		{"odd", 2, 1, true},
+		{"EVEN", 2, 0, true},
+		{"5", 0, 5, true},
+		{"-3", 0, -3, true},
+		{"n", 1, 0, true},
+		{"-n+3", -1, 3, true},
+		{"+2n - 1", 2, -1, true},
+		{"10n+0", 10, 0, true},
+		{"n-", 0, 0, false},
+		{"2n+-1", 0, 0, false},
+		{"x", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		a, b, ok := parseAnB(tt.input)
+		if ok != tt.ok || (ok && (a != tt.a || b != tt.b)) {
+			t.Errorf("parseAnB(%q) = %d, %d, %v; want %d, %d, %v", tt.input, a, b, ok, tt.a, tt.b, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package extract
+
+import (
+	"strings"
+
+	"github.com/btrobot/mydsl/crawler/selector"
+)
+
+// Suffix 表示选择器末尾的伪元素后缀，例如 ::text 或 ::attr(href)
+// 语法定义在不依赖 HTML 解析的 selector 包中
+type Suffix = selector.Suffix
+
+// ParseSuffix 拆分选择器末尾的伪元素后缀，没有后缀时返回 nil
+// 未知的后缀返回 *SelectorError
+func ParseSuffix(sel string) (string, *Suffix, error) {
+	return selector.ParseSuffix(sel)
+}
+
+// SuffixValue 返回结果按后缀取得的字符串：
+// ::text 为规范化的内部文本，::own-text 为折叠空白后的直接文本，
+// ::html 为元素的 HTML，::attr(name) 为属性值（属性不存在时返回 false）
+func (r *Result) SuffixValue(s *Suffix) (string, bool) {
+	switch s.Name {
+	case "text":
+		return r.Text, true
+	case "own-text":
+		return strings.Join(strings.Fields(r.OwnText), " "), true
+	case "html":
+		return r.HTML, true
+	}
+	val, ok := r.Attr[s.Attr]
+	return val, ok
+}
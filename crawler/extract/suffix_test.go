@@ -0,0 +1,35 @@
+package extract
+
+import "testing"
+
+func TestResult_SuffixValue(t *testing.T) {
+	doc, err := Parse(`<p class="x">Hello  <b>World</b>
+	again</p>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := Extract(doc, "p")
+	if err != nil || len(results) != 1 {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	p := results[0]
+
+	tests := []struct {
+		suffix   Suffix
+		expected string
+		ok       bool
+	}{
+		{Suffix{Name: "text"}, "Hello World again", true},
+		{Suffix{Name: "own-text"}, "Hello again", true},
+		{Suffix{Name: "html"}, "<p class=\"x\">Hello  <b>World</b>\n\tagain</p>", true},
+		{Suffix{Name: "attr", Attr: "class"}, "x", true},
+		{Suffix{Name: "attr", Attr: "id"}, "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := p.SuffixValue(&tt.suffix)
+		if got != tt.expected || ok != tt.ok {
+			t.Errorf("%s: got (%q, %v), want (%q, %v)", tt.suffix.String(), got, ok, tt.expected, tt.ok)
+		}
+	}
+}
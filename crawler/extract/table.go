@@ -0,0 +1,253 @@
+package extract
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// 限制展开后网格的大小，避免异常页面生成巨大的表格
+const (
+	maxSpan    = 1000    // rowspan/colspan 的最大取值
+	maxColumns = 1000    // 网格的最大列数，超出的单元格被丢弃
+	maxCells   = 1000000 // 网格的最大单元格数，超出时丢弃后面的行
+)
+
+// Table 表示解析后的 HTML 表格，rowspan/colspan 已展开为规则的网格
+type Table struct {
+	Headers []string   // 列名，与 Rows 中的每一列一一对应
+	Rows    [][]string // 数据行，每个单元格为规范化的文本
+}
+
+// ParseTable 解析 table 元素
+// 表头取自 thead；没有 thead 时若第一行全部为 th 则作为表头；
+// 多行表头按列以 " / " 连接，缺失或重复的列名会被补全为唯一的名称
+// thead、tbody、tfoot 各自展开，rowspan 不会跨出所在的分区；
+// 网格最多 maxColumns 列、maxCells 个单元格
+func ParseTable(table *html.Node) *Table {
+	var head, body, foot [][]*html.Node
+	var bare []*html.Node // 不在分区中的 tr 组成隐含的 tbody
+	for c := table.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		if c.Data == "tr" {
+			bare = append(bare, c)
+			continue
+		}
+		if len(bare) > 0 {
+			body, bare = append(body, bare), nil
+		}
+		switch c.Data {
+		case "thead":
+			head = append(head, sectionRows(c))
+		case "tbody":
+			body = append(body, sectionRows(c))
+		case "tfoot":
+			foot = append(foot, sectionRows(c))
+		}
+	}
+	if len(bare) > 0 {
+		body = append(body, bare)
+	}
+	body = append(body, foot...)
+
+	budget := maxCells
+	var headGrid, bodyGrid [][]string
+	for _, rows := range head {
+		headGrid = append(headGrid, buildGrid(rows, &budget)...)
+	}
+	for _, rows := range body {
+		bodyGrid = append(bodyGrid, buildGrid(rows, &budget)...)
+	}
+	if len(headGrid) == 0 && len(body) > 0 && len(body[0]) > 0 && allHeaderCells(body[0][0]) {
+		headGrid, bodyGrid = bodyGrid[:1], bodyGrid[1:]
+	}
+
+	width := gridWidth(headGrid)
+	if w := gridWidth(bodyGrid); w > width {
+		width = w
+	}
+	// 补齐后的行同样受单元格数量限制
+	if width > 0 {
+		if n := maxCells/width - len(headGrid); len(bodyGrid) > n {
+			if n < 0 {
+				n = 0
+			}
+			bodyGrid = bodyGrid[:n]
+		}
+	}
+
+	t := &Table{Headers: tableHeaders(headGrid, width), Rows: make([][]string, len(bodyGrid))}
+	for i, row := range bodyGrid {
+		t.Rows[i] = make([]string, width)
+		copy(t.Rows[i], row)
+	}
+	return t
+}
+
+// Records 返回以列名为键的行记录
+func (t *Table) Records() []map[string]string {
+	records := make([]map[string]string, len(t.Rows))
+	for i, row := range t.Rows {
+		record := make(map[string]string, len(t.Headers))
+		for j, header := range t.Headers {
+			record[header] = row[j]
+		}
+		records[i] = record
+	}
+	return records
+}
+
+// FindTable 返回节点本身（若为 table）或其第一个 table 后代
+func FindTable(n *html.Node) *html.Node {
+	if n.Type == html.ElementNode && n.Data == "table" {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if t := FindTable(c); t != nil {
+			return t
+		}
+	}
+	return nil
+}
+
+// sectionRows 返回表格分区中的 tr 元素
+func sectionRows(section *html.Node) []*html.Node {
+	var rows []*html.Node
+	for c := section.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "tr" {
+			rows = append(rows, c)
+		}
+	}
+	return rows
+}
+
+func rowCells(row *html.Node) []*html.Node {
+	var cells []*html.Node
+	for c := row.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && (c.Data == "td" || c.Data == "th") {
+			cells = append(cells, c)
+		}
+	}
+	return cells
+}
+
+func allHeaderCells(row *html.Node) bool {
+	cells := rowCells(row)
+	for _, c := range cells {
+		if c.Data != "th" {
+			return false
+		}
+	}
+	return len(cells) > 0
+}
+
+// buildGrid 将一个分区的行展开为网格，跨行跨列的单元格在其覆盖的每个位置重复出现
+// rowspan="0" 或超出分区时延伸到分区的最后一行；
+// 每填充一个单元格 budget 减一，用尽后不再填充
+func buildGrid(rows []*html.Node, budget *int) [][]string {
+	grid := make([][]string, len(rows))
+	filled := make([][]bool, len(rows))
+
+	set := func(r, c int, text string) {
+		if c >= maxColumns || *budget <= 0 {
+			return
+		}
+		if c < len(filled[r]) && filled[r][c] {
+			grid[r][c] = text
+			return
+		}
+		*budget--
+		for len(grid[r]) <= c {
+			grid[r] = append(grid[r], "")
+			filled[r] = append(filled[r], false)
+		}
+		grid[r][c] = text
+		filled[r][c] = true
+	}
+
+	for r, row := range rows {
+		col := 0
+		for _, cell := range rowCells(row) {
+			for col < len(filled[r]) && filled[r][col] {
+				col++
+			}
+
+			colspan := spanAttr(cell, "colspan", 1)
+			rowspan := spanAttr(cell, "rowspan", 1)
+			if rowspan == 0 || r+rowspan > len(rows) {
+				rowspan = len(rows) - r
+			}
+			if colspan == 0 {
+				colspan = 1
+			}
+
+			text := InnerText(cell)
+			for i := 0; i < rowspan; i++ {
+				for j := 0; j < colspan; j++ {
+					set(r+i, col+j, text)
+				}
+			}
+			col += colspan
+		}
+	}
+	return grid
+}
+
+func spanAttr(cell *html.Node, name string, def int) int {
+	val, ok := attr(cell, name)
+	if !ok {
+		return def
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(val))
+	if err != nil || n < 0 {
+		return def
+	}
+	if n > maxSpan {
+		return maxSpan
+	}
+	return n
+}
+
+func gridWidth(grid [][]string) int {
+	width := 0
+	for _, row := range grid {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+	return width
+}
+
+// tableHeaders 根据表头网格生成每一列的唯一名称
+func tableHeaders(grid [][]string, width int) []string {
+	headers := make([]string, width)
+	seen := make(map[string]int, width)
+
+	for col := 0; col < width; col++ {
+		var parts []string
+		for _, row := range grid {
+			if col >= len(row) || row[col] == "" {
+				continue
+			}
+			// 跨列的上层表头在相邻行中重复出现时只保留一次
+			if len(parts) == 0 || parts[len(parts)-1] != row[col] {
+				parts = append(parts, row[col])
+			}
+		}
+
+		name := strings.Join(parts, " / ")
+		if name == "" {
+			name = fmt.Sprintf("column%d", col+1)
+		}
+		seen[name]++
+		if n := seen[name]; n > 1 {
+			name = fmt.Sprintf("%s_%d", name, n)
+		}
+		headers[col] = name
+	}
+	return headers
+}
@@ -0,0 +1,138 @@
+package extract
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTable(t *testing.T) {
+	tests := []struct {
+		name    string
+		html    string
+		headers []string
+		rows    [][]string
+	}{
+		{
+			"thead and tbody",
+			`<table><thead><tr><th>Name</th><th>Price</th></tr></thead>` +
+				`<tbody><tr><td>Apple</td><td>1</td></tr><tr><td>Pear</td><td>2</td></tr></tbody></table>`,
+			[]string{"Name", "Price"},
+			[][]string{{"Apple", "1"}, {"Pear", "2"}},
+		},
+		{
+			"first row of th without thead",
+			`<table><tr><th>A</th><th>B</th></tr><tr><td>1</td><td>2</td></tr></table>`,
+			[]string{"A", "B"},
+			[][]string{{"1", "2"}},
+		},
+		{
+			"no header",
+			`<table><tr><td>1</td><td>2</td></tr></table>`,
+			[]string{"column1", "column2"},
+			[][]string{{"1", "2"}},
+		},
+		{
+			"multiple tbody and tfoot last",
+			`<table><thead><tr><th>N</th></tr></thead><tfoot><tr><td>total</td></tr></tfoot>` +
+				`<tbody><tr><td>a</td></tr></tbody><tbody><tr><td>b</td></tr></tbody></table>`,
+			[]string{"N"},
+			[][]string{{"a"}, {"b"}, {"total"}},
+		},
+		{
+			"rowspan and colspan",
+			`<table><tr><th>Group</th><th>X</th><th>Y</th></tr>` +
+				`<tr><td rowspan="2">g1</td><td colspan="2">wide</td></tr>` +
+				`<tr><td>x2</td><td>y2</td></tr></table>`,
+			[]string{"Group", "X", "Y"},
+			[][]string{{"g1", "wide", "wide"}, {"g1", "x2", "y2"}},
+		},
+		{
+			"rowspan zero extends to the last row",
+			`<table><tr><td rowspan="0">a</td><td>1</td></tr><tr><td>2</td></tr><tr><td>3</td></tr></table>`,
+			[]string{"column1", "column2"},
+			[][]string{{"a", "1"}, {"a", "2"}, {"a", "3"}},
+		},
+		{
+			"rowspan stops at the end of its row group",
+			`<table><thead><tr><th rowspan="0">H</th></tr></thead>` +
+				`<tbody><tr><td rowspan="5">a</td><td>1</td></tr><tr><td>2</td></tr></tbody>` +
+				`<tbody><tr><td>b</td><td>3</td></tr></tbody>` +
+				`<tfoot><tr><td>total</td></tr></tfoot></table>`,
+			[]string{"H", "column2"},
+			[][]string{{"a", "1"}, {"a", "2"}, {"b", "3"}, {"total", ""}},
+		},
+		{
+			"multi-row header",
+			`<table><thead><tr><th rowspan="2">Item</th><th colspan="2">Price</th></tr>` +
+				`<tr><th>USD</th><th>EUR</th></tr></thead>` +
+				`<tbody><tr><td>a</td><td>1</td><td>2</td></tr></tbody></table>`,
+			[]string{"Item", "Price / USD", "Price / EUR"},
+			[][]string{{"a", "1", "2"}},
+		},
+		{
+			"duplicate and empty headers",
+			`<table><tr><th>A</th><th>A</th><th></th></tr><tr><td>1</td><td>2</td></tr></table>`,
+			[]string{"A", "A_2", "column3"},
+			[][]string{{"1", "2", ""}},
+		},
+		{
+			"nested tables are not flattened",
+			`<table><tr><th>Outer</th></tr><tr><td><table><tr><td>inner</td></tr></table></td></tr></table>`,
+			[]string{"Outer"},
+			[][]string{{"inner"}},
+		},
+	}
+
+	for _, tt := range tests {
+		table := ParseTable(parseFragment(t, tt.html))
+		if !reflect.DeepEqual(table.Headers, tt.headers) {
+			t.Errorf("%s: headers = %q, want %q", tt.name, table.Headers, tt.headers)
+		}
+		if !reflect.DeepEqual(table.Rows, tt.rows) {
+			t.Errorf("%s: rows = %q, want %q", tt.name, table.Rows, tt.rows)
+		}
+	}
+}
+
+func TestParseTable_Limits(t *testing.T) {
+	// 列数不超过 maxColumns
+	table := ParseTable(parseFragment(t,
+		`<table><tr><td colspan="1000">a</td><td colspan="1000">b</td></tr></table>`))
+	if len(table.Headers) != maxColumns || len(table.Rows) != 1 || table.Rows[0][maxColumns-1] != "a" {
+		t.Errorf("wide table: %d headers, %d rows", len(table.Headers), len(table.Rows))
+	}
+
+	// 补齐后的单元格数不超过 maxCells
+	var b strings.Builder
+	b.WriteString(`<table><tr><td colspan="1000">wide</td></tr>`)
+	for i := 0; i < 1500; i++ {
+		b.WriteString(`<tr><td>x</td></tr>`)
+	}
+	b.WriteString(`</table>`)
+	table = ParseTable(parseFragment(t, b.String()))
+	if cells := len(table.Headers) * len(table.Rows); cells > maxCells || len(table.Rows) != maxCells/maxColumns {
+		t.Errorf("long table: %d rows of %d columns", len(table.Rows), len(table.Headers))
+	}
+}
+
+func TestTable_Records(t *testing.T) {
+	table := ParseTable(parseFragment(t,
+		`<table><tr><th>Name</th><th>Price</th></tr><tr><td>Apple</td><td>1</td></tr></table>`))
+
+	want := []map[string]string{{"Name": "Apple", "Price": "1"}}
+	if got := table.Records(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Records() = %v, want %v", got, want)
+	}
+}
+
+func TestFindTable(t *testing.T) {
+	root := parseFragment(t, `<div><p>x</p><table id="t"><tr><td>1</td></tr></table></div>`)
+	table := FindTable(root)
+	if table == nil || table.Data != "table" {
+		t.Fatalf("FindTable() = %v, want table element", table)
+	}
+	if FindTable(parseFragment(t, `<p>none</p>`)) != nil {
+		t.Errorf("FindTable() on element without table should return nil")
+	}
+}
@@ -0,0 +1,147 @@
+package extract
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// blockElements 是前后产生换行的块级元素
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "caption": true,
+	"dd": true, "details": true, "dialog": true, "div": true, "dl": true, "dt": true,
+	"fieldset": true, "figcaption": true, "figure": true, "footer": true, "form": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"header": true, "hgroup": true, "hr": true, "li": true, "main": true, "nav": true,
+	"ol": true, "p": true, "pre": true, "section": true, "summary": true, "table": true,
+	"tbody": true, "tfoot": true, "thead": true, "title": true, "tr": true, "ul": true,
+}
+
+// ignoredElements 是不参与文本提取的元素
+var ignoredElements = map[string]bool{
+	"script": true, "style": true, "template": true, "noscript": true,
+}
+
+// InnerText 返回节点规范化后的内部文本：
+// 连续空白折叠为一个空格，<br> 与块级元素边界转换为换行，
+// script/style 等元素被忽略，<pre> 中的空白原样保留
+func InnerText(n *html.Node) string {
+	w := &textWriter{}
+	w.node(n, false)
+	return strings.TrimRight(w.sb.String(), " \t\r\n")
+}
+
+// OwnText 返回节点直接文本子节点的原始内容，不包括后代元素中的文本
+func OwnText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			sb.WriteString(c.Data)
+		}
+	}
+	return sb.String()
+}
+
+// textWriter 累积文本，延迟写入空格和换行以便折叠与去除首尾空白
+type textWriter struct {
+	sb     strings.Builder
+	space  bool // 下一段文本前需要空格
+	breaks int  // 下一段文本前需要的换行数
+}
+
+func (w *textWriter) node(n *html.Node, pre bool) {
+	switch n.Type {
+	case html.TextNode:
+		if pre {
+			w.raw(n.Data)
+		} else {
+			w.text(n.Data)
+		}
+		return
+	case html.ElementNode:
+	case html.DocumentNode:
+		w.children(n, pre)
+		return
+	default:
+		return
+	}
+
+	if ignoredElements[n.Data] {
+		return
+	}
+
+	switch n.Data {
+	case "br":
+		w.breaks++
+		return
+	case "td", "th":
+		w.space = true
+		w.children(n, pre)
+		w.space = true
+		return
+	}
+
+	block := blockElements[n.Data]
+	if block {
+		w.blockBoundary()
+	}
+	w.children(n, pre || n.Data == "pre")
+	if block {
+		w.blockBoundary()
+	}
+}
+
+func (w *textWriter) children(n *html.Node, pre bool) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.node(c, pre)
+	}
+}
+
+// blockBoundary 要求下一段文本另起一行，连续的块边界不叠加
+func (w *textWriter) blockBoundary() {
+	if w.breaks == 0 {
+		w.breaks = 1
+	}
+}
+
+// text 写入折叠空白后的文本
+func (w *textWriter) text(s string) {
+	for _, r := range s {
+		if isTextSpace(r) {
+			w.space = true
+			continue
+		}
+		w.flush()
+		w.sb.WriteRune(r)
+	}
+}
+
+// raw 原样写入文本，用于 <pre> 内容
+func (w *textWriter) raw(s string) {
+	if s == "" {
+		return
+	}
+	w.flush()
+	w.sb.WriteString(s)
+}
+
+// flush 在写入非空白内容前输出挂起的换行或空格，开头的空白被丢弃
+func (w *textWriter) flush() {
+	if w.sb.Len() > 0 {
+		if w.breaks > 0 {
+			w.sb.WriteString(strings.Repeat("\n", w.breaks))
+		} else if w.space {
+			w.sb.WriteByte(' ')
+		}
+	}
+	w.breaks = 0
+	w.space = false
+}
+
+func isTextSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f'
+}
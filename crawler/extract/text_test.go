@@ -0,0 +1,71 @@
+package extract
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// parseFragment 解析 HTML 并返回 body 的第一个子元素
+func parseFragment(t *testing.T, fragment string) *html.Node {
+	t.Helper()
+
+	doc, err := html.Parse(strings.NewReader("<html><body>" + fragment + "</body></html>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := MustCompile("body > *").Select(doc)
+	if len(nodes) == 0 {
+		t.Fatalf("no element in fragment %q", fragment)
+	}
+	return nodes[0]
+}
+
+func TestInnerText(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"<h1>  Hello\n\t World  </h1>", "Hello World"},
+		{"<p>Hello <b>World</b>!</p>", "Hello World!"},
+		{"<p>a<br>b</p>", "a\nb"},
+		{"<p>a<br><br>b</p>", "a\n\nb"},
+		{"<div><p>one</p><p>two</p></div>", "one\ntwo"},
+		{"<div>intro<div>nested</div>tail</div>", "intro\nnested\ntail"},
+		{"<div><h2>Title</h2>  <span>x</span> <span>y</span></div>", "Title\nx y"},
+		{"<div>keep<script>var x = 1;</script><style>p{}</style> this</div>", "keep this"},
+		{"<ul><li>1</li>\n<li>2</li></ul>", "1\n2"},
+		{"<table><tr><td>a</td><td>b</td></tr><tr><td>c</td></tr></table>", "a b\nc"},
+		{"<div><pre>  x = 1\n  y = 2\n</pre></div>", "  x = 1\n  y = 2"},
+		{"<div>a&nbsp;&nbsp;b</div>", "a  b"},
+		{"<div><!-- comment -->text</div>", "text"},
+		{"<div>  </div>", ""},
+		{"<div><p>a<br></p><p>b</p></div>", "a\nb"},
+	}
+
+	for _, tt := range tests {
+		n := parseFragment(t, tt.input)
+		if got := InnerText(n); got != tt.expected {
+			t.Errorf("InnerText(%q): expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestOwnText(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"<p>Hello <b>World</b>!</p>", "Hello !"},
+		{"<p>  raw\n text </p>", "  raw\n text "},
+		{"<div><span>only child</span></div>", ""},
+	}
+
+	for _, tt := range tests {
+		n := parseFragment(t, tt.input)
+		if got := OwnText(n); got != tt.expected {
+			t.Errorf("OwnText(%q): expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,1479 @@
+package extract
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/btrobot/mydsl/crawler/selector"
+	"golang.org/x/net/html"
+)
+
+// XPath 表示编译后的 XPath 1.0 表达式
+type XPath struct {
+	source string
+	expr   xpathExpr
+}
+
+// CompileXPath 编译 XPath 1.0 表达式，语法错误时返回 *SelectorError
+// 与标准不同的是，table 的 child 轴同时包含其 tbody 中的 tr，
+// 因此按源码写出的 //table/tr 能匹配解析器放入隐含 tbody 的行
+func CompileXPath(expr string) (*XPath, error) {
+	tokens, err := tokenizeXPath(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &xpathParser{source: expr, tokens: tokens}
+	e, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.cur(); tok.kind != xtokEOF {
+		return nil, p.errorf(tok, "unexpected %s", tok)
+	}
+	return &XPath{source: expr, expr: e}, nil
+}
+
+// String 返回原始表达式文本
+func (x *XPath) String() string {
+	return x.source
+}
+
+// ReturnsNodeSet 判断表达式的结果是否为节点集
+func (x *XPath) ReturnsNodeSet() bool {
+	return x.expr.resultType() == xpathNodeSet
+}
+
+// Select 以 root 为上下文节点求值，按文档顺序返回结果节点
+// 表达式结果不是节点集时返回 nil；属性节点以游离的文本节点表示，其内容为属性值
+func (x *XPath) Select(root *html.Node) []*html.Node {
+	return x.selectIn(root, newDocOrder(root))
+}
+
+// selectIn 使用已建立的文档顺序求值
+func (x *XPath) selectIn(root *html.Node, order *docOrder) []*html.Node {
+	set, ok := x.evaluate(root, order).(nodeSet)
+	if !ok {
+		return nil
+	}
+
+	nodes := make([]*html.Node, len(set))
+	for i, n := range set {
+		if n.isAttr() {
+			nodes[i] = &html.Node{Type: html.TextNode, Data: n.node.Attr[n.attr].Val}
+		} else {
+			nodes[i] = n.node
+		}
+	}
+	return nodes
+}
+
+// Evaluate 以 root 为上下文节点求值
+// 返回值为 []*html.Node（节点集）、string、float64 或 bool
+func (x *XPath) Evaluate(root *html.Node) interface{} {
+	order := newDocOrder(root)
+	v := x.evaluate(root, order)
+	if _, ok := v.(nodeSet); ok {
+		return x.selectIn(root, order)
+	}
+	return v
+}
+
+func (x *XPath) evaluate(root *html.Node, order *docOrder) interface{} {
+	c := &xpathContext{node: xnode{node: root, attr: -1}, position: 1, size: 1, doc: order}
+	return x.expr.eval(c)
+}
+
+// xpathType 是表达式的静态结果类型
+type xpathType int
+
+const (
+	xpathNodeSet xpathType = iota
+	xpathString
+	xpathNumber
+	xpathBoolean
+)
+
+// xnode 表示 XPath 数据模型中的节点：HTML 节点或元素的某个属性
+type xnode struct {
+	node *html.Node
+	attr int // 属性节点时为 node.Attr 中的下标，否则为 -1
+}
+
+func (n xnode) isAttr() bool {
+	return n.attr >= 0
+}
+
+// nodeSet 是按文档顺序排列且无重复的节点集
+type nodeSet []xnode
+
+// docOrder 记录节点的文档顺序
+type docOrder struct {
+	root  *html.Node
+	index map[*html.Node]int
+}
+
+func newDocOrder(n *html.Node) *docOrder {
+	for n.Parent != nil {
+		n = n.Parent
+	}
+
+	d := &docOrder{root: n, index: make(map[*html.Node]int)}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		d.index[n] = len(d.index)
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return d
+}
+
+// sort 按文档顺序排序并去重，属性节点位于其元素之后、子节点之前
+func (d *docOrder) sort(set nodeSet) nodeSet {
+	sort.SliceStable(set, func(i, j int) bool {
+		a, b := d.index[set[i].node], d.index[set[j].node]
+		if a != b {
+			return a < b
+		}
+		return set[i].attr < set[j].attr
+	})
+
+	out := set[:0]
+	for i, n := range set {
+		if i == 0 || n != set[i-1] {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// xpathContext 是表达式求值的上下文
+type xpathContext struct {
+	node     xnode
+	position int
+	size     int
+	doc      *docOrder
+}
+
+func (c *xpathContext) with(n xnode, position, size int) *xpathContext {
+	return &xpathContext{node: n, position: position, size: size, doc: c.doc}
+}
+
+// xpathExpr 是编译后的表达式节点，求值结果为 nodeSet、string、float64 或 bool
+type xpathExpr interface {
+	eval(c *xpathContext) interface{}
+	resultType() xpathType
+}
+
+type literalExpr struct{ value string }
+
+func (e *literalExpr) eval(*xpathContext) interface{} { return e.value }
+func (e *literalExpr) resultType() xpathType          { return xpathString }
+
+type numberExpr struct{ value float64 }
+
+func (e *numberExpr) eval(*xpathContext) interface{} { return e.value }
+func (e *numberExpr) resultType() xpathType          { return xpathNumber }
+
+type negateExpr struct{ expr xpathExpr }
+
+func (e *negateExpr) eval(c *xpathContext) interface{} { return -toNumber(e.expr.eval(c)) }
+func (e *negateExpr) resultType() xpathType            { return xpathNumber }
+
+// binaryExpr 表示二元运算
+type binaryExpr struct {
+	op          string
+	left, right xpathExpr
+}
+
+func (e *binaryExpr) resultType() xpathType {
+	switch e.op {
+	case "|":
+		return xpathNodeSet
+	case "+", "-", "*", "div", "mod":
+		return xpathNumber
+	}
+	return xpathBoolean
+}
+
+func (e *binaryExpr) eval(c *xpathContext) interface{} {
+	switch e.op {
+	case "or":
+		return toBool(e.left.eval(c)) || toBool(e.right.eval(c))
+	case "and":
+		return toBool(e.left.eval(c)) && toBool(e.right.eval(c))
+	case "|":
+		left := e.left.eval(c).(nodeSet)
+		right := e.right.eval(c).(nodeSet)
+		return c.doc.sort(append(append(nodeSet{}, left...), right...))
+	}
+
+	left, right := e.left.eval(c), e.right.eval(c)
+	switch e.op {
+	case "+":
+		return toNumber(left) + toNumber(right)
+	case "-":
+		return toNumber(left) - toNumber(right)
+	case "*":
+		return toNumber(left) * toNumber(right)
+	case "div":
+		return toNumber(left) / toNumber(right)
+	case "mod":
+		return math.Mod(toNumber(left), toNumber(right))
+	}
+	return compareValues(e.op, left, right)
+}
+
+// filterExpr 表示带谓词的基本表达式，例如 (//a)[1]
+type filterExpr struct {
+	primary    xpathExpr
+	predicates []xpathExpr
+}
+
+func (e *filterExpr) resultType() xpathType { return xpathNodeSet }
+
+func (e *filterExpr) eval(c *xpathContext) interface{} {
+	set := e.primary.eval(c).(nodeSet)
+	for _, pred := range e.predicates {
+		set = applyPredicate(c, pred, set)
+	}
+	return set
+}
+
+// pathExpr 表示位置路径，filter 不为 nil 时从其结果开始
+type pathExpr struct {
+	filter   xpathExpr
+	absolute bool
+	steps    []*xpathStep
+}
+
+func (e *pathExpr) resultType() xpathType { return xpathNodeSet }
+
+func (e *pathExpr) eval(c *xpathContext) interface{} {
+	var set nodeSet
+	switch {
+	case e.filter != nil:
+		set = e.filter.eval(c).(nodeSet)
+	case e.absolute:
+		set = nodeSet{{node: c.doc.root, attr: -1}}
+	default:
+		set = nodeSet{c.node}
+	}
+
+	for _, step := range e.steps {
+		set = step.apply(c, set)
+	}
+	return set
+}
+
+// 节点测试类型
+const (
+	testName = iota
+	testNode
+	testText
+	testComment
+	testProcessingInstruction
+)
+
+// xpathStep 表示位置步 axis::test[predicate]...
+type xpathStep struct {
+	axis       string
+	test       int
+	name       string // 名称测试的名称，"*" 表示任意名称
+	predicates []xpathExpr
+}
+
+func (s *xpathStep) apply(c *xpathContext, input nodeSet) nodeSet {
+	var out nodeSet
+	for _, n := range input {
+		var candidates nodeSet
+		for _, m := range axisNodes(s.axis, n) {
+			if s.matches(m) {
+				candidates = append(candidates, m)
+			}
+		}
+		for _, pred := range s.predicates {
+			candidates = applyPredicate(c, pred, candidates)
+		}
+		out = append(out, candidates...)
+	}
+	return c.doc.sort(out)
+}
+
+func (s *xpathStep) matches(n xnode) bool {
+	switch s.test {
+	case testNode:
+		return true
+	case testText:
+		return !n.isAttr() && n.node.Type == html.TextNode
+	case testComment:
+		return !n.isAttr() && n.node.Type == html.CommentNode
+	case testProcessingInstruction:
+		return false
+	}
+
+	// 名称测试匹配轴的主节点类型：attribute 轴为属性，其余为元素
+	if s.axis == "attribute" {
+		if !n.isAttr() {
+			return false
+		}
+	} else if n.isAttr() || n.node.Type != html.ElementNode {
+		return false
+	}
+
+	if s.name == "*" {
+		return true
+	}
+	return strings.EqualFold(localName(nodeName(n)), s.name)
+}
+
+// applyPredicate 以节点在集合中的位置为上下文位置求值谓词
+// 谓词结果为数字时与位置比较，否则转换为布尔值
+func applyPredicate(c *xpathContext, pred xpathExpr, set nodeSet) nodeSet {
+	var out nodeSet
+	for i, n := range set {
+		v := pred.eval(c.with(n, i+1, len(set)))
+		if num, ok := v.(float64); ok {
+			if num == float64(i+1) {
+				out = append(out, n)
+			}
+		} else if toBool(v) {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// isTableBody 判断 c 是否为 HTML table 元素 table 的 tbody 子元素
+func isTableBody(table, c *html.Node) bool {
+	return table.Type == html.ElementNode && table.Data == "table" && table.Namespace == "" &&
+		c.Type == html.ElementNode && c.Data == "tbody"
+}
+
+// axisNodes 按轴的方向返回节点：正向轴为文档顺序，反向轴为逆文档顺序
+func axisNodes(axis string, n xnode) nodeSet {
+	var out nodeSet
+	add := func(h *html.Node) {
+		out = append(out, xnode{node: h, attr: -1})
+	}
+
+	switch axis {
+	case "self":
+		out = append(out, n)
+
+	case "child":
+		if !n.isAttr() {
+			for c := n.node.FirstChild; c != nil; c = c.NextSibling {
+				if isXPathNode(c) {
+					add(c)
+				}
+				if isTableBody(n.node, c) {
+					// 解析器为直接写在 table 中的行补上 tbody，行仍视为 table 的子节点，
+					// 使源码形式的 //table/tr 可以匹配
+					for r := c.FirstChild; r != nil; r = r.NextSibling {
+						if r.Type == html.ElementNode && r.Data == "tr" {
+							add(r)
+						}
+					}
+				}
+			}
+		}
+
+	case "descendant", "descendant-or-self":
+		if axis == "descendant-or-self" {
+			out = append(out, n)
+		}
+		if !n.isAttr() {
+			walkDescendants(n.node, add)
+		}
+
+	case "parent":
+		if n.isAttr() {
+			add(n.node)
+		} else if n.node.Parent != nil {
+			add(n.node.Parent)
+		}
+
+	case "ancestor", "ancestor-or-self":
+		if axis == "ancestor-or-self" {
+			out = append(out, n)
+		}
+		p := n.node.Parent
+		if n.isAttr() {
+			p = n.node
+		}
+		for ; p != nil; p = p.Parent {
+			add(p)
+		}
+
+	case "following-sibling":
+		if !n.isAttr() {
+			for s := n.node.NextSibling; s != nil; s = s.NextSibling {
+				if isXPathNode(s) {
+					add(s)
+				}
+			}
+		}
+
+	case "preceding-sibling":
+		if !n.isAttr() {
+			for s := n.node.PrevSibling; s != nil; s = s.PrevSibling {
+				if isXPathNode(s) {
+					add(s)
+				}
+			}
+		}
+
+	case "following":
+		if n.isAttr() {
+			walkDescendants(n.node, add)
+		}
+		for h := n.node; h != nil; h = h.Parent {
+			for s := h.NextSibling; s != nil; s = s.NextSibling {
+				if isXPathNode(s) {
+					add(s)
+					walkDescendants(s, add)
+				}
+			}
+		}
+
+	case "preceding":
+		for h := n.node; h != nil; h = h.Parent {
+			for s := h.PrevSibling; s != nil; s = s.PrevSibling {
+				if !isXPathNode(s) {
+					continue
+				}
+				var subtree []*html.Node
+				subtree = append(subtree, s)
+				walkDescendants(s, func(d *html.Node) { subtree = append(subtree, d) })
+				for i := len(subtree) - 1; i >= 0; i-- {
+					add(subtree[i])
+				}
+			}
+		}
+
+	case "attribute":
+		if !n.isAttr() && n.node.Type == html.ElementNode {
+			for i := range n.node.Attr {
+				out = append(out, xnode{node: n.node, attr: i})
+			}
+		}
+	}
+
+	return out
+}
+
+func walkDescendants(n *html.Node, fn func(*html.Node)) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if isXPathNode(c) {
+			fn(c)
+			walkDescendants(c, fn)
+		}
+	}
+}
+
+// isXPathNode 判断节点是否属于 XPath 数据模型（文档类型声明不属于）
+func isXPathNode(n *html.Node) bool {
+	return n.Type != html.DoctypeNode
+}
+
+// nodeName 返回节点的名称：元素为标签名，属性为属性名，其余为空
+func nodeName(n xnode) string {
+	if n.isAttr() {
+		a := n.node.Attr[n.attr]
+		if a.Namespace != "" {
+			return a.Namespace + ":" + a.Key
+		}
+		return a.Key
+	}
+	if n.node.Type == html.ElementNode {
+		return n.node.Data
+	}
+	return ""
+}
+
+func localName(name string) string {
+	if i := strings.LastIndexByte(name, ':'); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// stringValue 返回节点的字符串值
+func stringValue(n xnode) string {
+	if n.isAttr() {
+		return n.node.Attr[n.attr].Val
+	}
+
+	switch n.node.Type {
+	case html.TextNode, html.CommentNode:
+		return n.node.Data
+	}
+
+	var sb strings.Builder
+	walkDescendants(n.node, func(d *html.Node) {
+		if d.Type == html.TextNode {
+			sb.WriteString(d.Data)
+		}
+	})
+	return sb.String()
+}
+
+// 类型转换
+
+func toBool(v interface{}) bool {
+	switch v := v.(type) {
+	case nodeSet:
+		return len(v) > 0
+	case string:
+		return v != ""
+	case float64:
+		return v != 0 && !math.IsNaN(v)
+	case bool:
+		return v
+	}
+	return false
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case nodeSet:
+		if len(v) == 0 {
+			return ""
+		}
+		return stringValue(v[0])
+	case string:
+		return v
+	case float64:
+		return formatNumber(v)
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	}
+	return ""
+}
+
+func toNumber(v interface{}) float64 {
+	switch v := v.(type) {
+	case float64:
+		return v
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	}
+	return parseNumber(toString(v))
+}
+
+// parseNumber 按 XPath 语法解析数字：可选负号、数字和小数点，两侧允许空白
+func parseNumber(s string) float64 {
+	s = strings.Trim(s, " \t\r\n")
+	digits := strings.TrimPrefix(s, "-")
+	if digits == "" || digits == "." {
+		return math.NaN()
+	}
+
+	dot := false
+	for i := 0; i < len(digits); i++ {
+		switch ch := digits[i]; {
+		case ch == '.' && !dot:
+			dot = true
+		case ch >= '0' && ch <= '9':
+		default:
+			return math.NaN()
+		}
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return f
+}
+
+// formatNumber 按 XPath 规则将数字转换为字符串
+func formatNumber(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	case f == 0:
+		return "0"
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// compareValues 按 XPath 1.0 规则比较两个值，节点集比较时只要存在一对满足条件的节点即为真
+func compareValues(op string, left, right interface{}) bool {
+	leftSet, leftIsSet := left.(nodeSet)
+	rightSet, rightIsSet := right.(nodeSet)
+
+	switch {
+	case leftIsSet && rightIsSet:
+		for _, a := range leftSet {
+			for _, b := range rightSet {
+				if compareAtoms(op, stringValue(a), stringValue(b)) {
+					return true
+				}
+			}
+		}
+		return false
+
+	case leftIsSet:
+		if b, ok := right.(bool); ok {
+			return compareAtoms(op, len(leftSet) > 0, b)
+		}
+		for _, a := range leftSet {
+			if compareAtoms(op, stringValue(a), right) {
+				return true
+			}
+		}
+		return false
+
+	case rightIsSet:
+		return compareValues(reverseOperator(op), right, left)
+	}
+
+	return compareAtoms(op, left, right)
+}
+
+func compareAtoms(op string, left, right interface{}) bool {
+	if op == "=" || op == "!=" {
+		var equal bool
+		_, leftBool := left.(bool)
+		_, rightBool := right.(bool)
+		_, leftNum := left.(float64)
+		_, rightNum := right.(float64)
+
+		switch {
+		case leftBool || rightBool:
+			equal = toBool(left) == toBool(right)
+		case leftNum || rightNum:
+			equal = toNumber(left) == toNumber(right)
+		default:
+			equal = toString(left) == toString(right)
+		}
+		return equal == (op == "=")
+	}
+
+	a, b := toNumber(left), toNumber(right)
+	switch op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	default: // ">="
+		return a >= b
+	}
+}
+
+func reverseOperator(op string) string {
+	switch op {
+	case "<":
+		return ">"
+	case "<=":
+		return ">="
+	case ">":
+		return "<"
+	case ">=":
+		return "<="
+	}
+	return op
+}
+
+// 函数
+
+// functionExpr 表示函数调用
+type functionExpr struct {
+	fn   *xpathFunction
+	args []xpathExpr
+}
+
+func (e *functionExpr) eval(c *xpathContext) interface{} { return e.fn.call(c, e.args) }
+func (e *functionExpr) resultType() xpathType            { return e.fn.result }
+
+// xpathFunction 描述一个核心库函数
+type xpathFunction struct {
+	minArgs, maxArgs int  // maxArgs 小于 0 表示不限
+	nodeSetArgs      bool // 参数必须是节点集
+	result           xpathType
+	call             func(c *xpathContext, args []xpathExpr) interface{}
+}
+
+// xpathFunctions 是 XPath 1.0 核心函数库
+var xpathFunctions = map[string]*xpathFunction{
+	// 节点集函数
+	"last": {0, 0, false, xpathNumber, func(c *xpathContext, args []xpathExpr) interface{} {
+		return float64(c.size)
+	}},
+	"position": {0, 0, false, xpathNumber, func(c *xpathContext, args []xpathExpr) interface{} {
+		return float64(c.position)
+	}},
+	"count": {1, 1, true, xpathNumber, func(c *xpathContext, args []xpathExpr) interface{} {
+		return float64(len(args[0].eval(c).(nodeSet)))
+	}},
+	"id":            {1, 1, false, xpathNodeSet, xpathID},
+	"local-name":    {0, 1, true, xpathString, nameFunction(localName)},
+	"name":          {0, 1, true, xpathString, nameFunction(func(s string) string { return s })},
+	"namespace-uri": {0, 1, true, xpathString, nameFunction(func(string) string { return "" })},
+
+	// 字符串函数
+	"string": {0, 1, false, xpathString, func(c *xpathContext, args []xpathExpr) interface{} {
+		return toString(argOrContext(c, args))
+	}},
+	"concat": {2, -1, false, xpathString, func(c *xpathContext, args []xpathExpr) interface{} {
+		var sb strings.Builder
+		for _, arg := range args {
+			sb.WriteString(toString(arg.eval(c)))
+		}
+		return sb.String()
+	}},
+	"starts-with": {2, 2, false, xpathBoolean, stringPredicate(strings.HasPrefix)},
+	"contains":    {2, 2, false, xpathBoolean, stringPredicate(strings.Contains)},
+	"substring-before": {2, 2, false, xpathString, func(c *xpathContext, args []xpathExpr) interface{} {
+		s, sep := toString(args[0].eval(c)), toString(args[1].eval(c))
+		if i := strings.Index(s, sep); i >= 0 {
+			return s[:i]
+		}
+		return ""
+	}},
+	"substring-after": {2, 2, false, xpathString, func(c *xpathContext, args []xpathExpr) interface{} {
+		s, sep := toString(args[0].eval(c)), toString(args[1].eval(c))
+		if i := strings.Index(s, sep); i >= 0 {
+			return s[i+len(sep):]
+		}
+		return ""
+	}},
+	"substring": {2, 3, false, xpathString, xpathSubstring},
+	"string-length": {0, 1, false, xpathNumber, func(c *xpathContext, args []xpathExpr) interface{} {
+		return float64(utf8.RuneCountInString(toString(argOrContext(c, args))))
+	}},
+	"normalize-space": {0, 1, false, xpathString, func(c *xpathContext, args []xpathExpr) interface{} {
+		return strings.Join(strings.FieldsFunc(toString(argOrContext(c, args)), isXMLSpace), " ")
+	}},
+	"translate": {3, 3, false, xpathString, xpathTranslate},
+
+	// 布尔函数
+	"boolean": {1, 1, false, xpathBoolean, func(c *xpathContext, args []xpathExpr) interface{} {
+		return toBool(args[0].eval(c))
+	}},
+	"not": {1, 1, false, xpathBoolean, func(c *xpathContext, args []xpathExpr) interface{} {
+		return !toBool(args[0].eval(c))
+	}},
+	"true": {0, 0, false, xpathBoolean, func(*xpathContext, []xpathExpr) interface{} {
+		return true
+	}},
+	"false": {0, 0, false, xpathBoolean, func(*xpathContext, []xpathExpr) interface{} {
+		return false
+	}},
+	"lang": {1, 1, false, xpathBoolean, xpathLang},
+
+	// 数字函数
+	"number": {0, 1, false, xpathNumber, func(c *xpathContext, args []xpathExpr) interface{} {
+		return toNumber(argOrContext(c, args))
+	}},
+	"sum": {1, 1, true, xpathNumber, func(c *xpathContext, args []xpathExpr) interface{} {
+		total := 0.0
+		for _, n := range args[0].eval(c).(nodeSet) {
+			total += parseNumber(stringValue(n))
+		}
+		return total
+	}},
+	"floor":   {1, 1, false, xpathNumber, numberFunction(math.Floor)},
+	"ceiling": {1, 1, false, xpathNumber, numberFunction(math.Ceil)},
+	"round":   {1, 1, false, xpathNumber, numberFunction(xpathRound)},
+}
+
+// argOrContext 返回第一个参数的值，没有参数时返回只包含上下文节点的节点集
+func argOrContext(c *xpathContext, args []xpathExpr) interface{} {
+	if len(args) == 0 {
+		return nodeSet{c.node}
+	}
+	return args[0].eval(c)
+}
+
+func nameFunction(fn func(string) string) func(*xpathContext, []xpathExpr) interface{} {
+	return func(c *xpathContext, args []xpathExpr) interface{} {
+		set := argOrContext(c, args).(nodeSet)
+		if len(set) == 0 {
+			return ""
+		}
+		return fn(nodeName(set[0]))
+	}
+}
+
+func stringPredicate(fn func(s, sub string) bool) func(*xpathContext, []xpathExpr) interface{} {
+	return func(c *xpathContext, args []xpathExpr) interface{} {
+		return fn(toString(args[0].eval(c)), toString(args[1].eval(c)))
+	}
+}
+
+func numberFunction(fn func(float64) float64) func(*xpathContext, []xpathExpr) interface{} {
+	return func(c *xpathContext, args []xpathExpr) interface{} {
+		return fn(toNumber(args[0].eval(c)))
+	}
+}
+
+// xpathRound 四舍五入到最接近的整数，正好居中时取较大者
+func xpathRound(f float64) float64 {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return f
+	}
+	return math.Floor(f + 0.5)
+}
+
+// xpathSubstring 实现 substring(s, start, length?)，位置从 1 开始并按 round 取整
+func xpathSubstring(c *xpathContext, args []xpathExpr) interface{} {
+	runes := []rune(toString(args[0].eval(c)))
+	start := xpathRound(toNumber(args[1].eval(c)))
+	end := math.Inf(1)
+	if len(args) == 3 {
+		end = start + xpathRound(toNumber(args[2].eval(c)))
+	}
+
+	var sb strings.Builder
+	for i, r := range runes {
+		if p := float64(i + 1); p >= start && p < end {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// xpathTranslate 实现 translate(s, from, to)
+func xpathTranslate(c *xpathContext, args []xpathExpr) interface{} {
+	s := toString(args[0].eval(c))
+	from := []rune(toString(args[1].eval(c)))
+	to := []rune(toString(args[2].eval(c)))
+
+	var sb strings.Builder
+	for _, r := range s {
+		i := indexRune(from, r)
+		switch {
+		case i < 0:
+			sb.WriteRune(r)
+		case i < len(to):
+			sb.WriteRune(to[i])
+		}
+	}
+	return sb.String()
+}
+
+func indexRune(runes []rune, r rune) int {
+	for i, c := range runes {
+		if c == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// xpathID 返回 id 属性在参数给出的列表中的元素
+func xpathID(c *xpathContext, args []xpathExpr) interface{} {
+	var ids []string
+	switch v := args[0].eval(c).(type) {
+	case nodeSet:
+		for _, n := range v {
+			ids = append(ids, strings.FieldsFunc(stringValue(n), isXMLSpace)...)
+		}
+	default:
+		ids = strings.FieldsFunc(toString(v), isXMLSpace)
+	}
+
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	var out nodeSet
+	walkDescendants(c.doc.root, func(n *html.Node) {
+		if n.Type != html.ElementNode {
+			return
+		}
+		if id, ok := attr(n, "id"); ok && wanted[id] {
+			out = append(out, xnode{node: n, attr: -1})
+		}
+	})
+	return out
+}
+
+// xpathLang 判断上下文节点的语言是否为参数指定的语言或其子语言
+func xpathLang(c *xpathContext, args []xpathExpr) interface{} {
+	lang := strings.ToLower(toString(args[0].eval(c)))
+
+	n := c.node.node
+	for ; n != nil; n = n.Parent {
+		if n.Type != html.ElementNode {
+			continue
+		}
+		for _, a := range n.Attr {
+			if a.Key == "lang" {
+				val := strings.ToLower(a.Val)
+				return val == lang || strings.HasPrefix(val, lang+"-")
+			}
+		}
+	}
+	return false
+}
+
+func isXMLSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\r' || r == '\n'
+}
+
+// 词法分析
+
+type xpathTokenKind int
+
+const (
+	xtokEOF xpathTokenKind = iota
+	xtokName
+	xtokStar
+	xtokNumber
+	xtokLiteral
+	xtokVariable
+	xtokPunct
+)
+
+type xpathToken struct {
+	kind xpathTokenKind
+	text string
+	pos  int
+}
+
+func (t xpathToken) String() string {
+	if t.kind == xtokEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+func (t xpathToken) is(kind xpathTokenKind, text string) bool {
+	return t.kind == kind && t.text == text
+}
+
+func tokenizeXPath(expr string) ([]xpathToken, error) {
+	var tokens []xpathToken
+	pos := 0
+
+	errorf := func(format string, a ...interface{}) error {
+		return &SelectorError{Selector: expr, Offset: pos, Message: fmt.Sprintf(format, a...)}
+	}
+
+	for pos < len(expr) {
+		ch := expr[pos]
+		start := pos
+
+		switch {
+		case isXMLSpace(rune(ch)):
+			pos++
+			continue
+
+		case ch == '"' || ch == '\'':
+			end := strings.IndexByte(expr[pos+1:], ch)
+			if end < 0 {
+				return nil, errorf("unterminated string literal")
+			}
+			tokens = append(tokens, xpathToken{xtokLiteral, expr[pos+1 : pos+1+end], start})
+			pos += end + 2
+
+		case ch >= '0' && ch <= '9' || ch == '.' && pos+1 < len(expr) && isDigit(expr[pos+1]):
+			for pos < len(expr) && isDigit(expr[pos]) {
+				pos++
+			}
+			if pos < len(expr) && expr[pos] == '.' {
+				pos++
+				for pos < len(expr) && isDigit(expr[pos]) {
+					pos++
+				}
+			}
+			tokens = append(tokens, xpathToken{xtokNumber, expr[start:pos], start})
+
+		case ch == '$':
+			pos++
+			name := scanXPathName(expr, pos)
+			if name == "" {
+				return nil, errorf("expected variable name")
+			}
+			pos += len(name)
+			tokens = append(tokens, xpathToken{xtokVariable, name, start})
+
+		case ch == '*':
+			pos++
+			tokens = append(tokens, xpathToken{xtokStar, "*", start})
+
+		case isNameStart(ch):
+			name := scanXPathName(expr, pos)
+			pos += len(name)
+			// QName 或 prefix:*，但不包括轴分隔符 ::
+			if pos+1 < len(expr) && expr[pos] == ':' && expr[pos+1] != ':' {
+				if expr[pos+1] == '*' {
+					name += ":*"
+					pos += 2
+				} else if local := scanXPathName(expr, pos+1); local != "" {
+					name += ":" + local
+					pos += 1 + len(local)
+				}
+			}
+			tokens = append(tokens, xpathToken{xtokName, name, start})
+
+		default:
+			punct := ""
+			for _, p := range []string{"//", "::", "..", "!=", "<=", ">=", "/", "(", ")", "[", "]", ".", "@", ",", "|", "+", "-", "=", "<", ">"} {
+				if strings.HasPrefix(expr[pos:], p) {
+					punct = p
+					break
+				}
+			}
+			if punct == "" {
+				return nil, errorf("unexpected character %q", ch)
+			}
+			pos += len(punct)
+			tokens = append(tokens, xpathToken{xtokPunct, punct, start})
+		}
+	}
+
+	return append(tokens, xpathToken{xtokEOF, "", len(expr)}), nil
+}
+
+// scanXPathName 从 pos 开始扫描 NCName
+func scanXPathName(s string, pos int) string {
+	if pos >= len(s) || !isNameStart(s[pos]) {
+		return ""
+	}
+	end := pos + 1
+	for end < len(s) && (isNameChar(s[end]) || s[end] == '.') {
+		end++
+	}
+	return s[pos:end]
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+// 语法分析
+
+type xpathParser struct {
+	source string
+	tokens []xpathToken
+	pos    int
+}
+
+func (p *xpathParser) cur() xpathToken {
+	return p.tokens[p.pos]
+}
+
+func (p *xpathParser) peek() xpathToken {
+	if p.pos+1 < len(p.tokens) {
+		return p.tokens[p.pos+1]
+	}
+	return p.tokens[len(p.tokens)-1]
+}
+
+func (p *xpathParser) next() xpathToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != xtokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *xpathParser) errorf(tok xpathToken, format string, a ...interface{}) error {
+	return &SelectorError{Selector: p.source, Offset: tok.pos, Message: fmt.Sprintf(format, a...)}
+}
+
+func (p *xpathParser) expect(text string) error {
+	if tok := p.cur(); !tok.is(xtokPunct, text) {
+		return p.errorf(tok, "expected %q, got %s", text, tok)
+	}
+	p.next()
+	return nil
+}
+
+func (p *xpathParser) parseExpr() (xpathExpr, error) {
+	return p.parseOr()
+}
+
+// parseBinary 解析左结合的二元运算，isOperator 判断当前词法单元是否为该层级的运算符
+func (p *xpathParser) parseBinary(operand func() (xpathExpr, error), isOperator func(xpathToken) bool) (xpathExpr, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+
+	for isOperator(p.cur()) {
+		op := p.next().text
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *xpathParser) parseOr() (xpathExpr, error) {
+	return p.parseBinary(p.parseAnd, func(t xpathToken) bool { return t.is(xtokName, "or") })
+}
+
+func (p *xpathParser) parseAnd() (xpathExpr, error) {
+	return p.parseBinary(p.parseEquality, func(t xpathToken) bool { return t.is(xtokName, "and") })
+}
+
+func (p *xpathParser) parseEquality() (xpathExpr, error) {
+	return p.parseBinary(p.parseRelational, func(t xpathToken) bool {
+		return t.is(xtokPunct, "=") || t.is(xtokPunct, "!=")
+	})
+}
+
+func (p *xpathParser) parseRelational() (xpathExpr, error) {
+	return p.parseBinary(p.parseAdditive, func(t xpathToken) bool {
+		return t.kind == xtokPunct && (t.text == "<" || t.text == "<=" || t.text == ">" || t.text == ">=")
+	})
+}
+
+func (p *xpathParser) parseAdditive() (xpathExpr, error) {
+	return p.parseBinary(p.parseMultiplicative, func(t xpathToken) bool {
+		return t.is(xtokPunct, "+") || t.is(xtokPunct, "-")
+	})
+}
+
+// parseMultiplicative 解析乘除运算；此处处于运算符位置，* 与 div/mod 被视为运算符
+func (p *xpathParser) parseMultiplicative() (xpathExpr, error) {
+	return p.parseBinary(p.parseUnary, func(t xpathToken) bool {
+		return t.kind == xtokStar || t.is(xtokName, "div") || t.is(xtokName, "mod")
+	})
+}
+
+func (p *xpathParser) parseUnary() (xpathExpr, error) {
+	if p.cur().is(xtokPunct, "-") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &negateExpr{expr: operand}, nil
+	}
+	return p.parseUnion()
+}
+
+func (p *xpathParser) parseUnion() (xpathExpr, error) {
+	left, err := p.parsePath()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.cur().is(xtokPunct, "|") {
+		tok := p.next()
+		right, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		if left.resultType() != xpathNodeSet || right.resultType() != xpathNodeSet {
+			return nil, p.errorf(tok, "operands of '|' must be node-sets")
+		}
+		left = &binaryExpr{op: "|", left: left, right: right}
+	}
+	return left, nil
+}
+
+// isNodeType 判断名称是否为节点类型测试
+func isNodeType(name string) bool {
+	switch name {
+	case "node", "text", "comment", "processing-instruction":
+		return true
+	}
+	return false
+}
+
+// startsFilter 判断当前位置是否为过滤表达式（基本表达式）而非位置路径
+func (p *xpathParser) startsFilter() bool {
+	tok := p.cur()
+	switch tok.kind {
+	case xtokLiteral, xtokNumber, xtokVariable:
+		return true
+	case xtokPunct:
+		return tok.text == "("
+	case xtokName:
+		return p.peek().is(xtokPunct, "(") && !isNodeType(tok.text)
+	}
+	return false
+}
+
+// canStartStep 判断当前词法单元能否开始一个位置步
+func (p *xpathParser) canStartStep() bool {
+	tok := p.cur()
+	switch tok.kind {
+	case xtokName, xtokStar:
+		return true
+	case xtokPunct:
+		return tok.text == "." || tok.text == ".." || tok.text == "@"
+	}
+	return false
+}
+
+func (p *xpathParser) parsePath() (xpathExpr, error) {
+	if !p.startsFilter() {
+		return p.parseLocationPath()
+	}
+
+	tok := p.cur()
+	filter, err := p.parseFilter()
+	if err != nil {
+		return nil, err
+	}
+	if !p.cur().is(xtokPunct, "/") && !p.cur().is(xtokPunct, "//") {
+		return filter, nil
+	}
+
+	if filter.resultType() != xpathNodeSet {
+		return nil, p.errorf(tok, "path must start with a node-set")
+	}
+	path := &pathExpr{filter: filter}
+	if err := p.parseRelativePath(path, true); err != nil {
+		return nil, err
+	}
+	return path, nil
+}
+
+func (p *xpathParser) parseLocationPath() (xpathExpr, error) {
+	path := &pathExpr{}
+
+	switch tok := p.cur(); {
+	case tok.is(xtokPunct, "/"):
+		path.absolute = true
+		p.next()
+		if !p.canStartStep() {
+			return path, nil
+		}
+	case tok.is(xtokPunct, "//"):
+		path.absolute = true
+		if err := p.parseRelativePath(path, true); err != nil {
+			return nil, err
+		}
+		return path, nil
+	}
+
+	if err := p.parseRelativePath(path, false); err != nil {
+		return nil, err
+	}
+	return path, nil
+}
+
+// parseRelativePath 解析以 / 或 // 分隔的位置步
+// leadingSeparator 为 true 时当前词法单元是路径开头的分隔符
+func (p *xpathParser) parseRelativePath(path *pathExpr, leadingSeparator bool) error {
+	if !leadingSeparator {
+		step, err := p.parseStep()
+		if err != nil {
+			return err
+		}
+		path.steps = append(path.steps, step)
+	}
+
+	for {
+		switch tok := p.cur(); {
+		case tok.is(xtokPunct, "/"):
+			p.next()
+		case tok.is(xtokPunct, "//"):
+			p.next()
+			path.steps = append(path.steps, &xpathStep{axis: "descendant-or-self", test: testNode})
+		default:
+			return nil
+		}
+
+		step, err := p.parseStep()
+		if err != nil {
+			return err
+		}
+		path.steps = append(path.steps, step)
+	}
+}
+
+func (p *xpathParser) parseStep() (*xpathStep, error) {
+	tok := p.cur()
+	switch {
+	case tok.is(xtokPunct, "."):
+		p.next()
+		return &xpathStep{axis: "self", test: testNode}, nil
+	case tok.is(xtokPunct, ".."):
+		p.next()
+		return &xpathStep{axis: "parent", test: testNode}, nil
+	}
+
+	step := &xpathStep{axis: "child"}
+	if tok.is(xtokPunct, "@") {
+		step.axis = "attribute"
+		p.next()
+	} else if tok.kind == xtokName && p.peek().is(xtokPunct, "::") {
+		if !selector.XPathAxes[tok.text] {
+			return nil, p.errorf(tok, "unknown axis %q", tok.text)
+		}
+		step.axis = tok.text
+		p.next()
+		p.next()
+	}
+
+	if err := p.parseNodeTest(step); err != nil {
+		return nil, err
+	}
+
+	for p.cur().is(xtokPunct, "[") {
+		pred, err := p.parsePredicate()
+		if err != nil {
+			return nil, err
+		}
+		step.predicates = append(step.predicates, pred)
+	}
+	return step, nil
+}
+
+func (p *xpathParser) parseNodeTest(step *xpathStep) error {
+	tok := p.next()
+	switch tok.kind {
+	case xtokStar:
+		step.test, step.name = testName, "*"
+		return nil
+	case xtokName:
+	default:
+		return p.errorf(tok, "expected node test, got %s", tok)
+	}
+
+	if !p.cur().is(xtokPunct, "(") {
+		step.test = testName
+		step.name = localName(tok.text)
+		return nil
+	}
+
+	switch tok.text {
+	case "node":
+		step.test = testNode
+	case "text":
+		step.test = testText
+	case "comment":
+		step.test = testComment
+	case "processing-instruction":
+		step.test = testProcessingInstruction
+	default:
+		return p.errorf(tok, "unexpected function call %s() in location step", tok.text)
+	}
+
+	p.next()
+	if step.test == testProcessingInstruction && p.cur().kind == xtokLiteral {
+		p.next()
+	}
+	return p.expect(")")
+}
+
+func (p *xpathParser) parsePredicate() (xpathExpr, error) {
+	p.next() // 跳过 '['
+	pred, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+	return pred, nil
+}
+
+func (p *xpathParser) parseFilter() (xpathExpr, error) {
+	tok := p.cur()
+	primary, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if !p.cur().is(xtokPunct, "[") {
+		return primary, nil
+	}
+
+	if primary.resultType() != xpathNodeSet {
+		return nil, p.errorf(tok, "predicate requires a node-set")
+	}
+	filter := &filterExpr{primary: primary}
+	for p.cur().is(xtokPunct, "[") {
+		pred, err := p.parsePredicate()
+		if err != nil {
+			return nil, err
+		}
+		filter.predicates = append(filter.predicates, pred)
+	}
+	return filter, nil
+}
+
+func (p *xpathParser) parsePrimary() (xpathExpr, error) {
+	tok := p.next()
+	switch tok.kind {
+	case xtokLiteral:
+		return &literalExpr{value: tok.text}, nil
+	case xtokNumber:
+		f, _ := strconv.ParseFloat(tok.text, 64)
+		return &numberExpr{value: f}, nil
+	case xtokVariable:
+		return nil, p.errorf(tok, "variables are not supported")
+	case xtokName:
+		return p.parseFunctionCall(tok)
+	}
+
+	// '('
+	e, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (p *xpathParser) parseFunctionCall(name xpathToken) (xpathExpr, error) {
+	fn, ok := xpathFunctions[name.text]
+	if !ok {
+		return nil, p.errorf(name, "unknown function %s()", name.text)
+	}
+
+	p.next() // 跳过 '('
+	var args []xpathExpr
+	if !p.cur().is(xtokPunct, ")") {
+		for {
+			arg, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if !p.cur().is(xtokPunct, ",") {
+				break
+			}
+			p.next()
+		}
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+		return nil, p.errorf(name, "wrong number of arguments to %s(): got %d", name.text, len(args))
+	}
+	if fn.nodeSetArgs {
+		for _, arg := range args {
+			if arg.resultType() != xpathNodeSet {
+				return nil, p.errorf(name, "argument to %s() must be a node-set", name.text)
+			}
+		}
+	}
+	return &functionExpr{fn: fn, args: args}, nil
+}
@@ -0,0 +1,211 @@
+package extract
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+const xpathHTML = `<!DOCTYPE html>
+<html lang="en">
+<body>
+	<table id="x">
+		<tr id="r1"><td>a</td><td>1</td><td>50</td></tr>
+		<tr id="r2"><td>b</td><td>2</td><td>150</td></tr>
+		<tr id="r3"><td>c</td><td>3</td><td>250</td></tr>
+	</table>
+	<div id="d1" class="box"><p id="p1">Hello <b id="b1">World</b></p><!-- note --></div>
+	<div id="d2"><p id="p2">  Second   para </p><a id="a1" href="/one">one</a><a id="a2" href="/two" title="2">two</a></div>
+</body>
+</html>`
+
+func parseXPathDoc(t *testing.T) *html.Node {
+	t.Helper()
+	doc, err := html.Parse(strings.NewReader(xpathHTML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func xpathIDs(t *testing.T, doc *html.Node, expr string) string {
+	t.Helper()
+
+	x, err := CompileXPath(expr)
+	if err != nil {
+		t.Fatalf("CompileXPath(%q) returned error: %v", expr, err)
+	}
+
+	var ids []string
+	for _, n := range x.Select(doc) {
+		switch n.Type {
+		case html.ElementNode:
+			id, _ := attr(n, "id")
+			if id == "" {
+				id = n.Data
+			}
+			ids = append(ids, id)
+		default:
+			ids = append(ids, strings.TrimSpace(n.Data))
+		}
+	}
+	return strings.Join(ids, " ")
+}
+
+func TestXPath_Select(t *testing.T) {
+	doc := parseXPathDoc(t)
+
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"//table[@id='x']/tr[td[3]>100]", "r2 r3"},
+		{"//table[@id='x']//tr[td[3]>100]", "r2 r3"},
+		{"//table[@id='x']/tbody/tr[1]", "r1"},
+		{"//table/tr[last()]/td[1]/text()", "c"},
+		{"//tr[td[2] = 2]/td[1]/text()", "b"},
+		{"/html/body/div", "d1 d2"},
+		{"//div[@class]", "d1"},
+		{"//p[contains(., 'World')]", "p1"},
+		{"//b/ancestor::div", "d1"},
+		{"//b/ancestor-or-self::*[@id][1]", "b1"},
+		{"//b/ancestor::*[1]", "p1"},
+		{"(//b/ancestor::*)[1]", "html"},
+		{"//a[last()]", "a2"},
+		{"//a[position() = 1]", "a1"},
+		{"//a[2]/@href", "/two"},
+		{"//a/@*", "a1 /one a2 /two 2"},
+		{"//div[1]/comment()", "note"},
+		{"//tr[1]/following-sibling::tr", "r2 r3"},
+		{"//tr[3]/preceding-sibling::tr[1]", "r2"},
+		{"//p[@id='p1']/following::a", "a1 a2"},
+		{"//a[1]/preceding::p", "p1 p2"},
+		{"//b/..", "p1"},
+		{"//b/.", "b1"},
+		{"//p | //b", "p1 b1 p2"},
+		{"//b | //p", "p1 b1 p2"},
+		{"id('a2 d1')", "d1 a2"},
+		{"//*[starts-with(@href, '/t')]", "a2"},
+		{"//p[normalize-space() = 'Second para']", "p2"},
+		{"//tr[not(td[3] > 100)]", "r1"},
+		{"//tr[td[3] mod 100 = 50 and td[2] > 1]", "r2 r3"},
+		{"//td[. = 'a' or . = 'c']/..", "r1 r3"},
+		{"//*[lang('en')][self::table]", "x"},
+		{"//div[count(p) = 1][2]", "d2"},
+		{"//a[@title != '2']", ""},
+		{"//TR[1]", "r1"},
+		{"child::html/child::body/child::div[last()]", "d2"},
+		{"//div/descendant::*[name() = 'b']", "b1"},
+		{"//text()[. = 'two']/parent::node()", "a2"},
+	}
+
+	for _, tt := range tests {
+		if got := xpathIDs(t, doc, tt.expr); got != tt.expected {
+			t.Errorf("xpath %q: expected=%q, got=%q", tt.expr, tt.expected, got)
+		}
+	}
+}
+
+func TestXPath_Evaluate(t *testing.T) {
+	doc := parseXPathDoc(t)
+
+	tests := []struct {
+		expr     string
+		expected interface{}
+	}{
+		{"count(//tr)", 3.0},
+		{"sum(//tr/td[3])", 450.0},
+		{"1 + 2 * 3", 7.0},
+		{"7 div 2", 3.5},
+		{"-7 mod 3", -1.0},
+		{"- -2", 2.0},
+		{"string(//b)", "World"},
+		{"string(//p)", "Hello World"},
+		{"concat('a', 1, true())", "a1true"},
+		{"substring('12345', 2, 3)", "234"},
+		{"substring('12345', 1.5, 2.6)", "234"},
+		{"substring('12345', 0, 3)", "12"},
+		{"substring-before('1999/04/01', '/')", "1999"},
+		{"substring-after('1999/04/01', '/')", "04/01"},
+		{"translate('bar', 'abc', 'ABC')", "BAr"},
+		{"translate('--aaa--', 'abc-', 'ABC')", "AAA"},
+		{"string-length('héllo')", 5.0},
+		{"round(2.5)", 3.0},
+		{"round(-2.5)", -2.0},
+		{"floor(-1.5)", -2.0},
+		{"ceiling(1.2)", 2.0},
+		{"number('  12.5 ')", 12.5},
+		{"string(1 div 0)", "Infinity"},
+		{"string(0.5)", "0.5"},
+		{"string(number('1e3'))", "NaN"},
+		{"boolean('')", false},
+		{"//td = 'c'", true},
+		{"//td = 'z'", false},
+		{"//td != 'a'", true},
+		{"//tr/td[3] > 200", true},
+		{"1 = true()", true},
+		{"'1' = 1.0", true},
+		{"local-name(//a/@href)", "href"},
+		{"name(/)", ""},
+	}
+
+	for _, tt := range tests {
+		x, err := CompileXPath(tt.expr)
+		if err != nil {
+			t.Errorf("CompileXPath(%q) returned error: %v", tt.expr, err)
+			continue
+		}
+		if got := x.Evaluate(doc); got != tt.expected {
+			t.Errorf("xpath %q: expected=%#v, got=%#v", tt.expr, tt.expected, got)
+		}
+	}
+
+	x, _ := CompileXPath("number('abc')")
+	if f, ok := x.Evaluate(doc).(float64); !ok || !math.IsNaN(f) {
+		t.Errorf("number('abc') should be NaN. got=%#v", x.Evaluate(doc))
+	}
+}
+
+func TestXPath_Errors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		message string
+	}{
+		{"", "expected node test"},
+		{"//", "expected node test"},
+		{"//a[", "expected node test"},
+		{"//a[1", `expected "]"`},
+		{"//a)", "unexpected"},
+		{"'abc", "unterminated string literal"},
+		{"//a[#]", "unexpected character"},
+		{"foo(1)", "unknown function foo()"},
+		{"count('a')", "must be a node-set"},
+		{"concat('a')", "wrong number of arguments"},
+		{"'a'/b", "path must start with a node-set"},
+		{"'a' | //b", "must be node-sets"},
+		{"bogus::a", "unknown axis"},
+		{"//a/count(b)", "unexpected function call"},
+		{"$var", "variables are not supported"},
+		{"1[1]", "predicate requires a node-set"},
+	}
+
+	for _, tt := range tests {
+		_, err := CompileXPath(tt.expr)
+		if err == nil {
+			t.Errorf("xpath %q: expected error", tt.expr)
+			continue
+		}
+
+		var selErr *SelectorError
+		if !errors.As(err, &selErr) {
+			t.Errorf("xpath %q: error is not *SelectorError. got=%T", tt.expr, err)
+			continue
+		}
+		if !strings.Contains(selErr.Message, tt.message) {
+			t.Errorf("xpath %q: expected message containing %q, got=%q", tt.expr, tt.message, selErr.Message)
+		}
+	}
+}
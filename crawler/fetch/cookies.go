@@ -0,0 +1,216 @@
+package fetch
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// storedCookie 是保存到文件中的 cookie
+type storedCookie struct {
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain"`
+	Path     string    `json:"path"`
+	HostOnly bool      `json:"host_only,omitempty"` // 只发送给 Domain 本身，不包括子域名
+	Secure   bool      `json:"secure,omitempty"`
+	HttpOnly bool      `json:"http_only,omitempty"`
+	Expires  time.Time `json:"expires,omitempty"` // 零值表示会话 cookie
+}
+
+// CookieJar 是可以保存到文件并重新加载的 cookie 容器，可以被多个 goroutine 同时使用
+// cookie 的匹配规则由 net/http/cookiejar 实现，同时另外记录一份用于保存
+type CookieJar struct {
+	jar *cookiejar.Jar
+
+	mu      sync.Mutex
+	cookies map[string]*storedCookie // 以 domain;path;name 为键
+}
+
+var _ http.CookieJar = (*CookieJar)(nil)
+
+// NewCookieJar 创建空的 cookie 容器
+func NewCookieJar() *CookieJar {
+	jar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	return &CookieJar{jar: jar, cookies: make(map[string]*storedCookie)}
+}
+
+// SetCookies 实现 http.CookieJar 接口
+// 只有 cookiejar 接受的 cookie 才会被记录，例如 Domain 为公共后缀的 cookie 不会被保存
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	host := strings.ToLower(u.Hostname())
+	for _, c := range cookies {
+		sc := &storedCookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   host,
+			Path:     c.Path,
+			HostOnly: true,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+		}
+		if domain := strings.TrimPrefix(strings.ToLower(c.Domain), "."); domain != "" {
+			// IP 地址和公共后缀只接受与主机相同的 Domain，作为仅主机 cookie 保存；
+			// 与请求主机不匹配的 Domain 属性会被 cookiejar 拒绝
+			restricted := net.ParseIP(host) != nil || isPublicSuffix(domain)
+			switch {
+			case restricted && domain == host:
+			case restricted || host != domain && !strings.HasSuffix(host, "."+domain):
+				continue
+			default:
+				sc.Domain, sc.HostOnly = domain, false
+			}
+		}
+		if !strings.HasPrefix(sc.Path, "/") {
+			sc.Path = defaultCookiePath(u.Path)
+		}
+
+		key := sc.Domain + ";" + sc.Path + ";" + sc.Name
+		switch {
+		case c.MaxAge < 0:
+			delete(j.cookies, key)
+			continue
+		case c.MaxAge > 0:
+			sc.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		case !c.Expires.IsZero():
+			if !c.Expires.After(now) {
+				delete(j.cookies, key)
+				continue
+			}
+			sc.Expires = c.Expires
+		}
+		if !j.stored(sc) {
+			continue
+		}
+		j.cookies[key] = sc
+	}
+}
+
+// isPublicSuffix 判断 domain 是否为公共后缀，例如 com 或 co.uk
+func isPublicSuffix(domain string) bool {
+	ps, _ := publicsuffix.PublicSuffix(domain)
+	return ps == domain
+}
+
+// stored 确认 cookiejar 确实保存了 sc，避免记录被其拒绝的 cookie
+func (j *CookieJar) stored(sc *storedCookie) bool {
+	u := &url.URL{Scheme: "https", Host: sc.Domain, Path: sc.Path}
+	if strings.Contains(sc.Domain, ":") {
+		u.Host = "[" + sc.Domain + "]"
+	}
+	for _, c := range j.jar.Cookies(u) {
+		if c.Name == sc.Name && c.Value == sc.Value {
+			return true
+		}
+	}
+	return false
+}
+
+// Cookies 实现 http.CookieJar 接口，返回请求 u 时应发送的 cookie
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// defaultCookiePath 返回没有 Path 属性的 cookie 的默认路径
+func defaultCookiePath(path string) string {
+	i := strings.LastIndex(path, "/")
+	if i <= 0 {
+		return "/"
+	}
+	return path[:i]
+}
+
+// Save 将未过期的 cookie（包括会话 cookie）以 JSON 格式写入文件
+// 先写入临时文件再重命名，写入失败时不会破坏原文件
+func (j *CookieJar) Save(path string) error {
+	j.mu.Lock()
+	now := time.Now()
+	keys := make([]string, 0, len(j.cookies))
+	for key, c := range j.cookies {
+		if c.Expires.IsZero() || c.Expires.After(now) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	cookies := make([]*storedCookie, len(keys))
+	for i, key := range keys {
+		cookies[i] = j.cookies[key]
+	}
+	data, err := json.MarshalIndent(cookies, "", "  ")
+	j.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(append(data, '\n'))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o600)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// Load 从 Save 写入的文件中读取 cookie 并加入容器，已过期的 cookie 被忽略
+func (j *CookieJar) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var cookies []*storedCookie
+	if err := json.Unmarshal(data, &cookies); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, sc := range cookies {
+		if !sc.Expires.IsZero() && !sc.Expires.After(now) {
+			continue
+		}
+		scheme := "http"
+		if sc.Secure {
+			scheme = "https"
+		}
+		u := &url.URL{Scheme: scheme, Host: sc.Domain, Path: sc.Path}
+		c := &http.Cookie{
+			Name:     sc.Name,
+			Value:    sc.Value,
+			Path:     sc.Path,
+			Secure:   sc.Secure,
+			HttpOnly: sc.HttpOnly,
+			Expires:  sc.Expires,
+		}
+		if !sc.HostOnly {
+			c.Domain = sc.Domain
+		}
+		j.SetCookies(u, []*http.Cookie{c})
+	}
+	return nil
+}
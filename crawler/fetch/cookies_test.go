@@ -0,0 +1,166 @@
+package fetch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// newLoginServer 返回的服务器在 /login 设置会话 cookie，/member 只允许带 cookie 的请求
+func newLoginServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: "secret", Path: "/"})
+		w.Write([]byte("logged in"))
+	})
+	mux.HandleFunc("/member", func(w http.ResponseWriter, r *http.Request) {
+		c, err := r.Cookie("sid")
+		if err != nil || c.Value != "secret" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte("welcome"))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestFetcher_Cookies(t *testing.T) {
+	server := newLoginServer()
+	defer server.Close()
+
+	options := DefaultOptions()
+	options.IgnoreRobots = true
+	options.MaxRetries = 0
+	fetcher := NewFetcher(options)
+	other := fetcher.WithJar(NewCookieJar())
+	ctx := context.Background()
+
+	if resp, _ := fetcher.Fetch(ctx, server.URL+"/member"); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("member page before login: status %d, want 403", resp.StatusCode)
+	}
+	if _, err := fetcher.Fetch(ctx, server.URL+"/login"); err != nil {
+		t.Fatalf("login returned error: %v", err)
+	}
+	resp, err := fetcher.Fetch(ctx, server.URL+"/member")
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("member page after login: status %v, err %v", resp, err)
+	}
+
+	// 使用其他 cookie 容器的副本不共享登录状态
+	if resp, _ := other.Fetch(ctx, server.URL+"/member"); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("member page with separate jar: status %d, want 403", resp.StatusCode)
+	}
+
+	// 保存后由新的抓取器加载，无需重新登录
+	path := filepath.Join(t.TempDir(), "cookies.json")
+	if err := fetcher.Jar().Save(path); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	restored := NewFetcher(options)
+	if err := restored.Jar().Load(path); err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if resp, _ := restored.Fetch(ctx, server.URL+"/member"); resp.StatusCode != http.StatusOK {
+		t.Errorf("member page with loaded cookies: status %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestCookieJar_SaveLoad(t *testing.T) {
+	jar := NewCookieJar()
+	site, _ := url.Parse("https://www.example.com/shop/cart")
+	jar.SetCookies(site, []*http.Cookie{
+		{Name: "host", Value: "1"},
+		{Name: "domain", Value: "2", Domain: ".example.com", Path: "/"},
+		{Name: "persistent", Value: "3", Path: "/", Expires: time.Now().Add(time.Hour)},
+		{Name: "expired", Value: "4", Path: "/", Expires: time.Now().Add(-time.Hour)},
+		{Name: "secure", Value: "5", Path: "/", Secure: true},
+		{Name: "foreign", Value: "6", Domain: "other.org", Path: "/"},
+	})
+	jar.SetCookies(site, []*http.Cookie{{Name: "persistent", Path: "/", MaxAge: -1}})
+
+	path := filepath.Join(t.TempDir(), "cookies.json")
+	if err := jar.Save(path); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	loaded := NewCookieJar()
+	if err := loaded.Load(path); err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+
+	tests := []struct {
+		url  string
+		want map[string]string
+	}{
+		{"https://www.example.com/shop/item", map[string]string{"host": "1", "domain": "2", "secure": "5"}},
+		{"http://www.example.com/shop/item", map[string]string{"host": "1", "domain": "2"}},
+		{"https://www.example.com/", map[string]string{"domain": "2", "secure": "5"}},
+		{"https://api.example.com/shop/", map[string]string{"domain": "2"}},
+		{"https://other.org/", map[string]string{}},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		got := map[string]string{}
+		for _, c := range loaded.Cookies(u) {
+			got[c.Name] = c.Value
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: cookies = %v, want %v", tt.url, got, tt.want)
+			continue
+		}
+		for name, value := range tt.want {
+			if got[name] != value {
+				t.Errorf("%s: cookie %s = %q, want %q", tt.url, name, got[name], value)
+			}
+		}
+	}
+
+	if err := loaded.Load(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("Load of missing file returned no error")
+	}
+}
+
+func TestCookieJar_RejectedCookiesAreNotSaved(t *testing.T) {
+	jar := NewCookieJar()
+	site, _ := url.Parse("https://www.example.co.uk/")
+	jar.SetCookies(site, []*http.Cookie{
+		{Name: "ok", Value: "1", Domain: "example.co.uk", Path: "/"},
+		{Name: "suffix", Value: "2", Domain: ".co.uk", Path: "/"},
+		{Name: "tld", Value: "3", Domain: "uk", Path: "/"},
+		{Name: "foreign", Value: "4", Domain: "other.co.uk", Path: "/"},
+		{Name: "dot", Value: "5", Domain: "example.co.uk.", Path: "/"},
+	})
+	ip, _ := url.Parse("http://127.0.0.1/")
+	jar.SetCookies(ip, []*http.Cookie{
+		{Name: "ip", Value: "6", Path: "/"},
+		{Name: "ipdomain", Value: "7", Domain: "127.0.0.2", Path: "/"},
+	})
+
+	path := filepath.Join(t.TempDir(), "cookies.json")
+	if err := jar.Save(path); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved []storedCookie
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, c := range saved {
+		names = append(names, c.Name+"@"+c.Domain)
+	}
+	sort.Strings(names)
+	if want := []string{"ip@127.0.0.1", "ok@example.co.uk"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("saved cookies = %q, want %q", names, want)
+	}
+}
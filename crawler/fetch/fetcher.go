@@ -1,10 +1,12 @@
 package fetch
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -13,7 +15,9 @@ type Response struct {
 	StatusCode int
 	Body       []byte
 	Headers    map[string][]string
-	URL        string
+	URL        string // 请求的 URL
+	FinalURL   string // 跟随重定向后的最终 URL
+	Attempts   int    // 请求次数，包括重试
 	Error      error
 }
 
@@ -24,6 +28,19 @@ type Options struct {
 	FollowRedirect bool
 	MaxRetries    int
 	Headers       map[string]string
+
+	// IgnoreRobots 为 true 时不下载 robots.txt，也不遵守其中的规则和 Crawl-delay，
+	// 只应用于自己的站点
+	IgnoreRobots bool
+
+	// 按主机的限速，FetchBatch 的并发请求同样受其限制
+	PerHostRate        float64 // 每个主机每秒的请求数，0 表示不限
+	PerHostBurst       int     // 允许的突发请求数，小于 1 时为 1
+	PerHostConcurrency int     // 每个主机同时进行的请求数上限，0 表示不限
+	AdaptiveRate       bool    // 主机返回 429/503 时自动增大请求间隔
+
+	// RetryPolicy 决定失败的请求是否重试，为 nil 时使用 DefaultRetryPolicy(MaxRetries)
+	RetryPolicy RetryPolicy
 }
 
 // DefaultOptions 返回默认选项
@@ -34,13 +51,18 @@ func DefaultOptions() Options {
 		FollowRedirect: true,
 		MaxRetries:    3,
 		Headers:       make(map[string]string),
+		AdaptiveRate:  true,
 	}
 }
 
 // Fetcher 表示网页抓取器
+// 每个抓取器有自己的 cookie 容器，响应设置的 cookie 在之后的请求中发送
 type Fetcher struct {
 	client  *http.Client
 	options Options
+	jar     *CookieJar
+	robots  *robotsCache
+	limits  *hostLimits
 }
 
 // NewFetcher 创建新的抓取器
@@ -58,45 +80,165 @@ func NewFetcher(options Options) *Fetcher {
 		},
 	}
 	
+	jar := NewCookieJar()
+	client.Jar = jar
+	
 	return &Fetcher{
 		client:  client,
 		options: options,
+		jar:     jar,
+		robots:  &robotsCache{},
+		limits:  &hostLimits{},
+	}
+}
+
+// Jar 返回抓取器的 cookie 容器
+func (f *Fetcher) Jar() *CookieJar {
+	return f.jar
+}
+
+// WithJar 返回使用 jar 作为 cookie 容器的抓取器副本，用于同时维护多个登录会话
+// 副本与原抓取器共享 robots.txt 缓存和按主机的限速
+func (f *Fetcher) WithJar(jar *CookieJar) *Fetcher {
+	client := *f.client
+	client.Jar = jar
+	return &Fetcher{
+		client:  &client,
+		options: f.options,
+		jar:     jar,
+		robots:  f.robots,
+		limits:  f.limits,
 	}
 }
 
-// Fetch 抓取单个 URL
+// Fetch 以 GET 方法抓取单个 URL
 func (f *Fetcher) Fetch(ctx context.Context, url string) (*Response, error) {
+	return f.Do(ctx, &Request{URL: url})
+}
+
+// Do 发送请求并读取响应
+// 除非设置了 Options.IgnoreRobots，请求前先检查主机的 robots.txt，
+// 被禁止时返回包装了 ErrDisallowed 的错误，设置了 Crawl-delay 时等待相应的间隔。
+// 每次请求（包括重试）都按 Crawl-delay 等待并受所在主机的速率和并发数限制，robots.txt 的下载同样受主机限速，
+// 重试时重新发送相同的请求体；
+// 默认的重试策略不重试 POST 等非幂等的请求
+func (f *Fetcher) Do(ctx context.Context, r *Request) (*Response, error) {
+	return f.do(ctx, r, nil)
+}
+
+// do 实现 Do；inflight 不为 nil 时，每次发出请求（包括下载 robots.txt）前占用其中一个槽，
+// 等待主机的并发槽、速率限制和重试间隔时不占用
+func (f *Fetcher) do(ctx context.Context, r *Request, inflight chan struct{}) (*Response, error) {
 	var resp *http.Response
-	var err error
+	
+	url, err := r.target()
+	if err != nil {
+		return nil, err
+	}
+	body, contentType, err := r.encodeBody()
+	if err != nil {
+		return nil, err
+	}
+	
+	var crawlDelay func(context.Context) error
+	if !f.options.IgnoreRobots {
+		if crawlDelay, err = f.checkRobots(ctx, url, inflight); err != nil {
+			return nil, err
+		}
+	}
+	
+	host := f.limiter(url)
+	defer f.limits.done(host)
+	if err := host.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer host.release()
+	
+	held := false
+	defer func() {
+		if held {
+			<-inflight
+		}
+	}()
+	
+	policy := f.options.RetryPolicy
+	if policy == nil {
+		policy = DefaultRetryPolicy(f.options.MaxRetries)
+	}
+	start := time.Now()
+	attempts := 0
 	
 	// 重试逻辑
-	for i := 0; i <= f.options.MaxRetries; i++ {
-		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	for {
+		if crawlDelay != nil {
+			if err := crawlDelay(ctx); err != nil {
+				return nil, err
+			}
+		}
+		if err := host.wait(ctx); err != nil {
+			return nil, err
+		}
+		if inflight != nil {
+			if err := acquireSlot(ctx, inflight); err != nil {
+				return nil, err
+			}
+			held = true
+		}
+		
+		var reqBody io.Reader
+		if body != nil {
+			reqBody = bytes.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, r.method(), url, reqBody)
 		if err != nil {
 			return nil, err
 		}
 		
-		// 设置请求头
+		// 设置请求头，单个请求的请求头优先
 		req.Header.Set("User-Agent", f.options.UserAgent)
 		for k, v := range f.options.Headers {
 			req.Header.Set(k, v)
 		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		for k, v := range r.Header {
+			req.Header.Set(k, v)
+		}
 		
+		attempts++
 		resp, err = f.client.Do(req)
 		if err == nil {
+			host.observe(resp.StatusCode)
+		} else if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		
+		delay, retry := policy.Retry(attempts, req, resp, err, time.Since(start))
+		if !retry {
+			if err != nil {
+				return nil, err
+			}
 			break
 		}
 		
-		// 如果是最后一次重试，返回错误
-		if i == f.options.MaxRetries {
-			return nil, err
+		// 丢弃不再使用的响应，使连接可以复用
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		if held {
+			<-inflight
+			held = false
 		}
 		
 		// 等待一段时间后重试
+		timer := time.NewTimer(delay)
 		select {
 		case <-ctx.Done():
+			timer.Stop()
 			return nil, ctx.Err()
-		case <-time.After(time.Duration(i+1) * time.Second):
+		case <-timer.C:
 			// 继续重试
 		}
 	}
@@ -104,57 +246,100 @@ func (f *Fetcher) Fetch(ctx context.Context, url string) (*Response, error) {
 	defer resp.Body.Close()
 	
 	// 读取响应体
-	body, err := io.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
 	
 	return &Response{
 		StatusCode: resp.StatusCode,
-		Body:       body,
+		Body:       respBody,
 		Headers:    resp.Header,
 		URL:        url,
+		FinalURL:   resp.Request.URL.String(),
+		Attempts:   attempts,
 		Error:      nil,
 	}, nil
 }
 
-// FetchBatch 批量抓取 URL
+// FetchBatch 批量抓取 URL，同时进行的请求最多 concurrency 个
+// URL 按主机分组处理，等待主机的并发槽、速率限制或降速间隔时不占用全局的并发数，
+// 因此慢速或被降速的主机不会阻塞其他主机的请求。每个主机最多有 concurrency 个等待中的请求，
+// 主机很多时等待的 goroutine 随主机数增长。
+// 所有请求结束后通道关闭；ctx 取消后不再发出请求，尚未发送的结果被丢弃
 func (f *Fetcher) FetchBatch(ctx context.Context, urls []string, concurrency int) <-chan *Response {
 	results := make(chan *Response)
+	if concurrency < 1 {
+		concurrency = 1
+	}
 	
-	// 限制并发数的信号量
-	semaphore := make(chan struct{}, concurrency)
+	// 所有主机共享的全局并发槽
+	inflight := make(chan struct{}, concurrency)
 	
-	go func() {
-		defer close(results)
+	// 按主机分组，组内保持原来的顺序
+	var hosts []string
+	groups := make(map[string][]string)
+	for _, url := range urls {
+		key, _ := hostKey(url)
+		if _, ok := groups[key]; !ok {
+			hosts = append(hosts, key)
+		}
+		groups[key] = append(groups[key], url)
+	}
+	
+	var wg sync.WaitGroup
+	for _, key := range hosts {
+		queue := make(chan string, len(groups[key]))
+		for _, url := range groups[key] {
+			queue <- url
+		}
+		close(queue)
+		
+		workers := concurrency
+		if n := f.options.PerHostConcurrency; n > 0 && n < workers {
+			workers = n
+		}
+		if n := len(groups[key]); n < workers {
+			workers = n
+		}
 		
-		for _, url := range urls {
-			select {
-			case <-ctx.Done():
-				return
-			case semaphore <- struct{}{}: // 获取信号量
-				go func(url string) {
-					defer func() { <-semaphore } // 释放信号量
-					
-					resp, err := f.Fetch(ctx, url)
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for url := range queue {
+					if ctx.Err() != nil {
+						return
+					}
+					resp, err := f.do(ctx, &Request{URL: url}, inflight)
 					if err != nil {
-						results <- &Response{
-							URL:   url,
-							Error: err,
-						}
+						resp = &Response{URL: url, Error: err}
+					}
+					select {
+					case results <- resp:
+					case <-ctx.Done():
 						return
 					}
-					
-					results <- resp
-				}(url)
-			}
-		}
-		
-		// 等待所有 goroutine 完成
-		for i := 0; i < concurrency; i++ {
-			semaphore <- struct{}{}
+				}
+			}()
 		}
+	}
+	
+	// 所有 worker 退出后才关闭通道
+	go func() {
+		wg.Wait()
+		close(results)
 	}()
 	
 	return results
 }
+
+// acquireSlot 占用信号量中的一个槽，上下文取消时返回错误
+func acquireSlot(ctx context.Context, slots chan struct{}) error {
+	select {
+	case slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -132,3 +132,31 @@ func TestFetcher_Timeout(t *testing.T) {
 		t.Errorf("Expected timeout error, got: %v", err)
 	}
 }
+
+func TestFetcher_FinalURL(t *testing.T) {
+	// 创建带重定向的测试服务器
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusFound)
+	})
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("moved"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	
+	fetcher := NewFetcher(DefaultOptions())
+	
+	resp, err := fetcher.Fetch(context.Background(), server.URL+"/old")
+	if err != nil {
+		t.Fatalf("Fetch returned error: %v", err)
+	}
+	
+	if resp.URL != server.URL+"/old" {
+		t.Errorf("URL wrong. got=%q, want=%q", resp.URL, server.URL+"/old")
+	}
+	
+	if resp.FinalURL != server.URL+"/new" {
+		t.Errorf("FinalURL wrong. got=%q, want=%q", resp.FinalURL, server.URL+"/new")
+	}
+}
@@ -0,0 +1,225 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 主机返回 429 或 503 时的自适应降速参数
+const (
+	minHostPenalty = 500 * time.Millisecond // 第一次降速时请求间增加的间隔
+	maxHostPenalty = time.Minute            // 间隔的上限
+)
+
+// minHostSweep 是清理空闲限速器前至少保存的主机数
+const minHostSweep = 64
+
+// hostLimiter 限制对同一主机的请求速率和并发数
+// 速率使用令牌桶，主机返回 429/503 时请求间隔加倍，之后每个成功的响应使间隔减半
+type hostLimiter struct {
+	rate     float64       // 每秒令牌数，0 表示不限
+	burst    float64       // 桶容量
+	slots    chan struct{} // 并发槽，nil 表示不限
+	adaptive bool
+	refs     int // 正在使用的请求数，由 hostLimits.mu 保护
+
+	mu      sync.Mutex
+	tokens  float64
+	last    time.Time     // 上次补充令牌的时间
+	penalty time.Duration // 自适应降速增加的请求间隔
+	next    time.Time     // 降速时下一次可以请求的时间
+}
+
+// hostLimits 按主机保存限速状态，由同一个 Fetcher 的所有请求共享
+// 主机数超过上次清理后的两倍时删除空闲的限速器，即没有进行中的请求、令牌已补满且没有降速的主机，
+// 因此保存的主机数与最近活跃的主机数成正比
+type hostLimits struct {
+	mu      sync.Mutex
+	hosts   map[string]*hostLimiter
+	sweepAt int // 下一次清理时的主机数
+}
+
+// hostKey 返回 rawURL 的限速主机名，URL 没有主机时返回 false
+func hostKey(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "", false
+	}
+	return strings.ToLower(u.Host), true
+}
+
+// limiter 返回 rawURL 所在主机的限速器，URL 没有主机时返回 nil
+// 请求结束后必须调用 f.limits.done 归还
+func (f *Fetcher) limiter(rawURL string) *hostLimiter {
+	key, ok := hostKey(rawURL)
+	if !ok {
+		return nil
+	}
+
+	f.limits.mu.Lock()
+	defer f.limits.mu.Unlock()
+
+	if f.limits.hosts == nil {
+		f.limits.hosts = make(map[string]*hostLimiter)
+	}
+	l, ok := f.limits.hosts[key]
+	if !ok {
+		if len(f.limits.hosts) >= f.limits.sweepAt {
+			f.limits.sweep(time.Now())
+		}
+		l = newHostLimiter(f.options)
+		f.limits.hosts[key] = l
+	}
+	l.refs++
+	return l
+}
+
+// done 归还 limiter 返回的限速器
+func (h *hostLimits) done(l *hostLimiter) {
+	if l == nil {
+		return
+	}
+	h.mu.Lock()
+	l.refs--
+	h.mu.Unlock()
+}
+
+// sweep 删除空闲的限速器，调用时必须持有 h.mu
+func (h *hostLimits) sweep(now time.Time) {
+	for key, l := range h.hosts {
+		if l.refs == 0 && l.idle(now) {
+			delete(h.hosts, key)
+		}
+	}
+	h.sweepAt = 2 * len(h.hosts)
+	if h.sweepAt < minHostSweep {
+		h.sweepAt = minHostSweep
+	}
+}
+
+func newHostLimiter(options Options) *hostLimiter {
+	burst := options.PerHostBurst
+	if burst <= 0 {
+		burst = 1
+	}
+	l := &hostLimiter{
+		rate:     options.PerHostRate,
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		last:     time.Now(),
+		adaptive: options.AdaptiveRate,
+	}
+	if options.PerHostConcurrency > 0 {
+		l.slots = make(chan struct{}, options.PerHostConcurrency)
+	}
+	return l
+}
+
+// idle 判断限速器是否与新建的限速器等价：令牌已补满且没有降速
+func (l *hostLimiter) idle(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.penalty > 0 || l.next.After(now) {
+		return false
+	}
+	return l.rate <= 0 || l.tokens+now.Sub(l.last).Seconds()*l.rate >= l.burst
+}
+
+// acquire 占用一个并发槽，上下文取消时返回错误
+func (l *hostLimiter) acquire(ctx context.Context) error {
+	if l == nil || l.slots == nil {
+		return nil
+	}
+	return acquireSlot(ctx, l.slots)
+}
+
+// release 释放 acquire 占用的并发槽
+func (l *hostLimiter) release() {
+	if l == nil || l.slots == nil {
+		return
+	}
+	<-l.slots
+}
+
+// wait 在发出一次请求前等待令牌和降速间隔
+func (l *hostLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	d := l.reserve(time.Now())
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// reserve 预订一次请求，返回需要等待的时间
+// 令牌可以透支，等待时间按透支量计算，使并发的请求依次排队
+func (l *hostLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var d time.Duration
+	if l.rate > 0 {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+		l.last = now
+		l.tokens--
+		if l.tokens < 0 {
+			d = time.Duration(-l.tokens / l.rate * float64(time.Second))
+		}
+	}
+
+	if l.penalty > 0 {
+		start := now
+		if l.next.After(start) {
+			start = l.next
+		}
+		l.next = start.Add(l.penalty)
+		if wait := start.Sub(now); wait > d {
+			d = wait
+		}
+	}
+	return d
+}
+
+// observe 根据响应状态调整降速间隔：429/503 时加倍，其他响应时减半
+func (l *hostLimiter) observe(status int) {
+	if l == nil || !l.adaptive {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable {
+		switch {
+		case l.penalty == 0:
+			l.penalty = minHostPenalty
+		case l.penalty < maxHostPenalty:
+			l.penalty *= 2
+		}
+		if l.penalty > maxHostPenalty {
+			l.penalty = maxHostPenalty
+		}
+		return
+	}
+
+	l.penalty /= 2
+	if l.penalty < minHostPenalty {
+		l.penalty = 0
+	}
+}
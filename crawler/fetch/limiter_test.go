@@ -0,0 +1,286 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHostLimiter_Reserve(t *testing.T) {
+	options := DefaultOptions()
+	options.PerHostRate = 10
+	options.PerHostBurst = 2
+	l := newHostLimiter(options)
+
+	now := l.last
+	waits := []time.Duration{0, 0, 100 * time.Millisecond, 200 * time.Millisecond}
+	for i, want := range waits {
+		if got := l.reserve(now); got != want {
+			t.Errorf("reservation %d: wait = %v, want %v", i, got, want)
+		}
+	}
+
+	// 经过足够的时间后令牌补满，但不超过桶容量
+	later := now.Add(10 * time.Second)
+	for i, want := range []time.Duration{0, 0, 100 * time.Millisecond} {
+		if got := l.reserve(later); got != want {
+			t.Errorf("reservation after refill %d: wait = %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestHostLimiter_Adaptive(t *testing.T) {
+	l := newHostLimiter(DefaultOptions())
+
+	steps := []struct {
+		status int
+		want   time.Duration
+	}{
+		{http.StatusOK, 0},
+		{http.StatusTooManyRequests, minHostPenalty},
+		{http.StatusServiceUnavailable, 2 * minHostPenalty},
+		{http.StatusTooManyRequests, 4 * minHostPenalty},
+		{http.StatusOK, 2 * minHostPenalty},
+		{http.StatusNotFound, minHostPenalty},
+		{http.StatusOK, 0},
+	}
+	for i, step := range steps {
+		l.observe(step.status)
+		if l.penalty != step.want {
+			t.Errorf("step %d (status %d): penalty = %v, want %v", i, step.status, l.penalty, step.want)
+		}
+	}
+
+	for i := 0; i < 20; i++ {
+		l.observe(http.StatusServiceUnavailable)
+	}
+	if l.penalty != maxHostPenalty {
+		t.Errorf("penalty not capped. got=%v, want=%v", l.penalty, maxHostPenalty)
+	}
+
+	// 降速期间请求按间隔依次排队
+	now := time.Now()
+	if d := l.reserve(now); d != 0 {
+		t.Errorf("first reservation wait = %v, want 0", d)
+	}
+	if d := l.reserve(now); d != maxHostPenalty {
+		t.Errorf("second reservation wait = %v, want %v", d, maxHostPenalty)
+	}
+
+	options := DefaultOptions()
+	options.AdaptiveRate = false
+	l = newHostLimiter(options)
+	l.observe(http.StatusTooManyRequests)
+	if l.penalty != 0 {
+		t.Errorf("penalty set with AdaptiveRate disabled: %v", l.penalty)
+	}
+}
+
+// newConcurrencyServer 返回记录最大同时请求数的测试服务器
+func newConcurrencyServer(delay time.Duration) (*httptest.Server, func() int) {
+	var mu sync.Mutex
+	active, peak := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		active++
+		if active > peak {
+			peak = active
+		}
+		mu.Unlock()
+
+		time.Sleep(delay)
+
+		mu.Lock()
+		active--
+		mu.Unlock()
+		w.Write([]byte("ok"))
+	}))
+	return server, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return peak
+	}
+}
+
+func TestFetcher_PerHostConcurrency(t *testing.T) {
+	serverA, peakA := newConcurrencyServer(30 * time.Millisecond)
+	defer serverA.Close()
+	serverB, peakB := newConcurrencyServer(30 * time.Millisecond)
+	defer serverB.Close()
+
+	var urls []string
+	for i := 0; i < 8; i++ {
+		urls = append(urls, fmt.Sprintf("%s/a/%d", serverA.URL, i), fmt.Sprintf("%s/b/%d", serverB.URL, i))
+	}
+
+	options := DefaultOptions()
+	options.MaxRetries = 0
+	options.PerHostConcurrency = 2
+	fetcher := NewFetcher(options)
+
+	count := 0
+	for resp := range fetcher.FetchBatch(context.Background(), urls, 10) {
+		if resp.Error != nil {
+			t.Errorf("FetchBatch returned error for %s: %v", resp.URL, resp.Error)
+		}
+		count++
+	}
+	if count != len(urls) {
+		t.Errorf("FetchBatch returned %d responses, want %d", count, len(urls))
+	}
+	// 全局并发为 10，但每个主机同时最多 2 个请求
+	if peakA() != 2 || peakB() != 2 {
+		t.Errorf("peak concurrency per host = %d, %d, want 2, 2", peakA(), peakB())
+	}
+}
+
+func TestFetcher_PerHostRate(t *testing.T) {
+	server, _ := newConcurrencyServer(0)
+	defer server.Close()
+
+	options := DefaultOptions()
+	options.MaxRetries = 0
+	options.PerHostRate = 20
+	fetcher := NewFetcher(options)
+
+	// Fetch 和 FetchBatch 共享同一个令牌桶
+	start := time.Now()
+	if _, err := fetcher.Fetch(context.Background(), server.URL+"/"); err != nil {
+		t.Fatalf("Fetch returned error: %v", err)
+	}
+	urls := []string{server.URL + "/1", server.URL + "/2", server.URL + "/3", server.URL + "/4"}
+	for resp := range fetcher.FetchBatch(context.Background(), urls, 4) {
+		if resp.Error != nil {
+			t.Errorf("FetchBatch returned error for %s: %v", resp.URL, resp.Error)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 190*time.Millisecond {
+		t.Errorf("5 requests at 20/s took %v, want at least 200ms", elapsed)
+	}
+
+	// 等待令牌时取消上下文立即返回
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := fetcher.Fetch(ctx, server.URL+"/"); err == nil {
+		t.Errorf("Fetch with canceled context returned no error")
+	}
+}
+
+func TestFetcher_FetchBatchSlowHost(t *testing.T) {
+	slow, _ := newConcurrencyServer(300 * time.Millisecond)
+	defer slow.Close()
+	fast, _ := newConcurrencyServer(0)
+	defer fast.Close()
+
+	// 慢速主机的 URL 排在前面，且每次只能进行一个请求
+	var urls []string
+	for i := 0; i < 4; i++ {
+		urls = append(urls, fmt.Sprintf("%s/slow/%d", slow.URL, i))
+	}
+	for i := 0; i < 4; i++ {
+		urls = append(urls, fmt.Sprintf("%s/fast/%d", fast.URL, i))
+	}
+
+	options := DefaultOptions()
+	options.MaxRetries = 0
+	options.PerHostConcurrency = 1
+	fetcher := NewFetcher(options)
+
+	start := time.Now()
+	fastDone := 0
+	var fastElapsed time.Duration
+	for resp := range fetcher.FetchBatch(context.Background(), urls, 2) {
+		if resp.Error != nil {
+			t.Errorf("FetchBatch returned error for %s: %v", resp.URL, resp.Error)
+		}
+		if strings.Contains(resp.URL, "/fast/") {
+			if fastDone++; fastDone == 4 {
+				fastElapsed = time.Since(start)
+			}
+		}
+	}
+	if fastDone != 4 {
+		t.Fatalf("got %d responses from the fast host, want 4", fastDone)
+	}
+	if fastElapsed > 250*time.Millisecond {
+		t.Errorf("fast host finished after %v, want it not to wait for the slow host", fastElapsed)
+	}
+}
+
+func TestFetcher_FetchBatchCancel(t *testing.T) {
+	server, _ := newConcurrencyServer(20 * time.Millisecond)
+	defer server.Close()
+
+	var urls []string
+	for i := 0; i < 40; i++ {
+		urls = append(urls, fmt.Sprintf("%s/%d", server.URL, i))
+	}
+
+	options := DefaultOptions()
+	options.MaxRetries = 0
+	ctx, cancel := context.WithCancel(context.Background())
+	results := NewFetcher(options).FetchBatch(ctx, urls, 4)
+
+	<-results
+	cancel()
+
+	// 取消后通道在所有 worker 退出后关闭，不会向已关闭的通道发送
+	done := make(chan int)
+	go func() {
+		n := 1
+		for range results {
+			n++
+		}
+		done <- n
+	}()
+	select {
+	case n := <-done:
+		if n == len(urls) {
+			t.Errorf("all %d URLs were fetched after cancel", n)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("results channel not closed after cancel")
+	}
+}
+
+func TestHostLimits_Sweep(t *testing.T) {
+	options := DefaultOptions()
+	options.PerHostRate = 1
+	fetcher := NewFetcher(options)
+
+	busy := fetcher.limiter("http://busy.example/")
+	penalized := fetcher.limiter("http://penalized.example/")
+	penalized.observe(http.StatusTooManyRequests)
+	fetcher.limits.done(penalized)
+	drained := fetcher.limiter("http://drained.example/")
+	drained.reserve(time.Now())
+	fetcher.limits.done(drained)
+
+	for i := 0; i < 3*minHostSweep; i++ {
+		l := fetcher.limiter(fmt.Sprintf("http://host%d.example/", i))
+		l.last = l.last.Add(-time.Hour)
+		fetcher.limits.done(l)
+	}
+
+	hosts := fetcher.limits.hosts
+	if len(hosts) >= 3*minHostSweep {
+		t.Errorf("idle limiters were not evicted: %d hosts", len(hosts))
+	}
+	for _, key := range []string{"busy.example", "penalized.example", "drained.example"} {
+		if _, ok := hosts[key]; !ok {
+			t.Errorf("limiter for %s evicted while in use or limited", key)
+		}
+	}
+	if fetcher.limiter("http://busy.example/") != busy {
+		t.Errorf("limiter() returned a new limiter for a host in use")
+	}
+}
@@ -0,0 +1,150 @@
+package fetch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// FilePart 表示 multipart 请求体中上传的文件
+type FilePart struct {
+	Field       string // 表单字段名
+	Filename    string
+	ContentType string // 为空时为 application/octet-stream
+	Data        []byte
+}
+
+// Request 表示一个 HTTP 请求
+// 请求体最多使用 Body、Form（或 Form 与 Files 组成的 multipart）、JSON 中的一种
+type Request struct {
+	Method string // 为空时有请求体则为 POST，否则为 GET
+	URL    string
+	Header map[string]string // 覆盖 Options.Headers 中的同名请求头
+	Query  url.Values        // 追加到 URL 的查询参数
+
+	Body      []byte      // 原始请求体，内容类型由 Header 中的 Content-Type 指定
+	Form      url.Values  // 表单字段，默认编码为 application/x-www-form-urlencoded
+	Files     []FilePart  // 上传的文件，不为空时表单编码为 multipart/form-data
+	Multipart bool        // 没有文件时也使用 multipart/form-data 编码表单
+	JSON      interface{} // 编码为 JSON 的请求体
+}
+
+// method 返回大写的请求方法
+func (r *Request) method() string {
+	if r.Method == "" {
+		if r.hasBody() {
+			return http.MethodPost
+		}
+		return http.MethodGet
+	}
+	return strings.ToUpper(r.Method)
+}
+
+// hasBody 判断请求是否设置了请求体
+func (r *Request) hasBody() bool {
+	return r.Body != nil || r.Form != nil || len(r.Files) > 0 || r.Multipart || r.JSON != nil
+}
+
+// target 返回追加了查询参数的 URL
+func (r *Request) target() (string, error) {
+	if len(r.Query) == 0 {
+		return r.URL, nil
+	}
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return "", err
+	}
+	query := r.Query.Encode()
+	if u.RawQuery != "" {
+		query = u.RawQuery + "&" + query
+	}
+	u.RawQuery = query
+	return u.String(), nil
+}
+
+// encodeBody 返回编码后的请求体及其内容类型，没有请求体时返回 nil
+func (r *Request) encodeBody() ([]byte, string, error) {
+	kinds := 0
+	if r.Body != nil {
+		kinds++
+	}
+	if r.Form != nil || len(r.Files) > 0 || r.Multipart {
+		kinds++
+	}
+	if r.JSON != nil {
+		kinds++
+	}
+	if kinds > 1 {
+		return nil, "", fmt.Errorf("request body: only one of raw body, form and JSON may be set")
+	}
+
+	switch {
+	case r.Body != nil:
+		return r.Body, "", nil
+	case r.JSON != nil:
+		data, err := json.Marshal(r.JSON)
+		if err != nil {
+			return nil, "", fmt.Errorf("request body: %v", err)
+		}
+		return data, "application/json", nil
+	case len(r.Files) > 0 || r.Multipart:
+		return encodeMultipart(r.Form, r.Files)
+	case r.Form != nil:
+		return []byte(r.Form.Encode()), "application/x-www-form-urlencoded", nil
+	}
+	return nil, "", nil
+}
+
+// encodeMultipart 将表单字段和文件编码为 multipart/form-data，字段按名称排序
+func encodeMultipart(form url.Values, files []FilePart) ([]byte, string, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	keys := make([]string, 0, len(form))
+	for k := range form {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range form[k] {
+			if err := w.WriteField(k, v); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+
+	for _, f := range files {
+		contentType := f.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			escapeQuotes(f.Field), escapeQuotes(f.Filename)))
+		h.Set("Content-Type", contentType)
+		part, err := w.CreatePart(h)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := part.Write(f.Data); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), w.FormDataContentType(), nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
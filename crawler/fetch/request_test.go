@@ -0,0 +1,156 @@
+package fetch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newEchoServer 返回的服务器以 "方法 路径?查询 内容类型 请求体" 的形式回显请求
+func newEchoServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s %s %s", r.Method, r.URL.RequestURI(), r.Header.Get("Content-Type"), body)
+	}))
+}
+
+func TestFetcher_Do(t *testing.T) {
+	server := newEchoServer()
+	defer server.Close()
+
+	options := DefaultOptions()
+	options.IgnoreRobots = true
+	options.MaxRetries = 0
+	fetcher := NewFetcher(options)
+
+	tests := []struct {
+		name string
+		req  *Request
+		want string
+	}{
+		{"default GET", &Request{URL: server.URL + "/a"}, "GET /a  "},
+		{"form without method", &Request{URL: server.URL + "/f", Form: url.Values{"a": {"1"}}},
+			"POST /f application/x-www-form-urlencoded a=1"},
+		{"query", &Request{URL: server.URL + "/a?x=1", Query: url.Values{"q": {"go lang"}}}, "GET /a?x=1&q=go+lang  "},
+		{"form", &Request{Method: "post", URL: server.URL + "/login", Form: url.Values{"user": {"bob"}, "pass": {"p&w"}}},
+			"POST /login application/x-www-form-urlencoded pass=p%26w&user=bob"},
+		{"json", &Request{Method: "PUT", URL: server.URL + "/item", JSON: map[string]interface{}{"id": 1}},
+			`PUT /item application/json {"id":1}`},
+		{"raw body", &Request{Method: "POST", URL: server.URL + "/raw", Body: []byte("<x/>"), Header: map[string]string{"Content-Type": "text/xml"}},
+			"POST /raw text/xml <x/>"},
+	}
+	for _, tt := range tests {
+		resp, err := fetcher.Do(context.Background(), tt.req)
+		if err != nil {
+			t.Errorf("%s: Do returned error: %v", tt.name, err)
+			continue
+		}
+		if string(resp.Body) != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, resp.Body, tt.want)
+		}
+	}
+
+	_, err := fetcher.Do(context.Background(), &Request{URL: server.URL, Body: []byte("x"), JSON: 1})
+	if err == nil || !strings.Contains(err.Error(), "only one of") {
+		t.Errorf("conflicting bodies: error = %v", err)
+	}
+}
+
+func TestFetcher_DoMultipart(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		file, header, err := r.FormFile("doc")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(file)
+		fmt.Fprintf(w, "%s|%s|%s|%s|%s", r.FormValue("csrf"), header.Filename, header.Header.Get("Content-Type"), data, r.UserAgent())
+	}))
+	defer server.Close()
+
+	options := DefaultOptions()
+	options.IgnoreRobots = true
+	options.Headers["User-Agent"] = "ignored"
+	resp, err := NewFetcher(options).Do(context.Background(), &Request{
+		Method: "POST",
+		URL:    server.URL,
+		Header: map[string]string{"User-Agent": "Uploader"},
+		Form:   url.Values{"csrf": {"tok"}},
+		Files:  []FilePart{{Field: "doc", Filename: `a "b".txt`, ContentType: "text/plain", Data: []byte("content")}},
+	})
+	if err != nil {
+		t.Fatalf("Do returned error: %v", err)
+	}
+	want := `tok|a "b".txt|text/plain|content|Uploader`
+	if resp.StatusCode != http.StatusOK || string(resp.Body) != want {
+		t.Errorf("got %d %q, want 200 %q", resp.StatusCode, resp.Body, want)
+	}
+}
+
+func TestFetcher_DoRetry(t *testing.T) {
+	// 每个路径的第一次请求返回 503
+	var mu sync.Mutex
+	hits := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		hits[r.URL.Path]++
+		first := hits[r.URL.Path] == 1
+		mu.Unlock()
+		if first {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(payload["name"]))
+	}))
+	defer server.Close()
+
+	options := DefaultOptions()
+	options.IgnoreRobots = true
+	options.AdaptiveRate = false
+	policy := DefaultRetryPolicy(1)
+	policy.InitialDelay = time.Millisecond
+	options.RetryPolicy = policy
+	fetcher := NewFetcher(options)
+
+	// 默认不重试 POST，服务器可能已经处理了第一次请求
+	resp, err := fetcher.Do(context.Background(), &Request{Method: "POST", URL: server.URL, JSON: map[string]string{"name": "bob"}})
+	if err != nil {
+		t.Fatalf("Do returned error: %v", err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Attempts != 1 {
+		t.Errorf("POST: got status %d after %d attempts, want 503 after 1", resp.StatusCode, resp.Attempts)
+	}
+
+	// PUT 是幂等的，重试时重新发送相同的请求体
+	resp, err = fetcher.Do(context.Background(), &Request{Method: "PUT", URL: server.URL + "/put", JSON: map[string]string{"name": "bob"}})
+	if err != nil {
+		t.Fatalf("Do returned error: %v", err)
+	}
+	if string(resp.Body) != "bob" || resp.Attempts != 2 {
+		t.Errorf("PUT: got %q after %d attempts, want \"bob\" after 2", resp.Body, resp.Attempts)
+	}
+
+	// 显式允许时 POST 同样重试
+	policy.RetryNonIdempotent = true
+	resp, err = fetcher.Do(context.Background(), &Request{Method: "POST", URL: server.URL + "/retry", JSON: map[string]string{"name": "alice"}})
+	if err != nil {
+		t.Fatalf("Do returned error: %v", err)
+	}
+	if string(resp.Body) != "alice" || resp.Attempts != 2 {
+		t.Errorf("POST with RetryNonIdempotent: got %q after %d attempts, want \"alice\" after 2", resp.Body, resp.Attempts)
+	}
+}
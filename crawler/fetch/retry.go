@@ -0,0 +1,141 @@
+package fetch
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy 决定一次请求失败后是否重试以及重试前等待多久
+type RetryPolicy interface {
+	// Retry 在第 attempt 次请求（从 1 开始）结束后调用，req 是发出的请求，resp 和 err 恰有一个不为 nil；
+	// elapsed 是从第一次请求开始经过的时间。返回 false 时不再重试
+	Retry(attempt int, req *http.Request, resp *http.Response, err error, elapsed time.Duration) (time.Duration, bool)
+}
+
+// DefaultRetryStatus 是默认重试的状态码
+var DefaultRetryStatus = []int{
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// BackoffPolicy 在网络错误和指定状态码时以指数退避重试
+// 响应带有 Retry-After 时按其等待；等待后会超过 MaxElapsed 时不再重试。
+// 默认只重试幂等的请求（GET、HEAD、OPTIONS、TRACE、PUT、DELETE），
+// 服务器可能已经处理了失败的 POST 等请求，重新发送会重复提交
+type BackoffPolicy struct {
+	MaxRetries   int           // 最多重试次数，不包括第一次请求
+	StatusCodes  []int         // 需要重试的状态码
+	InitialDelay time.Duration // 第一次重试前的等待时间
+	MaxDelay     time.Duration // 单次等待的上限，0 表示不限；不限制 Retry-After
+	Multiplier   float64       // 每次重试等待时间的倍数，小于 1 时为 2
+	Jitter       float64       // 等待时间随机减少的最大比例，取值 0 到 1
+	MaxElapsed   time.Duration // 从第一次请求开始允许的总时间，0 表示不限
+
+	// RetryNonIdempotent 为 true 时 POST、PATCH 等非幂等的请求同样重试
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy 返回最多重试 maxRetries 次的默认策略：
+// 从 1 秒开始翻倍，单次最多 30 秒，随机减少至多一半，总时间不超过 2 分钟
+func DefaultRetryPolicy(maxRetries int) *BackoffPolicy {
+	return &BackoffPolicy{
+		MaxRetries:   maxRetries,
+		StatusCodes:  DefaultRetryStatus,
+		InitialDelay: time.Second,
+		MaxDelay:     30 * time.Second,
+		Multiplier:   2,
+		Jitter:       0.5,
+		MaxElapsed:   2 * time.Minute,
+	}
+}
+
+// Retry 实现 RetryPolicy 接口
+func (p *BackoffPolicy) Retry(attempt int, req *http.Request, resp *http.Response, err error, elapsed time.Duration) (time.Duration, bool) {
+	if attempt > p.MaxRetries {
+		return 0, false
+	}
+	if req != nil && !p.RetryNonIdempotent && !idempotent(req.Method) {
+		return 0, false
+	}
+	if resp != nil && !p.retryStatus(resp.StatusCode) {
+		return 0, false
+	}
+
+	delay := p.backoff(attempt)
+	if resp != nil {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			delay = d
+		}
+	}
+	if p.MaxElapsed > 0 && elapsed+delay > p.MaxElapsed {
+		return 0, false
+	}
+	return delay, true
+}
+
+// idempotent 判断请求方法是否幂等，重复发送与发送一次的效果相同
+func idempotent(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func (p *BackoffPolicy) retryStatus(code int) bool {
+	for _, c := range p.StatusCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff 返回第 attempt 次请求之后的退避时间
+func (p *BackoffPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+	delay := float64(p.InitialDelay) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	if delay > math.MaxInt64/2 {
+		delay = math.MaxInt64 / 2
+	}
+	if p.Jitter > 0 {
+		delay -= delay * math.Min(p.Jitter, 1) * rand.Float64()
+	}
+	return time.Duration(delay)
+}
+
+// parseRetryAfter 解析 Retry-After 头，值可以是秒数或 HTTP 日期
+// 已经过去的日期返回 0
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if d := t.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
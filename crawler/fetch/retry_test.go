@@ -0,0 +1,204 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"120", 2 * time.Minute, true},
+		{" 0 ", 0, true},
+		{"-1", 0, false},
+		{"", 0, false},
+		{"soon", 0, false},
+		{"Wed, 01 May 2024 12:00:30 GMT", 30 * time.Second, true},
+		{"Wednesday, 01-May-24 12:01:00 GMT", time.Minute, true},
+		{"Wed, 01 May 2024 11:00:00 GMT", 0, true},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestBackoffPolicy_Retry(t *testing.T) {
+	policy := &BackoffPolicy{
+		MaxRetries:   4,
+		StatusCodes:  []int{http.StatusServiceUnavailable},
+		InitialDelay: 100 * time.Millisecond,
+		MaxDelay:     time.Second,
+		Multiplier:   3,
+		MaxElapsed:   10 * time.Second,
+	}
+	unavailable := func(retryAfter string) *http.Response {
+		resp := &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{}}
+		if retryAfter != "" {
+			resp.Header.Set("Retry-After", retryAfter)
+		}
+		return resp
+	}
+
+	tests := []struct {
+		name    string
+		attempt int
+		resp    *http.Response
+		elapsed time.Duration
+		delay   time.Duration
+		retry   bool
+	}{
+		{"first retry", 1, unavailable(""), 0, 100 * time.Millisecond, true},
+		{"exponential", 2, unavailable(""), 0, 300 * time.Millisecond, true},
+		{"capped", 4, unavailable(""), 0, time.Second, true},
+		{"max retries", 5, unavailable(""), 0, 0, false},
+		{"network error", 1, nil, 0, 100 * time.Millisecond, true},
+		{"status not retried", 1, &http.Response{StatusCode: http.StatusNotFound}, 0, 0, false},
+		{"success", 1, &http.Response{StatusCode: http.StatusOK}, 0, 0, false},
+		{"retry-after", 1, unavailable("3"), 0, 3 * time.Second, true},
+		{"max elapsed", 2, unavailable(""), 9900 * time.Millisecond, 0, false},
+		{"retry-after beyond max elapsed", 1, unavailable("60"), 0, 0, false},
+	}
+	for _, tt := range tests {
+		var err error
+		if tt.resp == nil {
+			err = context.DeadlineExceeded
+		}
+		delay, retry := policy.Retry(tt.attempt, nil, tt.resp, err, tt.elapsed)
+		if delay != tt.delay || retry != tt.retry {
+			t.Errorf("%s: Retry = %v, %v, want %v, %v", tt.name, delay, retry, tt.delay, tt.retry)
+		}
+	}
+
+	// 默认只重试幂等的请求方法
+	methods := []struct {
+		method string
+		retry  bool
+	}{
+		{http.MethodGet, true},
+		{http.MethodHead, true},
+		{http.MethodOptions, true},
+		{http.MethodPut, true},
+		{http.MethodDelete, true},
+		{http.MethodPost, false},
+		{http.MethodPatch, false},
+	}
+	for _, tt := range methods {
+		req, _ := http.NewRequest(tt.method, "http://example.com/", nil)
+		if _, retry := policy.Retry(1, req, unavailable(""), nil, 0); retry != tt.retry {
+			t.Errorf("%s: retry = %v, want %v", tt.method, retry, tt.retry)
+		}
+	}
+	policy.RetryNonIdempotent = true
+	req, _ := http.NewRequest(http.MethodPost, "http://example.com/", nil)
+	if _, retry := policy.Retry(1, req, nil, context.DeadlineExceeded, 0); !retry {
+		t.Errorf("POST not retried with RetryNonIdempotent set")
+	}
+	policy.RetryNonIdempotent = false
+
+	// 随机抖动只会缩短等待时间
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		delay, _ := policy.Retry(2, nil, unavailable(""), nil, 0)
+		if delay < 150*time.Millisecond || delay > 300*time.Millisecond {
+			t.Fatalf("jittered delay %v out of range [150ms, 300ms]", delay)
+		}
+	}
+}
+
+func TestFetcher_RetryStatus(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&hits, 1) {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	options := DefaultOptions()
+	options.IgnoreRobots = true
+	options.AdaptiveRate = false
+	policy := DefaultRetryPolicy(3)
+	policy.InitialDelay = 10 * time.Millisecond
+	options.RetryPolicy = policy
+
+	resp, err := NewFetcher(options).Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Fetch returned error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || string(resp.Body) != "ok" {
+		t.Errorf("response wrong. got=%d %q", resp.StatusCode, resp.Body)
+	}
+	if resp.Attempts != 3 {
+		t.Errorf("Attempts wrong. got=%d, want=3", resp.Attempts)
+	}
+
+	// 重试次数用完时返回最后一个响应
+	atomic.StoreInt32(&hits, 0)
+	policy.MaxRetries = 1
+	resp, err = NewFetcher(options).Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Fetch returned error: %v", err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Attempts != 2 {
+		t.Errorf("exhausted retries: got status %d after %d attempts, want 503 after 2", resp.StatusCode, resp.Attempts)
+	}
+}
+
+// countingPolicy 记录调用次数，从不重试
+type countingPolicy struct {
+	calls int
+	err   error
+}
+
+func (p *countingPolicy) Retry(attempt int, req *http.Request, resp *http.Response, err error, elapsed time.Duration) (time.Duration, bool) {
+	p.calls++
+	p.err = err
+	return 0, false
+}
+
+func TestFetcher_CustomRetryPolicy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	policy := &countingPolicy{}
+	options := DefaultOptions()
+	options.IgnoreRobots = true
+	options.RetryPolicy = policy
+
+	resp, err := NewFetcher(options).Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Fetch returned error: %v", err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Attempts != 1 || policy.calls != 1 {
+		t.Errorf("got status %d, %d attempts, %d policy calls, want 503, 1, 1", resp.StatusCode, resp.Attempts, policy.calls)
+	}
+
+	// 网络错误同样交给策略判断
+	server.Close()
+	if _, err := NewFetcher(options).Fetch(context.Background(), server.URL); err == nil {
+		t.Fatalf("Fetch from closed server returned no error")
+	}
+	if policy.calls != 2 || policy.err == nil {
+		t.Errorf("policy not consulted for network error: calls=%d, err=%v", policy.calls, policy.err)
+	}
+}
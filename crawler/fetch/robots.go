@@ -0,0 +1,358 @@
+package fetch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrDisallowed 表示 URL 被 robots.txt 禁止抓取，可以用 errors.Is 判断
+var ErrDisallowed = errors.New("disallowed by robots.txt")
+
+// maxRobotsSize 是读取 robots.txt 的最大字节数，超出部分被忽略
+const maxRobotsSize = 500 << 10
+
+// robotsUnavailableTTL 是 robots.txt 返回 5xx 时禁止抓取的缓存时间，过期后重新下载
+var robotsUnavailableTTL = time.Minute
+
+// robotsRule 是一条 Allow 或 Disallow 规则
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// robotsGroup 是适用于一组 User-agent 的规则
+type robotsGroup struct {
+	agents     []string // 小写的 User-agent 名称
+	rules      []robotsRule
+	crawlDelay time.Duration
+	hasDelay   bool
+}
+
+// Robots 表示解析后的 robots.txt
+type Robots struct {
+	groups []*robotsGroup
+}
+
+// ParseRobots 解析 robots.txt 内容
+// 连续的 User-agent 行共用其后的规则；无法识别的行被忽略
+func ParseRobots(data []byte) *Robots {
+	r := &Robots{}
+	var group *robotsGroup
+	inAgents := false // 上一条有效记录是否为 User-agent
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 4096), maxRobotsSize)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				group = &robotsGroup{}
+				r.groups = append(r.groups, group)
+			}
+			group.agents = append(group.agents, strings.ToLower(value))
+			inAgents = true
+		case "allow", "disallow":
+			inAgents = false
+			// 空的 Disallow 表示不限制，不产生规则
+			if group == nil || value == "" {
+				continue
+			}
+			group.rules = append(group.rules, robotsRule{allow: key == "allow", pattern: value})
+		case "crawl-delay":
+			inAgents = false
+			if group == nil {
+				continue
+			}
+			if secs, err := strconv.ParseFloat(value, 64); err == nil && secs >= 0 {
+				group.crawlDelay = time.Duration(secs * float64(time.Second))
+				group.hasDelay = true
+			}
+		default:
+			// Sitemap 等与分组无关的记录
+			inAgents = false
+		}
+	}
+	return r
+}
+
+// groupsFor 返回适用于 userAgent 的规则组
+// 名称最长的匹配组优先，同名的组合并；没有匹配时使用 "*" 组
+func (r *Robots) groupsFor(userAgent string) []*robotsGroup {
+	name := strings.ToLower(userAgent)
+	if i := strings.IndexByte(name, '/'); i >= 0 {
+		name = name[:i]
+	}
+	name = strings.TrimSpace(name)
+
+	var matched, wildcard []*robotsGroup
+	best := 0
+	for _, g := range r.groups {
+		for _, agent := range g.agents {
+			if agent == "*" {
+				wildcard = append(wildcard, g)
+				break
+			}
+			if agent == "" || !strings.Contains(name, agent) || len(agent) < best {
+				continue
+			}
+			if len(agent) > best {
+				best = len(agent)
+				matched = matched[:0]
+			}
+			matched = append(matched, g)
+			break
+		}
+	}
+	if len(matched) > 0 {
+		return matched
+	}
+	return wildcard
+}
+
+// Allowed 判断 userAgent 能否抓取 u
+// 匹配最长的规则生效，长度相同时 Allow 优先；规则支持 * 通配符和表示结尾的 $
+func (r *Robots) Allowed(userAgent string, u *url.URL) bool {
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	// /robots.txt 本身总是允许
+	if path == "/robots.txt" {
+		return true
+	}
+
+	allowed, longest := true, -1
+	for _, g := range r.groupsFor(userAgent) {
+		for _, rule := range g.rules {
+			if !matchRobotsPattern(rule.pattern, path) {
+				continue
+			}
+			n := len(rule.pattern)
+			if n > longest || (n == longest && rule.allow) {
+				allowed, longest = rule.allow, n
+			}
+		}
+	}
+	return allowed
+}
+
+// CrawlDelay 返回适用于 userAgent 的 Crawl-delay，未设置时返回 false
+func (r *Robots) CrawlDelay(userAgent string) (time.Duration, bool) {
+	for _, g := range r.groupsFor(userAgent) {
+		if g.hasDelay {
+			return g.crawlDelay, true
+		}
+	}
+	return 0, false
+}
+
+// matchRobotsPattern 判断路径是否以 pattern 开头，* 匹配任意字符序列，末尾的 $ 匹配路径结尾
+func matchRobotsPattern(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = pattern[:len(pattern)-1]
+	}
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+	for i, part := range parts[1:] {
+		// 锚定时最后一段必须出现在路径末尾
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(path[pos:], part)
+		}
+		j := strings.Index(path[pos:], part)
+		if j < 0 {
+			return false
+		}
+		pos += j + len(part)
+	}
+	return !anchored || pos == len(path)
+}
+
+// robotsEntry 是一个主机的 robots.txt 缓存项
+type robotsEntry struct {
+	once    sync.Once
+	robots  *Robots
+	err     error
+	expires time.Time // 为零时不过期
+
+	mu   sync.Mutex
+	next time.Time // 按 Crawl-delay 下一次可以请求的时间
+}
+
+// robotsCache 按协议和主机缓存 robots.txt
+type robotsCache struct {
+	mu      sync.Mutex
+	entries map[string]*robotsEntry
+}
+
+// entry 返回主机的缓存项，第一次访问时下载 robots.txt
+func (c *robotsCache) entry(ctx context.Context, f *Fetcher, u *url.URL, inflight chan struct{}) (*robotsEntry, error) {
+	key := strings.ToLower(u.Scheme + "://" + u.Host)
+
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = make(map[string]*robotsEntry)
+	}
+	e, ok := c.entries[key]
+	if !ok || e.expired(time.Now()) {
+		e = &robotsEntry{}
+		c.entries[key] = e
+	}
+	c.mu.Unlock()
+
+	e.once.Do(func() {
+		var ttl time.Duration
+		e.robots, ttl, e.err = f.fetchRobots(ctx, key+"/robots.txt", inflight)
+		if ttl > 0 {
+			e.mu.Lock()
+			e.expires = time.Now().Add(ttl)
+			e.mu.Unlock()
+		}
+	})
+	if e.err != nil {
+		// 网络错误不缓存，下次请求时重新下载
+		c.mu.Lock()
+		if c.entries[key] == e {
+			delete(c.entries, key)
+		}
+		c.mu.Unlock()
+		return nil, e.err
+	}
+	return e, nil
+}
+
+// expired 判断缓存项是否已过期，正在下载的缓存项不会过期
+func (e *robotsEntry) expired(now time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return !e.expires.IsZero() && now.After(e.expires)
+}
+
+// wait 按 Crawl-delay 等待，同一主机的请求之间至少间隔 delay
+func (e *robotsEntry) wait(ctx context.Context, delay time.Duration) error {
+	e.mu.Lock()
+	now := time.Now()
+	start := now
+	if e.next.After(now) {
+		start = e.next
+	}
+	e.next = start.Add(delay)
+	e.mu.Unlock()
+
+	if d := start.Sub(now); d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return nil
+}
+
+// fetchRobots 下载并解析 robots.txt，ttl 为结果的缓存时间，0 表示一直有效
+// 4xx 表示没有限制；5xx 表示暂时不可访问，此时整个站点在 robotsUnavailableTTL 内视为禁止抓取。
+// 下载与普通请求一样受主机的速率和并发数限制；inflight 不为 nil 时发送期间占用其中一个槽
+func (f *Fetcher) fetchRobots(ctx context.Context, robotsURL string, inflight chan struct{}) (*Robots, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", robotsURL, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("User-Agent", f.options.UserAgent)
+
+	host := f.limiter(robotsURL)
+	defer f.limits.done(host)
+	if err := host.acquire(ctx); err != nil {
+		return nil, 0, err
+	}
+	defer host.release()
+	if err := host.wait(ctx); err != nil {
+		return nil, 0, err
+	}
+	if inflight != nil {
+		if err := acquireSlot(ctx, inflight); err != nil {
+			return nil, 0, err
+		}
+		defer func() { <-inflight }()
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("robots.txt: %w", err)
+	}
+	defer resp.Body.Close()
+	host.observe(resp.StatusCode)
+
+	switch {
+	case resp.StatusCode >= 500:
+		return &Robots{groups: []*robotsGroup{{
+			agents: []string{"*"},
+			rules:  []robotsRule{{allow: false, pattern: "/"}},
+		}}}, robotsUnavailableTTL, nil
+	case resp.StatusCode >= 400:
+		return &Robots{}, 0, nil
+	case resp.StatusCode >= 300:
+		// 未跟随的重定向视为没有 robots.txt
+		return &Robots{}, 0, nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRobotsSize))
+	if err != nil {
+		return nil, 0, fmt.Errorf("robots.txt: %w", err)
+	}
+	return ParseRobots(data), 0, nil
+}
+
+// checkRobots 检查 robots.txt 是否允许抓取 rawURL，返回的 wait 在每次发出请求前
+// 按 Crawl-delay 等待，没有 Crawl-delay 时为 nil
+// 非 http(s) 或无法解析的 URL 不检查，由请求本身报告错误；
+// inflight 不为 nil 时下载 robots.txt 期间占用其中一个槽
+func (f *Fetcher) checkRobots(ctx context.Context, rawURL string, inflight chan struct{}) (wait func(context.Context) error, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, nil
+	}
+
+	e, err := f.robots.entry(ctx, f, u, inflight)
+	if err != nil {
+		return nil, err
+	}
+	if !e.robots.Allowed(f.options.UserAgent, u) {
+		return nil, fmt.Errorf("%s: %w", rawURL, ErrDisallowed)
+	}
+	if delay, ok := e.robots.CrawlDelay(f.options.UserAgent); ok && delay > 0 {
+		return func(ctx context.Context) error {
+			return e.wait(ctx, delay)
+		}, nil
+	}
+	return nil, nil
+}
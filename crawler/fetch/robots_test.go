@@ -0,0 +1,305 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testRobots = `# comment
+User-agent: *
+Disallow: /private/
+Allow: /private/public
+Disallow: /*.pdf$
+Disallow: /search?*q=
+
+User-agent: OtherBot
+User-agent: mydsl
+Disallow: /mydsl-only
+Crawl-delay: 0.5
+
+User-agent: mydsl crawler
+Disallow: /specific
+Allow: /specific/ok
+Disallow:
+Sitemap: https://example.com/sitemap.xml
+`
+
+func TestRobots_Allowed(t *testing.T) {
+	robots := ParseRobots([]byte(testRobots))
+
+	tests := []struct {
+		agent string
+		path  string
+		want  bool
+	}{
+		{"GenericBot/2.0", "/", true},
+		{"GenericBot/2.0", "/private/", false},
+		{"GenericBot/2.0", "/private/x", false},
+		{"GenericBot/2.0", "/private/public/page", true},
+		{"GenericBot/2.0", "/docs/file.pdf", false},
+		{"GenericBot/2.0", "/docs/file.pdf?x=1", true},
+		{"GenericBot/2.0", "/search?lang=en&q=go", false},
+		{"GenericBot/2.0", "/search", true},
+		{"GenericBot/2.0", "/robots.txt", true},
+		// 最具体的组生效，不再使用 * 组
+		{"MyDSL Crawler/1.0", "/private/", true},
+		{"MyDSL Crawler/1.0", "/specific/page", false},
+		{"MyDSL Crawler/1.0", "/specific/ok", true},
+		{"MyDSL Crawler/1.0", "/mydsl-only", true},
+		{"OtherBot/1.0", "/mydsl-only", false},
+		{"mydsl-tool", "/mydsl-only", false},
+	}
+
+	for _, tt := range tests {
+		u, err := url.Parse("https://example.com" + tt.path)
+		if err != nil {
+			t.Fatalf("url.Parse(%q): %v", tt.path, err)
+		}
+		if got := robots.Allowed(tt.agent, u); got != tt.want {
+			t.Errorf("Allowed(%q, %q) = %v, want %v", tt.agent, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRobots_CrawlDelay(t *testing.T) {
+	robots := ParseRobots([]byte(testRobots))
+
+	if d, ok := robots.CrawlDelay("OtherBot"); !ok || d != 500*time.Millisecond {
+		t.Errorf("CrawlDelay(OtherBot) = %v, %v, want 500ms, true", d, ok)
+	}
+	if _, ok := robots.CrawlDelay("GenericBot"); ok {
+		t.Errorf("CrawlDelay(GenericBot) should not be set")
+	}
+}
+
+func TestMatchRobotsPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"/", "/anything", true},
+		{"/fish", "/fish.html", true},
+		{"/fish", "/Fish", false},
+		{"/fish$", "/fish", true},
+		{"/fish$", "/fish/", false},
+		{"/*.php", "/index.php?x", true},
+		{"/*.php$", "/index.php?x", false},
+		{"/*.php$", "/a.php", true},
+		{"/a*b*c", "/aXbYc/d", true},
+		{"/a*b*c", "/aXcYb", false},
+		{"*", "/", true},
+		{"/a*$", "/a", true},
+	}
+	for _, tt := range tests {
+		if got := matchRobotsPattern(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("matchRobotsPattern(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFetcher_Robots(t *testing.T) {
+	var robotsHits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			atomic.AddInt32(&robotsHits, 1)
+			w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	options := DefaultOptions()
+	options.MaxRetries = 0
+	fetcher := NewFetcher(options)
+	ctx := context.Background()
+
+	if _, err := fetcher.Fetch(ctx, server.URL+"/public"); err != nil {
+		t.Fatalf("Fetch(/public) returned error: %v", err)
+	}
+	_, err := fetcher.Fetch(ctx, server.URL+"/private/page")
+	if !errors.Is(err, ErrDisallowed) {
+		t.Fatalf("Fetch(/private/page) error = %v, want ErrDisallowed", err)
+	}
+	if n := atomic.LoadInt32(&robotsHits); n != 1 {
+		t.Errorf("robots.txt fetched %d times, want 1", n)
+	}
+
+	options.IgnoreRobots = true
+	if _, err := NewFetcher(options).Fetch(ctx, server.URL+"/private/page"); err != nil {
+		t.Errorf("Fetch with IgnoreRobots returned error: %v", err)
+	}
+	if n := atomic.LoadInt32(&robotsHits); n != 1 {
+		t.Errorf("robots.txt fetched with IgnoreRobots set")
+	}
+}
+
+func TestFetcher_RobotsStatus(t *testing.T) {
+	tests := []struct {
+		status  int
+		allowed bool
+	}{
+		{http.StatusNotFound, true},
+		{http.StatusForbidden, true},
+		{http.StatusServiceUnavailable, false},
+	}
+
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/robots.txt" {
+				w.WriteHeader(tt.status)
+				return
+			}
+			w.Write([]byte("ok"))
+		}))
+
+		options := DefaultOptions()
+		options.MaxRetries = 0
+		_, err := NewFetcher(options).Fetch(context.Background(), server.URL+"/page")
+		server.Close()
+
+		if tt.allowed && err != nil {
+			t.Errorf("robots.txt status %d: unexpected error %v", tt.status, err)
+		}
+		if !tt.allowed && !errors.Is(err, ErrDisallowed) {
+			t.Errorf("robots.txt status %d: error = %v, want ErrDisallowed", tt.status, err)
+		}
+	}
+}
+
+func TestFetcher_RobotsUnavailableExpires(t *testing.T) {
+	defer func(ttl time.Duration) { robotsUnavailableTTL = ttl }(robotsUnavailableTTL)
+	robotsUnavailableTTL = 50 * time.Millisecond
+
+	var robotsHits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			if atomic.AddInt32(&robotsHits, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	options := DefaultOptions()
+	options.MaxRetries = 0
+	fetcher := NewFetcher(options)
+
+	if _, err := fetcher.Fetch(context.Background(), server.URL+"/page"); !errors.Is(err, ErrDisallowed) {
+		t.Fatalf("while robots.txt returns 503: error = %v, want ErrDisallowed", err)
+	}
+	if _, err := fetcher.Fetch(context.Background(), server.URL+"/page"); !errors.Is(err, ErrDisallowed) {
+		t.Fatalf("before the 503 expires: error = %v, want ErrDisallowed", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if _, err := fetcher.Fetch(context.Background(), server.URL+"/page"); err != nil {
+		t.Errorf("after robots.txt recovered: unexpected error %v", err)
+	}
+	if _, err := fetcher.Fetch(context.Background(), server.URL+"/private"); !errors.Is(err, ErrDisallowed) {
+		t.Errorf("after robots.txt recovered: /private error = %v, want ErrDisallowed", err)
+	}
+	if hits := atomic.LoadInt32(&robotsHits); hits != 2 {
+		t.Errorf("robots.txt fetched %d times, want 2", hits)
+	}
+}
+
+func TestFetcher_CrawlDelay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.Write([]byte("User-agent: *\nCrawl-delay: 0.2\n"))
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	options := DefaultOptions()
+	options.MaxRetries = 0
+	fetcher := NewFetcher(options)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := fetcher.Fetch(context.Background(), server.URL+"/"); err != nil {
+			t.Fatalf("Fetch returned error: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("3 requests with Crawl-delay 0.2 took %v, want at least 400ms", elapsed)
+	}
+
+	// 等待期间取消上下文时立即返回
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	fetcher.Fetch(context.Background(), server.URL+"/")
+	if _, err := fetcher.Fetch(ctx, server.URL+"/"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Fetch with canceled context error = %v, want context.Canceled", err)
+	}
+}
+
+func TestFetcher_CrawlDelayOnRetry(t *testing.T) {
+	var mu sync.Mutex
+	var hits []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.Write([]byte("User-agent: *\nCrawl-delay: 0.2\n"))
+			return
+		}
+		mu.Lock()
+		hits = append(hits, time.Now())
+		n := len(hits)
+		mu.Unlock()
+		if n == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	options := DefaultOptions()
+	options.RetryPolicy = &BackoffPolicy{MaxRetries: 1, StatusCodes: DefaultRetryStatus, InitialDelay: time.Millisecond}
+	resp, err := NewFetcher(options).Fetch(context.Background(), server.URL+"/")
+	if err != nil || resp.Attempts != 2 {
+		t.Fatalf("Fetch = %+v, %v; want 2 attempts", resp, err)
+	}
+	// 重试同样按 Crawl-delay 与上一次请求间隔
+	if gap := hits[1].Sub(hits[0]); gap < 190*time.Millisecond {
+		t.Errorf("retry sent %v after the first attempt, want at least 200ms", gap)
+	}
+}
+
+func TestFetcher_RobotsRateLimited(t *testing.T) {
+	var mu sync.Mutex
+	hits := map[string]time.Time{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path] = time.Now()
+		mu.Unlock()
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	options := DefaultOptions()
+	options.MaxRetries = 0
+	options.PerHostRate = 5
+	if _, err := NewFetcher(options).Fetch(context.Background(), server.URL+"/"); err != nil {
+		t.Fatalf("Fetch returned error: %v", err)
+	}
+	// 下载 robots.txt 消耗了主机的令牌，页面请求需要等待下一个令牌
+	if gap := hits["/"].Sub(hits["/robots.txt"]); gap < 190*time.Millisecond {
+		t.Errorf("page requested %v after robots.txt, want at least 200ms at 5/s", gap)
+	}
+}
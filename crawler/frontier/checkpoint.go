@@ -0,0 +1,243 @@
+package frontier
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Status 表示 URL 的抓取状态
+type Status int
+
+const (
+	Pending   Status = iota // 已入队，尚未完成
+	Completed               // 已抓取并处理
+	Failed                  // 抓取失败
+)
+
+func (s Status) String() string {
+	switch s {
+	case Pending:
+		return "pending"
+	case Completed:
+		return "completed"
+	case Failed:
+		return "failed"
+	}
+	return fmt.Sprintf("Status(%d)", int(s))
+}
+
+// 日志操作
+const (
+	opAdd    = "add"
+	opDone   = "done"
+	opFailed = "failed"
+)
+
+// entry 是检查点日志中的一行
+type entry struct {
+	Crawl    int     `json:"crawl"` // 同一文件中区分不同爬取任务的编号
+	Op       string  `json:"op"`
+	URL      string  `json:"url"`
+	Depth    int     `json:"depth,omitempty"`
+	Priority float64 `json:"priority,omitempty"`
+}
+
+// Checkpoint 以追加日志的形式把队列、已见集合和每个 URL 的状态保存到本地文件
+// 每行是一条 JSON 记录；写入先进入缓冲区，缓冲的记录最迟在写入 interval 之后由定时器同步到磁盘，
+// 即使之后没有新的记录，因此进程异常退出时最多丢失最近 interval 内的记录。
+// 一个文件可以保存多个爬取任务的状态，以任务编号区分
+type Checkpoint struct {
+	mu       sync.Mutex
+	path     string
+	file     *os.File
+	w        *bufio.Writer
+	interval time.Duration
+	lastSync time.Time
+	timer    *time.Timer // 有未同步的记录时等待同步的定时器
+	closed   bool
+	entries  []entry // 打开时已有的记录
+	err      error   // 第一次写入错误
+}
+
+// OpenCheckpoint 打开或创建检查点文件，interval 为 0 时每条记录都立即同步到磁盘
+// 文件末尾不完整的一行（例如写入时进程被终止）会被忽略
+func OpenCheckpoint(path string, interval time.Duration) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	// 丢弃不完整的最后一行，后续追加的记录从新行开始
+	complete := data[:bytes.LastIndexByte(data, '\n')+1]
+
+	var entries []entry
+	for i, line := range bytes.Split(complete, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var e entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid checkpoint record: %v", path, i+1, err)
+		}
+		entries = append(entries, e)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if len(complete) < len(data) {
+		if err := file.Truncate(int64(len(complete))); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+
+	return &Checkpoint{
+		path:     path,
+		file:     file,
+		w:        bufio.NewWriter(file),
+		interval: interval,
+		lastSync: time.Now(),
+		entries:  entries,
+	}, nil
+}
+
+// Path 返回检查点文件路径
+func (c *Checkpoint) Path() string {
+	return c.path
+}
+
+// record 追加一条记录，距上次同步超过 interval 时写入磁盘，否则安排定时同步
+func (c *Checkpoint) record(e entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return
+	}
+	line, err := json.Marshal(e)
+	if err == nil {
+		line = append(line, '\n')
+		_, err = c.w.Write(line)
+	}
+	if err == nil {
+		if wait := c.interval - time.Since(c.lastSync); wait <= 0 {
+			err = c.syncLocked()
+		} else if c.timer == nil {
+			c.timer = time.AfterFunc(wait, c.timedSync)
+		}
+	}
+	if err != nil {
+		c.err = err
+	}
+}
+
+// timedSync 由定时器调用，同步 record 之后没有写入磁盘的记录
+func (c *Checkpoint) timedSync() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.timer = nil
+	if c.closed || c.err != nil {
+		return
+	}
+	if err := c.syncLocked(); err != nil {
+		c.err = err
+	}
+}
+
+// Flush 将缓冲的记录写入磁盘
+func (c *Checkpoint) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return c.err
+	}
+	if err := c.syncLocked(); err != nil {
+		c.err = err
+	}
+	return c.err
+}
+
+func (c *Checkpoint) syncLocked() error {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if err := c.w.Flush(); err != nil {
+		return err
+	}
+	c.lastSync = time.Now()
+	return c.file.Sync()
+}
+
+// Err 返回写入检查点时发生的第一个错误
+func (c *Checkpoint) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Close 写入缓冲的记录并关闭文件
+func (c *Checkpoint) Close() error {
+	err := c.Flush()
+
+	c.mu.Lock()
+	c.closed = true
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.mu.Unlock()
+
+	if cerr := c.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// restore 将编号为 crawl 的任务的记录重放到队列中，返回重新入队的 URL 数量
+// 已完成的 URL 只计入已见集合；未完成和失败的 URL 按原深度重新入队
+func (c *Checkpoint) restore(f *Frontier, crawl int) int {
+	var order []*Item
+	for _, e := range c.entries {
+		if e.Crawl != crawl {
+			continue
+		}
+		switch e.Op {
+		case opAdd:
+			if _, ok := f.status[e.URL]; ok {
+				continue
+			}
+			f.status[e.URL] = Pending
+			if e.Depth == 0 {
+				if u, err := Normalize(e.URL, false); err == nil {
+					f.hosts[domainOf(u.Hostname())] = true
+				}
+			}
+			order = append(order, &Item{URL: e.URL, Depth: e.Depth, Priority: e.Priority})
+		case opDone:
+			f.status[e.URL] = Completed
+		case opFailed:
+			f.status[e.URL] = Failed
+		}
+	}
+
+	restored := 0
+	for _, item := range order {
+		if f.status[item.URL] == Completed {
+			continue
+		}
+		f.status[item.URL] = Pending
+		f.enqueue(item)
+		restored++
+	}
+	return restored
+}
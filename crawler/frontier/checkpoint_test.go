@@ -0,0 +1,210 @@
+package frontier
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/btrobot/mydsl/crawler/fetch"
+)
+
+func TestCheckpoint_Resume(t *testing.T) {
+	server := newSiteServer()
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "state.log")
+	visit := func(visited *[]string) VisitFunc {
+		return func(item *Item, resp *fetch.Response) ([]string, error) {
+			*visited = append(*visited, strings.TrimPrefix(item.URL, server.URL))
+			var links []string
+			for _, line := range strings.Fields(string(resp.Body)) {
+				links = append(links, server.URL+line)
+			}
+			return links, nil
+		}
+	}
+
+	// 第一次运行只处理两个页面后停止
+	cp, err := OpenCheckpoint(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := New(DefaultOptions())
+	if n := f.UseCheckpoint(cp, 1); n != 0 {
+		t.Errorf("UseCheckpoint() on new file restored %d URLs", n)
+	}
+	f.AddSeed(server.URL)
+
+	var first []string
+	c := &Crawler{Fetcher: newTestFetcher(), Frontier: f, MaxPages: 2}
+	if _, err := c.Run(context.Background(), visit(&first)); err != nil {
+		t.Fatal(err)
+	}
+	if err := cp.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 恢复后只处理剩余的页面
+	cp, err = OpenCheckpoint(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cp.Close()
+	f = New(DefaultOptions())
+	if n := f.UseCheckpoint(cp, 1); n != 2 {
+		t.Errorf("UseCheckpoint() restored %d URLs, want 2", n)
+	}
+	f.AddSeed(server.URL)
+
+	if status, ok := f.Status(server.URL + "/a"); !ok || status != Completed {
+		t.Errorf("Status(/a) = %v, %v; want completed", status, ok)
+	}
+
+	var second []string
+	c = &Crawler{Fetcher: newTestFetcher(), Frontier: f}
+	stats, err := c.Run(context.Background(), visit(&second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"/", "/a"}; !reflect.DeepEqual(first, want) {
+		t.Errorf("first run visited %q, want %q", first, want)
+	}
+	if want := []string{"/b", "/a/1", "/b/1", "/missing"}; !reflect.DeepEqual(second, want) {
+		t.Errorf("resumed run visited %q, want %q", second, want)
+	}
+	if stats.Visited != 4 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestCheckpoint_FailedURLsAreRetried(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.log")
+	cp, err := OpenCheckpoint(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := New(DefaultOptions())
+	f.UseCheckpoint(cp, 1)
+	f.AddSeed("http://a.com/")
+	f.Add("http://a.com/x", 1)
+	item, _ := f.Next()
+	f.MarkCompleted(item)
+	item, _ = f.Next()
+	f.MarkFailed(item)
+	cp.Close()
+
+	cp, err = OpenCheckpoint(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cp.Close()
+	f = New(DefaultOptions())
+	f.UseCheckpoint(cp, 1)
+	item, ok := f.Next()
+	if !ok || item.URL != "http://a.com/x" || item.Depth != 1 {
+		t.Errorf("Next() = %+v, %v; want failed URL at depth 1", item, ok)
+	}
+	if f.Add("http://a.com/", 1) {
+		t.Errorf("completed URL should stay in the seen set")
+	}
+}
+
+func TestCheckpoint_SeparateCrawls(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.log")
+	cp, err := OpenCheckpoint(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f1 := New(DefaultOptions())
+	f1.UseCheckpoint(cp, 1)
+	f1.AddSeed("http://a.com/")
+	f2 := New(DefaultOptions())
+	f2.UseCheckpoint(cp, 2)
+	f2.AddSeed("http://b.com/")
+	cp.Close()
+
+	cp, err = OpenCheckpoint(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cp.Close()
+	f := New(DefaultOptions())
+	f.UseCheckpoint(cp, 2)
+	if got := drain(f); !reflect.DeepEqual(got, []string{"http://b.com/"}) {
+		t.Errorf("crawl 2 restored %q", got)
+	}
+}
+
+func TestCheckpoint_TimedSync(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.log")
+	cp, err := OpenCheckpoint(path, 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cp.Close()
+	f := New(DefaultOptions())
+	f.UseCheckpoint(cp, 1)
+	f.AddSeed("http://a.com/")
+
+	if data, _ := os.ReadFile(path); len(data) != 0 {
+		t.Errorf("record synced before interval: %q", data)
+	}
+
+	// 之后没有新的写入，定时器仍应把缓冲的记录写入磁盘
+	want := `{"crawl":1,"op":"add","url":"http://a.com/"}` + "\n"
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) == want {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("checkpoint file = %q, want %q", data, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestOpenCheckpoint_TruncatedRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.log")
+	content := `{"crawl":1,"op":"add","url":"http://a.com/"}` + "\n" + `{"crawl":1,"op":"do`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cp, err := OpenCheckpoint(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := New(DefaultOptions())
+	if n := f.UseCheckpoint(cp, 1); n != 1 {
+		t.Errorf("UseCheckpoint() restored %d URLs, want 1", n)
+	}
+	item, _ := f.Next()
+	f.MarkCompleted(item)
+	cp.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"crawl":1,"op":"add","url":"http://a.com/"}` + "\n" + `{"crawl":1,"op":"done","url":"http://a.com/"}` + "\n"
+	if string(data) != want {
+		t.Errorf("checkpoint file = %q, want %q", data, want)
+	}
+
+	if err := os.WriteFile(path, []byte("not json\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenCheckpoint(path, 0); err == nil {
+		t.Errorf("OpenCheckpoint() should reject a corrupt record")
+	}
+}
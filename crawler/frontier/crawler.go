@@ -0,0 +1,85 @@
+package frontier
+
+import (
+	"context"
+
+	"github.com/btrobot/mydsl/crawler/fetch"
+)
+
+// VisitFunc 处理抓取到的页面，返回页面中需要继续跟进的链接
+// 返回错误时爬取立即停止
+type VisitFunc func(item *Item, resp *fetch.Response) ([]string, error)
+
+// Stats 表示一次爬取的统计信息
+type Stats struct {
+	Visited int // 成功抓取并处理的页面数
+	Failed  int // 抓取失败的页面数
+}
+
+// CheckpointError 表示写入检查点失败
+type CheckpointError struct {
+	Err error
+}
+
+func (e *CheckpointError) Error() string { return "checkpoint: " + e.Err.Error() }
+func (e *CheckpointError) Unwrap() error { return e.Err }
+
+// Crawler 使用 Fetcher 依次抓取队列中的 URL
+// 页面按顺序处理，VisitFunc 不会被并发调用
+type Crawler struct {
+	Fetcher  *fetch.Fetcher
+	Frontier *Frontier
+	MaxPages int // 最多处理的页面数，0 表示不限
+
+	// OnError 在抓取失败时调用，为 nil 时失败的 URL 被跳过
+	OnError func(item *Item, err error)
+}
+
+// Run 执行爬取，直到队列为空、达到页面上限、VisitFunc 返回错误或 ctx 被取消
+// 抓取失败的 URL 不会中断爬取；跟进的链接以当前深度加一入队。
+// 页面处理完并且其链接入队后才标记为已完成，返回前将检查点写入磁盘，
+// 写入检查点失败时返回 *CheckpointError
+func (c *Crawler) Run(ctx context.Context, visit VisitFunc) (stats Stats, err error) {
+	defer func() {
+		if ferr := c.Frontier.flushCheckpoint(); err == nil && ferr != nil {
+			err = &CheckpointError{Err: ferr}
+		}
+	}()
+
+	for c.MaxPages <= 0 || stats.Visited < c.MaxPages {
+		if err := c.Frontier.checkpointErr(); err != nil {
+			return stats, &CheckpointError{Err: err}
+		}
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+
+		item, ok := c.Frontier.Next()
+		if !ok {
+			break
+		}
+
+		resp, err := c.Fetcher.Fetch(ctx, item.URL)
+		if err != nil {
+			if ctx.Err() != nil {
+				return stats, ctx.Err()
+			}
+			stats.Failed++
+			c.Frontier.MarkFailed(item)
+			if c.OnError != nil {
+				c.OnError(item, err)
+			}
+			continue
+		}
+
+		links, err := visit(item, resp)
+		if err != nil {
+			return stats, err
+		}
+		stats.Visited++
+
+		c.Frontier.AddLinks(links, item.Depth+1)
+		c.Frontier.MarkCompleted(item)
+	}
+	return stats, nil
+}
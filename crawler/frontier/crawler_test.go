@@ -0,0 +1,107 @@
+package frontier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/btrobot/mydsl/crawler/fetch"
+)
+
+// newSiteServer 创建一个页面之间相互链接的测试站点
+func newSiteServer() *httptest.Server {
+	pages := map[string][]string{
+		"/":  {"/a", "/b"},
+		"/a": {"/a/1", "/"},
+		"/b": {"/b/1", "/missing"},
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		links, ok := pages[r.URL.Path]
+		if !ok && r.URL.Path != "/a/1" && r.URL.Path != "/b/1" {
+			http.NotFound(w, r)
+			return
+		}
+		for _, link := range links {
+			fmt.Fprintf(w, "%s\n", link)
+		}
+	}))
+}
+
+func newTestFetcher() *fetch.Fetcher {
+	options := fetch.DefaultOptions()
+	options.MaxRetries = 0
+	return fetch.NewFetcher(options)
+}
+
+func TestCrawler_Run(t *testing.T) {
+	server := newSiteServer()
+	defer server.Close()
+
+	opts := DefaultOptions()
+	opts.MaxDepth = 1
+	f := New(opts)
+	if err := f.AddSeed(server.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	var visited []string
+	c := &Crawler{Fetcher: newTestFetcher(), Frontier: f}
+	stats, err := c.Run(context.Background(), func(item *Item, resp *fetch.Response) ([]string, error) {
+		visited = append(visited, fmt.Sprintf("%s@%d:%d", item.URL[len(server.URL):], item.Depth, resp.StatusCode))
+		var links []string
+		for _, line := range strings.Fields(string(resp.Body)) {
+			links = append(links, server.URL+line)
+		}
+		return links, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"/@0:200", "/a@1:200", "/b@1:200"}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("visited = %q, want %q", visited, expected)
+	}
+	if stats.Visited != 3 || stats.Failed != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestCrawler_MaxPagesAndErrors(t *testing.T) {
+	server := newSiteServer()
+	defer server.Close()
+
+	f := New(DefaultOptions())
+	f.AddSeed(server.URL)
+	f.AddSeed("http://127.0.0.1:1/unreachable")
+
+	var failed []string
+	c := &Crawler{
+		Fetcher:  newTestFetcher(),
+		Frontier: f,
+		MaxPages: 2,
+		OnError:  func(item *Item, err error) { failed = append(failed, item.URL) },
+	}
+	stats, err := c.Run(context.Background(), func(item *Item, resp *fetch.Response) ([]string, error) {
+		return []string{server.URL + "/a", server.URL + "/b"}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Visited != 2 || stats.Failed != 1 || len(failed) != 1 {
+		t.Errorf("stats = %+v, failed = %q", stats, failed)
+	}
+
+	stop := errors.New("stop")
+	_, err = c.Run(context.Background(), func(item *Item, resp *fetch.Response) ([]string, error) {
+		return nil, stop
+	})
+	if err != stop {
+		t.Errorf("Run() error = %v, want %v", err, stop)
+	}
+}
@@ -0,0 +1,364 @@
+// Package frontier 管理爬虫待抓取的 URL 队列
+// 支持深度限制、广度优先/深度优先/优先级顺序、基于规范化 URL 的去重，
+// 以及允许/拒绝正则和同域名范围规则
+package frontier
+
+import (
+	"container/heap"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/btrobot/mydsl/crawler/extract"
+)
+
+// Strategy 表示 URL 的出队顺序
+type Strategy int
+
+const (
+	BreadthFirst  Strategy = iota // 先进先出，逐层抓取
+	DepthFirst                    // 后进先出，优先抓取最新发现的链接
+	PriorityOrder                 // 优先级高的先出队，相同优先级先进先出
+)
+
+var strategyNames = map[Strategy]string{
+	BreadthFirst:  "bfs",
+	DepthFirst:    "dfs",
+	PriorityOrder: "priority",
+}
+
+func (s Strategy) String() string {
+	if name, ok := strategyNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Strategy(%d)", int(s))
+}
+
+// ParseStrategy 解析策略名称：bfs、dfs 或 priority
+func ParseStrategy(name string) (Strategy, error) {
+	for s, n := range strategyNames {
+		if strings.EqualFold(name, n) {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown crawl strategy %q (want bfs, dfs or priority)", name)
+}
+
+// Options 表示队列的范围和排序规则
+type Options struct {
+	Strategy   Strategy
+	MaxDepth   int              // 最大深度，种子深度为 0；小于 0 表示不限
+	Allow      []*regexp.Regexp // 非空时 URL 必须匹配其中之一
+	Deny       []*regexp.Regexp // 匹配任意一个的 URL 被丢弃
+	SameDomain bool             // 只接受与某个种子同域名（含子域名）的 URL
+	SortQuery  bool             // 去重前是否按参数名排序查询参数
+
+	// Priority 计算 URL 的优先级，数值越大越先出队，只在 PriorityOrder 策略下使用；
+	// 调用时持有队列的锁，函数内不能再访问同一个 Frontier
+	Priority func(url string, depth int) float64
+}
+
+// DefaultOptions 返回默认选项：广度优先，深度不限
+func DefaultOptions() Options {
+	return Options{
+		Strategy: BreadthFirst,
+		MaxDepth: -1,
+	}
+}
+
+// Item 表示队列中的一个 URL
+type Item struct {
+	URL      string // 规范化后的 URL
+	Depth    int
+	Priority float64
+
+	seq uint64 // 入队序号，用于稳定排序
+}
+
+// Frontier 是待抓取的 URL 队列，可以被多个 goroutine 同时使用
+// 每个规范化后的 URL 只会入队一次
+type Frontier struct {
+	mu     sync.Mutex
+	opts   Options
+	queue  itemQueue
+	status map[string]Status // 已见集合及每个 URL 的状态
+	hosts  map[string]bool   // 种子的域名，用于同域名判断
+	seq    uint64
+
+	checkpoint *Checkpoint // 不为 nil 时记录状态变化
+	crawlID    int
+}
+
+// New 创建空队列
+func New(opts Options) *Frontier {
+	return &Frontier{
+		opts:   opts,
+		queue:  itemQueue{strategy: opts.Strategy},
+		status: make(map[string]Status),
+		hosts:  make(map[string]bool),
+	}
+}
+
+// AddSeed 添加深度为 0 的种子 URL，并将其域名加入同域名范围
+// 种子不受允许/拒绝规则限制；URL 无效时返回错误，已见过的种子被忽略
+func (f *Frontier) AddSeed(rawURL string) error {
+	u, err := Normalize(rawURL, f.opts.SortQuery)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.hosts[domainOf(u.Hostname())] = true
+	f.push(u.String(), 0)
+	return nil
+}
+
+// Add 添加在深度 depth 处发现的 URL，返回 URL 是否入队
+// 无效、超出深度、不在范围内或已见过的 URL 不会入队
+func (f *Frontier) Add(rawURL string, depth int) bool {
+	if f.opts.MaxDepth >= 0 && depth > f.opts.MaxDepth {
+		return false
+	}
+	u, err := Normalize(rawURL, f.opts.SortQuery)
+	if err != nil {
+		return false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.inScope(u) {
+		return false
+	}
+	return f.push(u.String(), depth)
+}
+
+// AddLinks 添加同一页面中发现的一组链接，返回入队的数量
+// 深度优先策略下链接按逆序入队，使页面中靠前的链接先被抓取
+func (f *Frontier) AddLinks(links []string, depth int) int {
+	added := 0
+	for i := range links {
+		link := links[i]
+		if f.opts.Strategy == DepthFirst {
+			link = links[len(links)-1-i]
+		}
+		if f.Add(link, depth) {
+			added++
+		}
+	}
+	return added
+}
+
+// push 在持有锁时将未见过的 URL 入队
+func (f *Frontier) push(link string, depth int) bool {
+	if _, ok := f.status[link]; ok {
+		return false
+	}
+	f.status[link] = Pending
+
+	item := &Item{URL: link, Depth: depth}
+	if f.opts.Strategy == PriorityOrder && f.opts.Priority != nil {
+		item.Priority = f.opts.Priority(link, depth)
+	}
+	f.enqueue(item)
+	f.record(opAdd, item)
+	return true
+}
+
+// enqueue 在持有锁时将条目放入堆中
+func (f *Frontier) enqueue(item *Item) {
+	item.seq = f.seq
+	f.seq++
+	heap.Push(&f.queue, item)
+}
+
+// record 在持有锁时向检查点追加记录
+func (f *Frontier) record(op string, item *Item) {
+	if f.checkpoint != nil {
+		f.checkpoint.record(entry{Crawl: f.crawlID, Op: op, URL: item.URL, Depth: item.Depth, Priority: item.Priority})
+	}
+}
+
+// MarkCompleted 将出队的 URL 标记为已完成
+func (f *Frontier) MarkCompleted(item *Item) {
+	f.mark(item, Completed, opDone)
+}
+
+// MarkFailed 将出队的 URL 标记为抓取失败
+func (f *Frontier) MarkFailed(item *Item) {
+	f.mark(item, Failed, opFailed)
+}
+
+func (f *Frontier) mark(item *Item, status Status, op string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.status[item.URL] = status
+	f.record(op, item)
+}
+
+// Status 返回 URL 规范化后的状态，未见过的 URL 返回 false
+func (f *Frontier) Status(rawURL string) (Status, bool) {
+	u, err := Normalize(rawURL, f.opts.SortQuery)
+	if err != nil {
+		return 0, false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	status, ok := f.status[u.String()]
+	return status, ok
+}
+
+// UseCheckpoint 从检查点恢复编号为 crawl 的任务的状态，之后的状态变化都写入该检查点
+// 已完成的 URL 不会再次入队，未完成和失败的 URL 按原深度重新入队；
+// 应在添加种子之前调用，返回重新入队的 URL 数量
+func (f *Frontier) UseCheckpoint(cp *Checkpoint, crawl int) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	restored := cp.restore(f, crawl)
+	f.checkpoint = cp
+	f.crawlID = crawl
+	return restored
+}
+
+// checkpointErr 返回写入检查点时发生的错误
+func (f *Frontier) checkpointErr() error {
+	f.mu.Lock()
+	cp := f.checkpoint
+	f.mu.Unlock()
+	if cp == nil {
+		return nil
+	}
+	return cp.Err()
+}
+
+// flushCheckpoint 将检查点缓冲的记录写入磁盘
+func (f *Frontier) flushCheckpoint() error {
+	f.mu.Lock()
+	cp := f.checkpoint
+	f.mu.Unlock()
+	if cp == nil {
+		return nil
+	}
+	return cp.Flush()
+}
+
+// inScope 判断 URL 是否满足同域名和允许/拒绝规则
+func (f *Frontier) inScope(u *url.URL) bool {
+	if f.opts.SameDomain && !f.sameDomain(u.Hostname()) {
+		return false
+	}
+
+	link := u.String()
+	for _, re := range f.opts.Deny {
+		if re.MatchString(link) {
+			return false
+		}
+	}
+	if len(f.opts.Allow) == 0 {
+		return true
+	}
+	for _, re := range f.opts.Allow {
+		if re.MatchString(link) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameDomain 判断主机是否为某个种子的域名或其子域名，忽略 www. 前缀
+func (f *Frontier) sameDomain(host string) bool {
+	host = domainOf(host)
+	for seed := range f.hosts {
+		if host == seed || strings.HasSuffix(host, "."+seed) {
+			return true
+		}
+	}
+	return false
+}
+
+func domainOf(host string) string {
+	return strings.TrimPrefix(strings.ToLower(host), "www.")
+}
+
+// Next 取出下一个待抓取的 URL，队列为空时返回 false
+func (f *Frontier) Next() (*Item, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.queue.Len() == 0 {
+		return nil, false
+	}
+	return heap.Pop(&f.queue).(*Item), true
+}
+
+// Len 返回队列中待抓取的 URL 数量
+func (f *Frontier) Len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.queue.Len()
+}
+
+// Seen 判断 URL 规范化后是否已经入过队
+func (f *Frontier) Seen(rawURL string) bool {
+	u, err := Normalize(rawURL, f.opts.SortQuery)
+	if err != nil {
+		return false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.status[u.String()]
+	return ok
+}
+
+// Normalize 解析并规范化用于去重的 URL，只接受带主机名的 http 和 https 地址
+func Normalize(rawURL string, sortQuery bool) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("unsupported URL %q: want absolute http or https URL", rawURL)
+	}
+	return extract.NormalizeURL(u, sortQuery), nil
+}
+
+// itemQueue 按策略排序的堆
+type itemQueue struct {
+	strategy Strategy
+	items    []*Item
+}
+
+func (q itemQueue) Len() int { return len(q.items) }
+
+func (q itemQueue) Less(i, j int) bool {
+	a, b := q.items[i], q.items[j]
+	switch q.strategy {
+	case DepthFirst:
+		return a.seq > b.seq
+	case PriorityOrder:
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+	}
+	return a.seq < b.seq
+}
+
+func (q itemQueue) Swap(i, j int) { q.items[i], q.items[j] = q.items[j], q.items[i] }
+
+func (q *itemQueue) Push(x interface{}) { q.items = append(q.items, x.(*Item)) }
+
+func (q *itemQueue) Pop() interface{} {
+	n := len(q.items)
+	item := q.items[n-1]
+	q.items[n-1] = nil
+	q.items = q.items[:n-1]
+	return item
+}
@@ -0,0 +1,134 @@
+package frontier
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// drain 依次取出队列中的全部 URL
+func drain(f *Frontier) []string {
+	var urls []string
+	for {
+		item, ok := f.Next()
+		if !ok {
+			return urls
+		}
+		urls = append(urls, item.URL)
+	}
+}
+
+func TestFrontier_Order(t *testing.T) {
+	links := []string{"http://a.com/1", "http://a.com/2", "http://a.com/3"}
+
+	tests := []struct {
+		strategy Strategy
+		priority func(string, int) float64
+		expected []string
+	}{
+		{BreadthFirst, nil, []string{"http://a.com/", "http://a.com/1", "http://a.com/2", "http://a.com/3"}},
+		{DepthFirst, nil, []string{"http://a.com/1", "http://a.com/2", "http://a.com/3", "http://a.com/"}},
+		{PriorityOrder, func(url string, depth int) float64 {
+			if url == "http://a.com/3" {
+				return 10
+			}
+			return float64(-depth)
+		}, []string{"http://a.com/3", "http://a.com/", "http://a.com/1", "http://a.com/2"}},
+	}
+
+	for _, tt := range tests {
+		opts := DefaultOptions()
+		opts.Strategy = tt.strategy
+		opts.Priority = tt.priority
+		f := New(opts)
+		if err := f.AddSeed("http://a.com"); err != nil {
+			t.Fatal(err)
+		}
+		f.AddLinks(links, 1)
+
+		if got := drain(f); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%s: order = %q, want %q", tt.strategy, got, tt.expected)
+		}
+	}
+}
+
+func TestFrontier_Dedup(t *testing.T) {
+	opts := DefaultOptions()
+	opts.SortQuery = true
+	f := New(opts)
+
+	if err := f.AddSeed("http://Example.com:80/a#top"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		url   string
+		added bool
+	}{
+		{"http://example.com/a", false},
+		{"HTTP://EXAMPLE.COM/a#other", false},
+		{"http://example.com/b?y=1&x=2", true},
+		{"http://example.com/b?x=2&y=1", false},
+		{"http://example.com/b", true},
+		{"mailto:me@example.com", false},
+		{"/relative", false},
+	}
+	for _, tt := range tests {
+		if got := f.Add(tt.url, 1); got != tt.added {
+			t.Errorf("Add(%q) = %v, want %v", tt.url, got, tt.added)
+		}
+	}
+	if f.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", f.Len())
+	}
+	if !f.Seen("http://example.com/b?y=1&x=2#frag") {
+		t.Errorf("Seen() should match normalized URL")
+	}
+}
+
+func TestFrontier_Scope(t *testing.T) {
+	opts := DefaultOptions()
+	opts.MaxDepth = 2
+	opts.SameDomain = true
+	opts.Allow = []*regexp.Regexp{regexp.MustCompile(`/docs/`), regexp.MustCompile(`/blog/`)}
+	opts.Deny = []*regexp.Regexp{regexp.MustCompile(`\.pdf$`)}
+	f := New(opts)
+
+	if err := f.AddSeed("https://www.example.com/"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		url   string
+		depth int
+		added bool
+	}{
+		{"https://example.com/docs/a", 1, true},
+		{"https://api.example.com/docs/b", 1, true},
+		{"https://example.org/docs/c", 1, false},
+		{"https://notexample.com/docs/d", 1, false},
+		{"https://example.com/about", 1, false},
+		{"https://example.com/docs/manual.pdf", 1, false},
+		{"https://example.com/blog/x", 2, true},
+		{"https://example.com/blog/y", 3, false},
+	}
+	for _, tt := range tests {
+		if got := f.Add(tt.url, tt.depth); got != tt.added {
+			t.Errorf("Add(%q, %d) = %v, want %v", tt.url, tt.depth, got, tt.added)
+		}
+	}
+
+	if err := f.AddSeed("ftp://example.com/"); err == nil {
+		t.Errorf("AddSeed() should reject non-http URL")
+	}
+}
+
+func TestParseStrategy(t *testing.T) {
+	for _, s := range []Strategy{BreadthFirst, DepthFirst, PriorityOrder} {
+		got, err := ParseStrategy(s.String())
+		if err != nil || got != s {
+			t.Errorf("ParseStrategy(%q) = %v, %v", s.String(), got, err)
+		}
+	}
+	if _, err := ParseStrategy("random"); err == nil {
+		t.Errorf("ParseStrategy(random) should fail")
+	}
+}
@@ -0,0 +1,133 @@
+// Package selector 定义选择器文本的语言前缀与伪元素后缀，不依赖 HTML 解析，
+// 供解析器检查字面量选择器和 extract 包求值时共用
+package selector
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 选择器语言前缀
+const (
+	XPathPrefix = "xpath:"
+	CSSPrefix   = "css:"
+)
+
+// Error 表示选择器语法错误
+type Error struct {
+	Selector string // 原始选择器
+	Offset   int    // 出错位置（字节偏移）
+	Message  string // 错误描述
+}
+
+// Error 实现 error 接口
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid selector %q at offset %d: %s", e.Selector, e.Offset, e.Message)
+}
+
+// Suffix 表示选择器末尾的伪元素后缀，例如 ::text 或 ::attr(href)
+// 带后缀的选择器提取字符串而不是元素
+type Suffix struct {
+	Name string // text、own-text、html 或 attr
+	Attr string // Name 为 attr 时的属性名
+}
+
+// String 返回后缀的文本形式
+func (s *Suffix) String() string {
+	if s.Name == "attr" {
+		return fmt.Sprintf("::attr(%s)", s.Attr)
+	}
+	return "::" + s.Name
+}
+
+// ParseSuffix 拆分选择器末尾的伪元素后缀，没有后缀时返回 nil
+// 未知的后缀返回 *Error
+func ParseSuffix(selector string) (string, *Suffix, error) {
+	idx := strings.LastIndex(selector, "::")
+	if idx < 0 {
+		return selector, nil, nil
+	}
+
+	base, rest := selector[:idx], selector[idx+2:]
+	name, arg, hasArg := rest, "", false
+	if open := strings.IndexByte(rest, '('); open >= 0 && strings.HasSuffix(rest, ")") {
+		name, arg, hasArg = rest[:open], strings.TrimSpace(rest[open+1:len(rest)-1]), true
+	}
+
+	// XPath 的轴也使用 ::，只有已知的后缀才会从 XPath 表达式中拆出
+	isXPath := strings.HasPrefix(selector, XPathPrefix)
+	if isXPath && !isXPathSuffix(base, name, hasArg) {
+		return selector, nil, nil
+	}
+	if !isSuffixName(name) {
+		if isXPath || !isSuffixIdent(name) {
+			return selector, nil, nil
+		}
+		return "", nil, &Error{Selector: selector, Offset: idx, Message: fmt.Sprintf("unknown pseudo-element ::%s", name)}
+	}
+
+	errorf := func(format string, a ...interface{}) error {
+		return &Error{Selector: selector, Offset: idx, Message: fmt.Sprintf(format, a...)}
+	}
+	switch {
+	case name == "attr" && (!hasArg || arg == ""):
+		return "", nil, errorf("::attr requires an attribute name")
+	case name != "attr" && hasArg:
+		return "", nil, errorf("::%s does not take arguments", name)
+	case strings.TrimSpace(base) == "" || strings.TrimSpace(base) == XPathPrefix:
+		return "", nil, errorf("missing selector before ::%s", name)
+	}
+
+	arg = strings.Trim(arg, `"'`)
+	return base, &Suffix{Name: name, Attr: strings.ToLower(arg)}, nil
+}
+
+// XPathAxes 是 XPath 1.0 的轴名称
+var XPathAxes = map[string]bool{
+	"ancestor": true, "ancestor-or-self": true, "attribute": true, "child": true,
+	"descendant": true, "descendant-or-self": true, "following": true,
+	"following-sibling": true, "namespace": true, "parent": true, "preceding": true,
+	"preceding-sibling": true, "self": true,
+}
+
+// isXPathSuffix 判断 XPath 表达式末尾的 ::name 是否为后缀而不是位置步：
+// :: 之前必须是完整的步而不是轴名称，例如 //p::text 是后缀而 //p/child::text() 是步；
+// 后缀中只有 ::attr(name) 带括号，::text() 等是节点测试
+func isXPathSuffix(base, name string, hasArg bool) bool {
+	if hasArg && name != "attr" {
+		return false
+	}
+	base = strings.TrimRight(base, " \t\n")
+	start := len(base)
+	for start > 0 && isNameChar(base[start-1]) {
+		start--
+	}
+	return !XPathAxes[base[start:]]
+}
+
+func isSuffixName(name string) bool {
+	switch name {
+	case "text", "own-text", "html", "attr":
+		return true
+	}
+	return false
+}
+
+// isSuffixIdent 判断 :: 之后的内容是否形如伪元素名称
+func isSuffixIdent(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isNameChar(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isNameChar 判断字节能否出现在 CSS 标识符中
+func isNameChar(ch byte) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' || ch >= 0x80 ||
+		'0' <= ch && ch <= '9' || ch == '-'
+}
@@ -0,0 +1,61 @@
+package selector
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseSuffix(t *testing.T) {
+	tests := []struct {
+		input  string
+		base   string
+		suffix string // 空表示无后缀
+		err    string
+	}{
+		{"a", "a", "", ""},
+		{"a::attr(href)", "a", "::attr(href)", ""},
+		{"a::attr( 'data-ID' )", "a", "::attr(data-id)", ""},
+		{"h1::text", "h1", "::text", ""},
+		{"div > p::own-text", "div > p", "::own-text", ""},
+		{"div::html", "div", "::html", ""},
+		{"xpath://a::text", "xpath://a", "::text", ""},
+		{"xpath://a/child::b", "xpath://a/child::b", "", ""},
+		{"xpath://p/child::text()", "xpath://p/child::text()", "", ""},
+		{"xpath://p/self::node()", "xpath://p/self::node()", "", ""},
+		{"xpath:self::html", "xpath:self::html", "", ""},
+		{"xpath://div/descendant::text()", "xpath://div/descendant::text()", "", ""},
+		{"xpath://p/child :: text", "xpath://p/child :: text", "", ""},
+		{"xpath://a/attribute::href", "xpath://a/attribute::href", "", ""},
+		{"xpath://a[@x]::attr(href)", "xpath://a[@x]", "::attr(href)", ""},
+		{"xpath://p/child::b::own-text", "xpath://p/child::b", "::own-text", ""},
+		{`a[href="x::y"]`, `a[href="x::y"]`, "", ""},
+		{"p::before", "", "", "unknown pseudo-element ::before"},
+		{"a::attr", "", "", "requires an attribute name"},
+		{"a::html(x)", "", "", "does not take arguments"},
+		{"::text", "", "", "missing selector"},
+	}
+
+	for _, tt := range tests {
+		base, suffix, err := ParseSuffix(tt.input)
+		if tt.err != "" {
+			var selErr *Error
+			if !errors.As(err, &selErr) || !strings.Contains(selErr.Message, tt.err) {
+				t.Errorf("ParseSuffix(%q): expected selector error containing %q, got %v", tt.input, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseSuffix(%q): unexpected error %v", tt.input, err)
+			continue
+		}
+
+		got := ""
+		if suffix != nil {
+			got = suffix.String()
+		}
+		if base != tt.base || got != tt.suffix {
+			t.Errorf("ParseSuffix(%q) = %q, %q; want %q, %q", tt.input, base, got, tt.base, tt.suffix)
+		}
+	}
+}
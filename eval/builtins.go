@@ -0,0 +1,464 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/btrobot/mydsl/errors"
+	"github.com/btrobot/mydsl/internal/debug"
+)
+
+var (
+	// Stdout 是 print 的输出目标
+	Stdout io.Writer = os.Stdout
+
+	// Stderr 是 log/info/warn/error 的输出目标
+	Stderr io.Writer = os.Stderr
+)
+
+// builtins 保存所有内置函数
+var builtins = map[string]*Builtin{}
+
+// RuntimeBuiltinFunction 表示需要访问运行时（抓取器、会话等）的内置函数
+type RuntimeBuiltinFunction func(rt *Runtime, args ...Object) Object
+
+// runtimeBuiltins 保存需要运行时的内置函数，查找标识符时绑定到当前环境的运行时
+var runtimeBuiltins = map[string]RuntimeBuiltinFunction{}
+
+func init() {
+	register := func(name string, fn BuiltinFunction) {
+		builtins[name] = &Builtin{Name: name, Fn: fn}
+	}
+
+	register("len", builtinLength)
+	register("length", builtinLength)
+	register("keys", builtinKeys)
+	register("values", builtinValues)
+	register("delete", builtinDelete)
+	register("push", builtinPush)
+	register("first", builtinFirst)
+	register("last", builtinLast)
+	register("range", builtinRange)
+	register("type", builtinType)
+	register("str", builtinStr)
+	register("int", builtinInt)
+	register("float", builtinFloat)
+	register("join", builtinJoin)
+	register("split", builtinSplit)
+	register("trim", builtinTrim)
+	register("lower", builtinLower)
+	register("upper", builtinUpper)
+	register("replace", builtinReplace)
+	register("contains", builtinContains)
+	register("print", builtinPrint)
+	register("log", logBuiltin("INFO"))
+	register("info", logBuiltin("INFO"))
+	register("warn", logBuiltin("WARN"))
+	register("error", logBuiltin("ERROR"))
+	register("debug", builtinDebug)
+	register("table", builtinTable)
+	register("metadata", builtinMetadata)
+	register("article", builtinArticle)
+	register("markdown", builtinMarkdown)
+	register("links", builtinLinks)
+
+	registerRuntime := func(name string, fn RuntimeBuiltinFunction) {
+		runtimeBuiltins[name] = fn
+	}
+
+	registerRuntime("session", builtinSession)
+	registerRuntime("cookies", builtinCookies)
+	registerRuntime("set_cookie", builtinSetCookie)
+	registerRuntime("save_cookies", builtinSaveCookies)
+	registerRuntime("load_cookies", builtinLoadCookies)
+	registerRuntime("submit", builtinSubmit)
+}
+
+// bindRuntime 将需要运行时的内置函数绑定到 rt，得到普通的内置函数对象
+func bindRuntime(name string, fn RuntimeBuiltinFunction, rt *Runtime) *Builtin {
+	return &Builtin{Name: name, Fn: func(args ...Object) Object {
+		return fn(rt, args...)
+	}}
+}
+
+// builtinError 创建内置函数错误，位置由调用处补充
+func builtinError(kind errors.ErrorType, format string, a ...interface{}) *Error {
+	return &Error{Message: fmt.Sprintf(format, a...), Kind: kind}
+}
+
+// checkArgs 检查参数数量是否在 [min, max] 范围内，max 小于 0 表示不限
+func checkArgs(name string, args []Object, min, max int) *Error {
+	if len(args) < min || (max >= 0 && len(args) > max) {
+		switch {
+		case min == max:
+			return builtinError(errors.TypeError, "%s: wrong number of arguments. got=%d, want=%d", name, len(args), min)
+		case max < 0:
+			return builtinError(errors.TypeError, "%s: wrong number of arguments. got=%d, want at least %d", name, len(args), min)
+		default:
+			return builtinError(errors.TypeError, "%s: wrong number of arguments. got=%d, want %d to %d", name, len(args), min, max)
+		}
+	}
+	return nil
+}
+
+func argTypeError(name string, arg Object) *Error {
+	return builtinError(errors.TypeError, "%s: argument of type %s not supported", name, arg.Type())
+}
+
+func builtinLength(args ...Object) Object {
+	if err := checkArgs("length", args, 1, 1); err != nil {
+		return err
+	}
+
+	switch arg := args[0].(type) {
+	case *String:
+		return &Integer{Value: int64(len([]rune(arg.Value)))}
+	case *Array:
+		return &Integer{Value: int64(len(arg.Elements))}
+	case *Hash:
+		return &Integer{Value: int64(len(arg.Pairs))}
+	case *Null:
+		return &Integer{Value: 0}
+	}
+	return argTypeError("length", args[0])
+}
+
+func builtinKeys(args ...Object) Object {
+	if err := checkArgs("keys", args, 1, 1); err != nil {
+		return err
+	}
+
+	hash, ok := args[0].(*Hash)
+	if !ok {
+		return argTypeError("keys", args[0])
+	}
+
+	pairs := sortedPairs(hash)
+	elements := make([]Object, len(pairs))
+	for i, pair := range pairs {
+		elements[i] = pair.Key
+	}
+	return &Array{Elements: elements}
+}
+
+func builtinValues(args ...Object) Object {
+	if err := checkArgs("values", args, 1, 1); err != nil {
+		return err
+	}
+
+	hash, ok := args[0].(*Hash)
+	if !ok {
+		return argTypeError("values", args[0])
+	}
+
+	pairs := sortedPairs(hash)
+	elements := make([]Object, len(pairs))
+	for i, pair := range pairs {
+		elements[i] = pair.Value
+	}
+	return &Array{Elements: elements}
+}
+
+func builtinDelete(args ...Object) Object {
+	if err := checkArgs("delete", args, 2, 2); err != nil {
+		return err
+	}
+
+	hash, ok := args[0].(*Hash)
+	if !ok {
+		return argTypeError("delete", args[0])
+	}
+
+	key, ok := args[1].(Hashable)
+	if !ok {
+		return builtinError(errors.TypeError, "unusable as hash key: %s", args[1].Type())
+	}
+
+	pair, ok := hash.Pairs[key.HashKey()]
+	if !ok {
+		return NULL
+	}
+	delete(hash.Pairs, key.HashKey())
+	return pair.Value
+}
+
+func builtinPush(args ...Object) Object {
+	if err := checkArgs("push", args, 2, -1); err != nil {
+		return err
+	}
+
+	array, ok := args[0].(*Array)
+	if !ok {
+		return argTypeError("push", args[0])
+	}
+
+	array.Elements = append(array.Elements, args[1:]...)
+	return array
+}
+
+func builtinFirst(args ...Object) Object {
+	if err := checkArgs("first", args, 1, 1); err != nil {
+		return err
+	}
+
+	array, ok := args[0].(*Array)
+	if !ok {
+		return argTypeError("first", args[0])
+	}
+	if len(array.Elements) == 0 {
+		return NULL
+	}
+	return array.Elements[0]
+}
+
+func builtinLast(args ...Object) Object {
+	if err := checkArgs("last", args, 1, 1); err != nil {
+		return err
+	}
+
+	array, ok := args[0].(*Array)
+	if !ok {
+		return argTypeError("last", args[0])
+	}
+	if len(array.Elements) == 0 {
+		return NULL
+	}
+	return array.Elements[len(array.Elements)-1]
+}
+
+// builtinRange 返回 [start, end) 的整数数组：range(end) 或 range(start, end)
+func builtinRange(args ...Object) Object {
+	if err := checkArgs("range", args, 1, 2); err != nil {
+		return err
+	}
+
+	bounds := make([]int64, len(args))
+	for i, arg := range args {
+		n, ok := arg.(*Integer)
+		if !ok {
+			return argTypeError("range", arg)
+		}
+		bounds[i] = n.Value
+	}
+
+	start, end := int64(0), bounds[0]
+	if len(bounds) == 2 {
+		start, end = bounds[0], bounds[1]
+	}
+
+	elements := []Object{}
+	for i := start; i < end; i++ {
+		elements = append(elements, &Integer{Value: i})
+	}
+	return &Array{Elements: elements}
+}
+
+func builtinType(args ...Object) Object {
+	if err := checkArgs("type", args, 1, 1); err != nil {
+		return err
+	}
+	return &String{Value: strings.ToLower(string(args[0].Type()))}
+}
+
+func builtinStr(args ...Object) Object {
+	if err := checkArgs("str", args, 1, 1); err != nil {
+		return err
+	}
+	if s, ok := args[0].(*String); ok {
+		return s
+	}
+	return &String{Value: args[0].Inspect()}
+}
+
+func builtinInt(args ...Object) Object {
+	if err := checkArgs("int", args, 1, 1); err != nil {
+		return err
+	}
+
+	switch arg := args[0].(type) {
+	case *Integer:
+		return arg
+	case *Float:
+		return &Integer{Value: int64(arg.Value)}
+	case *Boolean:
+		if arg.Value {
+			return &Integer{Value: 1}
+		}
+		return &Integer{Value: 0}
+	case *String:
+		value, err := strconv.ParseInt(strings.TrimSpace(arg.Value), 10, 64)
+		if err != nil {
+			return builtinError(errors.TypeError, "int: cannot convert %q to integer", arg.Value)
+		}
+		return &Integer{Value: value}
+	}
+	return argTypeError("int", args[0])
+}
+
+func builtinFloat(args ...Object) Object {
+	if err := checkArgs("float", args, 1, 1); err != nil {
+		return err
+	}
+
+	switch arg := args[0].(type) {
+	case *Integer:
+		return &Float{Value: float64(arg.Value)}
+	case *Float:
+		return arg
+	case *String:
+		value, err := strconv.ParseFloat(strings.TrimSpace(arg.Value), 64)
+		if err != nil {
+			return builtinError(errors.TypeError, "float: cannot convert %q to float", arg.Value)
+		}
+		return &Float{Value: value}
+	}
+	return argTypeError("float", args[0])
+}
+
+func builtinJoin(args ...Object) Object {
+	if err := checkArgs("join", args, 1, 2); err != nil {
+		return err
+	}
+
+	array, ok := args[0].(*Array)
+	if !ok {
+		return argTypeError("join", args[0])
+	}
+
+	sep := ""
+	if len(args) == 2 {
+		s, ok := args[1].(*String)
+		if !ok {
+			return argTypeError("join", args[1])
+		}
+		sep = s.Value
+	}
+
+	parts := make([]string, len(array.Elements))
+	for i, e := range array.Elements {
+		parts[i] = e.Inspect()
+	}
+	return &String{Value: strings.Join(parts, sep)}
+}
+
+func builtinSplit(args ...Object) Object {
+	if err := checkArgs("split", args, 2, 2); err != nil {
+		return err
+	}
+
+	s, ok := args[0].(*String)
+	if !ok {
+		return argTypeError("split", args[0])
+	}
+	sep, ok := args[1].(*String)
+	if !ok {
+		return argTypeError("split", args[1])
+	}
+
+	parts := strings.Split(s.Value, sep.Value)
+	elements := make([]Object, len(parts))
+	for i, part := range parts {
+		elements[i] = &String{Value: part}
+	}
+	return &Array{Elements: elements}
+}
+
+// stringBuiltin 创建对单个字符串参数进行转换的内置函数
+func stringBuiltin(name string, fn func(string) string) BuiltinFunction {
+	return func(args ...Object) Object {
+		if err := checkArgs(name, args, 1, 1); err != nil {
+			return err
+		}
+		switch arg := args[0].(type) {
+		case *String:
+			return &String{Value: fn(arg.Value)}
+		case *Null:
+			return NULL
+		}
+		return argTypeError(name, args[0])
+	}
+}
+
+var (
+	builtinTrim  = stringBuiltin("trim", strings.TrimSpace)
+	builtinLower = stringBuiltin("lower", strings.ToLower)
+	builtinUpper = stringBuiltin("upper", strings.ToUpper)
+)
+
+func builtinReplace(args ...Object) Object {
+	if err := checkArgs("replace", args, 3, 3); err != nil {
+		return err
+	}
+
+	strs := make([]string, 3)
+	for i, arg := range args {
+		s, ok := arg.(*String)
+		if !ok {
+			return argTypeError("replace", arg)
+		}
+		strs[i] = s.Value
+	}
+	return &String{Value: strings.ReplaceAll(strs[0], strs[1], strs[2])}
+}
+
+// builtinContains 判断字符串是否包含子串、数组是否包含元素或哈希是否包含键
+func builtinContains(args ...Object) Object {
+	if err := checkArgs("contains", args, 2, 2); err != nil {
+		return err
+	}
+
+	switch container := args[0].(type) {
+	case *String:
+		sub, ok := args[1].(*String)
+		if !ok {
+			return argTypeError("contains", args[1])
+		}
+		return nativeBoolToBooleanObject(strings.Contains(container.Value, sub.Value))
+	case *Array:
+		for _, e := range container.Elements {
+			if objectsEqual(e, args[1]) {
+				return TRUE
+			}
+		}
+		return FALSE
+	case *Hash:
+		key, ok := args[1].(Hashable)
+		if !ok {
+			return FALSE
+		}
+		_, ok = container.Pairs[key.HashKey()]
+		return nativeBoolToBooleanObject(ok)
+	}
+	return argTypeError("contains", args[0])
+}
+
+// joinInspect 以空格连接参数的字符串表示
+func joinInspect(args []Object) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = arg.Inspect()
+	}
+	return strings.Join(parts, " ")
+}
+
+func builtinPrint(args ...Object) Object {
+	fmt.Fprintln(Stdout, joinInspect(args))
+	return NULL
+}
+
+// logBuiltin 创建带日志级别前缀的日志函数
+func logBuiltin(level string) BuiltinFunction {
+	return func(args ...Object) Object {
+		fmt.Fprintf(Stderr, "[%s] %s\n", level, joinInspect(args))
+		return NULL
+	}
+}
+
+// builtinDebug 仅在调试模式下输出
+func builtinDebug(args ...Object) Object {
+	debug.Print("%s", joinInspect(args))
+	return NULL
+}
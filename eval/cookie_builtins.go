@@ -0,0 +1,175 @@
+package eval
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/btrobot/mydsl/errors"
+)
+
+// builtinSession 不带参数时返回当前会话名称；传入名称时切换到该会话并返回之前的会话名称
+// 每个会话有独立的 cookie，例如 session("member") 之后登录，再 session("default") 切回匿名访问
+func builtinSession(rt *Runtime, args ...Object) Object {
+	if err := checkArgs("session", args, 0, 1); err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return &String{Value: rt.Session()}
+	}
+	name, ok := args[0].(*String)
+	if !ok {
+		return argTypeError("session", args[0])
+	}
+	if name.Value == "" {
+		return builtinError(errors.RuntimeError, "session: name must not be empty")
+	}
+	return &String{Value: rt.UseSession(name.Value)}
+}
+
+// cookieURL 将参数解析为带主机名的 http(s) URL
+func cookieURL(name string, arg Object) (*url.URL, *Error) {
+	s, ok := arg.(*String)
+	if !ok {
+		return nil, argTypeError(name, arg)
+	}
+	u, err := url.Parse(s.Value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, builtinError(errors.RuntimeError, "%s: invalid URL %q: want absolute http or https URL", name, s.Value)
+	}
+	return u, nil
+}
+
+// builtinCookies 返回当前会话请求 url 时会发送的 cookie，结果为名称到值的哈希
+func builtinCookies(rt *Runtime, args ...Object) Object {
+	if err := checkArgs("cookies", args, 1, 1); err != nil {
+		return err
+	}
+	u, errObj := cookieURL("cookies", args[0])
+	if errObj != nil {
+		return errObj
+	}
+
+	values := map[string]string{}
+	for _, c := range rt.Fetcher.Jar().Cookies(u) {
+		if _, ok := values[c.Name]; !ok {
+			values[c.Name] = c.Value
+		}
+	}
+	return stringMapToHash(values)
+}
+
+// builtinSetCookie 在当前会话中为 url 设置 cookie：set_cookie(url, name, value, options)
+// 选项 domain、path、secure、http_only 和 max_age（秒，0 或负数表示删除）与 Set-Cookie 属性相同
+func builtinSetCookie(rt *Runtime, args ...Object) Object {
+	if err := checkArgs("set_cookie", args, 3, 4); err != nil {
+		return err
+	}
+	u, errObj := cookieURL("set_cookie", args[0])
+	if errObj != nil {
+		return errObj
+	}
+	name, ok := args[1].(*String)
+	if !ok {
+		return argTypeError("set_cookie", args[1])
+	}
+	if name.Value == "" {
+		return builtinError(errors.RuntimeError, "set_cookie: name must not be empty")
+	}
+	value, ok := args[2].(*String)
+	if !ok {
+		return argTypeError("set_cookie", args[2])
+	}
+
+	c := &http.Cookie{Name: name.Value, Value: value.Value}
+	if len(args) == 4 {
+		options, ok := args[3].(*Hash)
+		if !ok {
+			return argTypeError("set_cookie", args[3])
+		}
+		if errObj := cookieOptions(options, c); errObj != nil {
+			return errObj
+		}
+	}
+	rt.Fetcher.Jar().SetCookies(u, []*http.Cookie{c})
+	return NULL
+}
+
+// cookieOptions 将 set_cookie 的选项哈希写入 c
+func cookieOptions(options *Hash, c *http.Cookie) *Error {
+	for _, pair := range options.Pairs {
+		key, ok := pair.Key.(*String)
+		if !ok {
+			return builtinError(errors.TypeError, "set_cookie: option key must be STRING, got %s", pair.Key.Type())
+		}
+		switch key.Value {
+		case "domain", "path":
+			s, ok := pair.Value.(*String)
+			if !ok {
+				return builtinError(errors.TypeError, "set_cookie: %s must be STRING, got %s", key.Value, pair.Value.Type())
+			}
+			if key.Value == "domain" {
+				c.Domain = s.Value
+			} else {
+				c.Path = s.Value
+			}
+		case "secure", "http_only":
+			b, ok := pair.Value.(*Boolean)
+			if !ok {
+				return builtinError(errors.TypeError, "set_cookie: %s must be BOOLEAN, got %s", key.Value, pair.Value.Type())
+			}
+			if key.Value == "secure" {
+				c.Secure = b.Value
+			} else {
+				c.HttpOnly = b.Value
+			}
+		case "max_age":
+			n, ok := pair.Value.(*Integer)
+			if !ok {
+				return builtinError(errors.TypeError, "set_cookie: max_age must be INTEGER, got %s", pair.Value.Type())
+			}
+			// http.Cookie 中 MaxAge 为 0 表示未设置，负数表示立即过期
+			c.MaxAge = int(n.Value)
+			if n.Value <= 0 {
+				c.MaxAge = -1
+			}
+		default:
+			return builtinError(errors.TypeError, "set_cookie: unknown option %q", key.Value)
+		}
+	}
+	return nil
+}
+
+// builtinSaveCookies 将当前会话的 cookie 保存到文件，包括会话 cookie
+func builtinSaveCookies(rt *Runtime, args ...Object) Object {
+	path, errObj := cookieFile("save_cookies", args)
+	if errObj != nil {
+		return errObj
+	}
+	if err := rt.Fetcher.Jar().Save(path); err != nil {
+		return builtinError(errors.RuntimeError, "save_cookies: %v", err)
+	}
+	return NULL
+}
+
+// builtinLoadCookies 从 save_cookies 写入的文件中读取 cookie 并加入当前会话
+func builtinLoadCookies(rt *Runtime, args ...Object) Object {
+	path, errObj := cookieFile("load_cookies", args)
+	if errObj != nil {
+		return errObj
+	}
+	if err := rt.Fetcher.Jar().Load(path); err != nil {
+		return builtinError(errors.RuntimeError, "load_cookies: %v", err)
+	}
+	return NULL
+}
+
+func cookieFile(name string, args []Object) (string, *Error) {
+	if err := checkArgs(name, args, 1, 1); err != nil {
+		return "", err
+	}
+	path, ok := args[0].(*String)
+	if !ok {
+		return "", argTypeError(name, args[0])
+	}
+	return path.Value, nil
+}
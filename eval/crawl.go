@@ -0,0 +1,298 @@
+package eval
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"regexp"
+
+	"github.com/btrobot/mydsl/ast"
+	"github.com/btrobot/mydsl/crawler/extract"
+	"github.com/btrobot/mydsl/crawler/fetch"
+	"github.com/btrobot/mydsl/crawler/frontier"
+	"golang.org/x/net/html"
+)
+
+// crawlConfig 表示 crawl 的选项
+type crawlConfig struct {
+	frontier frontier.Options
+	follow   string // 限定跟进链接的选择器，为空时跟进页面中的所有链接
+	limit    int    // 最多处理的页面数，0 表示不限
+}
+
+// errStopCrawl 用于在回调出错时停止爬取，实际的错误对象另行保存
+var errStopCrawl = fmt.Errorf("crawl stopped")
+
+// evalCrawlExpression 从种子 URL 开始爬取，对每个页面调用回调函数
+// 回调以 (page, depth) 调用，page 与 open 的结果相同；返回值中非 null 的部分组成结果数组。
+// 选项：depth（最大深度，默认不限）、follow（跟进链接的选择器，默认所有链接）、
+// strategy（bfs、dfs 或 priority）、priority（fn(url, depth) 返回优先级）、
+// allow/deny（正则字符串或数组）、same_domain（默认 true）、sort_query 和 limit（页面上限）。
+// 抓取失败的页面被跳过，只从 HTML 页面（或没有 Content-Type 的响应）中跟进链接，
+// 回调出错时爬取停止并返回该错误。
+// 运行时设置了检查点时，上次已完成的页面不再抓取，回调也不会再次调用
+func evalCrawlExpression(node *ast.CrawlExpression, input Object, env *Environment) Object {
+	seedsObj, options, errObj := evalSourceAndOptions(node, node.Seeds, node.Options, input, env)
+	if errObj != nil {
+		return errObj
+	}
+	if seedsObj == nil {
+		return newError(node, "crawl requires seed URLs")
+	}
+	seeds, errObj := crawlSeeds(node, seedsObj)
+	if errObj != nil {
+		return errObj
+	}
+
+	callback := Eval(node.Callback, env)
+	if isError(callback) {
+		return callback
+	}
+	switch callback.(type) {
+	case *Function, *Builtin:
+	default:
+		return newTypeError(node, "crawl: callback must be FUNCTION, got %s", callback.Type())
+	}
+
+	// priority 回调的错误在下一次处理页面时报告
+	var stopErr *Error
+	cfg, errObj := crawlOptions(node, options, func(errObj *Error) {
+		if stopErr == nil {
+			stopErr = errObj
+		}
+	})
+	if errObj != nil {
+		return errObj
+	}
+
+	rt := env.Runtime()
+	f := frontier.New(cfg.frontier)
+	if rt.Checkpoint != nil {
+		f.UseCheckpoint(rt.Checkpoint, rt.nextCrawlID())
+	}
+	for _, seed := range seeds {
+		if err := f.AddSeed(seed); err != nil {
+			return newError(node, "crawl: invalid seed: %v", err)
+		}
+	}
+
+	crawler := &frontier.Crawler{Fetcher: rt.Fetcher, Frontier: f, MaxPages: cfg.limit}
+	results := []Object{}
+	_, err := crawler.Run(rt.Context, func(item *frontier.Item, resp *fetch.Response) ([]string, error) {
+		if stopErr != nil {
+			return nil, errStopCrawl
+		}
+
+		page := newHTTPResponse(resp)
+		result := applyFunction(node, callback, []Object{page, &Integer{Value: int64(item.Depth)}})
+		if errObj, ok := result.(*Error); ok {
+			stopErr = errObj
+			return nil, errStopCrawl
+		}
+		if result != NULL {
+			results = append(results, result)
+		}
+
+		if resp.StatusCode >= 400 || !isHTMLResponse(resp) {
+			return nil, nil
+		}
+		links, errObj := followLinks(node, page, cfg.follow)
+		if errObj != nil {
+			stopErr = errObj
+			return nil, errStopCrawl
+		}
+		return links, nil
+	})
+	if stopErr != nil {
+		return stopErr
+	}
+	if _, ok := err.(*frontier.CheckpointError); ok {
+		return newError(node, "crawl: %v", err)
+	}
+	if err != nil {
+		return newNetworkError(node, "crawl: %v", err)
+	}
+	return &Array{Elements: results}
+}
+
+// crawlSeeds 将种子参数转换为 URL 列表，接受字符串或字符串数组
+func crawlSeeds(node ast.Node, obj Object) ([]string, *Error) {
+	switch obj := obj.(type) {
+	case *String:
+		return []string{obj.Value}, nil
+	case *Array:
+		seeds := make([]string, len(obj.Elements))
+		for i, elem := range obj.Elements {
+			s, ok := elem.(*String)
+			if !ok {
+				return nil, newTypeError(node, "crawl: seed URL must be STRING, got %s", elem.Type())
+			}
+			seeds[i] = s.Value
+		}
+		return seeds, nil
+	}
+	return nil, newTypeError(node, "crawl: seeds must be STRING or ARRAY, got %s", obj.Type())
+}
+
+// crawlOptions 读取 crawl 的选项哈希，onError 接收 priority 回调产生的错误
+func crawlOptions(node *ast.CrawlExpression, options *Hash, onError func(*Error)) (*crawlConfig, *Error) {
+	cfg := &crawlConfig{frontier: frontier.DefaultOptions()}
+	cfg.frontier.SameDomain = true
+	if options == nil {
+		return cfg, nil
+	}
+
+	strategySet := false
+	for _, pair := range options.Pairs {
+		key, ok := pair.Key.(*String)
+		if !ok {
+			return nil, newTypeError(node, "crawl: option key must be STRING, got %s", pair.Key.Type())
+		}
+		value := pair.Value
+
+		switch key.Value {
+		case "depth", "limit":
+			n, ok := value.(*Integer)
+			if !ok || n.Value < 0 {
+				return nil, newTypeError(node, "crawl: %s must be a non-negative INTEGER, got %s", key.Value, value.Inspect())
+			}
+			if key.Value == "depth" {
+				cfg.frontier.MaxDepth = int(n.Value)
+			} else {
+				cfg.limit = int(n.Value)
+			}
+		case "follow":
+			selector, errObj := selectorValue(node, value)
+			if errObj != nil {
+				return nil, errObj
+			}
+			// 在开始抓取前检查选择器是否有效
+			empty := extract.NewDocument(&html.Node{Type: html.DocumentNode})
+			if _, err := extract.Extract(empty, selector); err != nil {
+				return nil, newSelectorError(node, "crawl: %v", err)
+			}
+			cfg.follow = selector
+		case "strategy":
+			name, ok := value.(*String)
+			if !ok {
+				return nil, newTypeError(node, "crawl: strategy must be STRING, got %s", value.Type())
+			}
+			strategy, err := frontier.ParseStrategy(name.Value)
+			if err != nil {
+				return nil, newTypeError(node, "crawl: %v", err)
+			}
+			cfg.frontier.Strategy = strategy
+			strategySet = true
+		case "priority":
+			switch value.(type) {
+			case *Function, *Builtin:
+			default:
+				return nil, newTypeError(node, "crawl: priority must be FUNCTION, got %s", value.Type())
+			}
+			cfg.frontier.Priority = crawlPriority(node, value, onError)
+		case "allow", "deny":
+			patterns, errObj := crawlPatterns(node, key.Value, value)
+			if errObj != nil {
+				return nil, errObj
+			}
+			if key.Value == "allow" {
+				cfg.frontier.Allow = patterns
+			} else {
+				cfg.frontier.Deny = patterns
+			}
+		case "same_domain", "sort_query":
+			flag, ok := value.(*Boolean)
+			if !ok {
+				return nil, newTypeError(node, "crawl: %s must be BOOLEAN, got %s", key.Value, value.Type())
+			}
+			if key.Value == "same_domain" {
+				cfg.frontier.SameDomain = flag.Value
+			} else {
+				cfg.frontier.SortQuery = flag.Value
+			}
+		default:
+			return nil, newTypeError(node, "crawl: unknown option %q", key.Value)
+		}
+	}
+
+	// 只提供 priority 回调时使用优先级顺序
+	if !strategySet && cfg.frontier.Priority != nil {
+		cfg.frontier.Strategy = frontier.PriorityOrder
+	}
+	return cfg, nil
+}
+
+// crawlPriority 将 DSL 函数包装为队列的优先级函数，非数字结果视为 0
+func crawlPriority(node ast.Node, fn Object, onError func(*Error)) func(string, int) float64 {
+	return func(url string, depth int) float64 {
+		result := applyFunction(node, fn, []Object{&String{Value: url}, &Integer{Value: int64(depth)}})
+		switch result := result.(type) {
+		case *Integer:
+			return float64(result.Value)
+		case *Float:
+			return result.Value
+		case *Error:
+			onError(result)
+		}
+		return 0
+	}
+}
+
+// crawlPatterns 编译 allow/deny 选项中的正则表达式，接受字符串或字符串数组
+func crawlPatterns(node ast.Node, name string, value Object) ([]*regexp.Regexp, *Error) {
+	var sources []Object
+	switch value := value.(type) {
+	case *String:
+		sources = []Object{value}
+	case *Array:
+		sources = value.Elements
+	default:
+		return nil, newTypeError(node, "crawl: %s must be STRING or ARRAY, got %s", name, value.Type())
+	}
+
+	patterns := make([]*regexp.Regexp, 0, len(sources))
+	for _, src := range sources {
+		s, ok := src.(*String)
+		if !ok {
+			return nil, newTypeError(node, "crawl: %s pattern must be STRING, got %s", name, src.Type())
+		}
+		re, err := regexp.Compile(s.Value)
+		if err != nil {
+			return nil, newError(node, "crawl: invalid %s pattern: %v", name, err)
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns, nil
+}
+
+// isHTMLResponse 判断响应的 Content-Type 是否为 text/html 或 application/xhtml+xml，
+// 没有 Content-Type 时按 HTML 处理
+func isHTMLResponse(resp *fetch.Response) bool {
+	value := http.Header(resp.Headers).Get("Content-Type")
+	if value == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(value)
+	return err == nil && (mediaType == "text/html" || mediaType == "application/xhtml+xml")
+}
+
+// followLinks 返回页面中需要跟进的绝对链接，selector 为空时返回所有链接
+func followLinks(node ast.Node, page *HTTPResponse, selector string) ([]string, *Error) {
+	tree, err := page.Document().Tree()
+	if err != nil {
+		return nil, newError(node, "crawl: cannot parse HTML from %s: %v", page.URL, err)
+	}
+
+	nodes := []*html.Node{tree.Root}
+	if selector != "" {
+		results, err := extract.Extract(tree, selector)
+		if err != nil {
+			return nil, newSelectorError(node, "crawl: %v", err)
+		}
+		nodes = nodes[:0]
+		for _, r := range results {
+			nodes = append(nodes, r.Node())
+		}
+	}
+	return extract.Links(nodes, tree.BaseURL(), extract.LinkOptions{}), nil
+}
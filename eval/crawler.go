@@ -0,0 +1,387 @@
+package eval
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/btrobot/mydsl/ast"
+	"github.com/btrobot/mydsl/crawler/extract"
+	"github.com/btrobot/mydsl/crawler/fetch"
+	"github.com/btrobot/mydsl/errors"
+)
+
+// evalOpenExpression 抓取 URL 并返回 HTTPResponse
+// input 不为 nil 时表示管道左侧传入的 URL；选项哈希可以指定方法、请求头、查询参数和请求体
+func evalOpenExpression(node *ast.OpenExpression, input Object, env *Environment) Object {
+	target, options, errObj := evalSourceAndOptions(node, node.URL, node.Options, input, env)
+	if errObj != nil {
+		return errObj
+	}
+	if target == nil {
+		return newError(node, "open requires a URL")
+	}
+
+	url, ok := target.(*String)
+	if !ok {
+		return newTypeError(node, "open: URL must be STRING, got %s", target.Type())
+	}
+
+	req := &fetch.Request{URL: url.Value}
+	if options != nil {
+		if errObj := requestOptions("open", options, req); errObj != nil {
+			errObj.Line, errObj.Column = node.Position()
+			return errObj
+		}
+	}
+
+	rt := env.Runtime()
+	resp, err := rt.Fetcher.Do(rt.Context, req)
+	if err != nil {
+		return newNetworkError(node, "open %s: %v", url.Value, err)
+	}
+
+	return newHTTPResponse(resp)
+}
+
+// newHTTPResponse 将 fetch.Response 转换为 HTTPResponse 对象
+func newHTTPResponse(resp *fetch.Response) *HTTPResponse {
+	headers := make(map[string]string, len(resp.Headers))
+	for k, v := range resp.Headers {
+		headers[k] = strings.Join(v, ", ")
+	}
+
+	url := resp.FinalURL
+	if url == "" {
+		url = resp.URL
+	}
+
+	return &HTTPResponse{
+		StatusCode: resp.StatusCode,
+		Body:       string(resp.Body),
+		Headers:    headers,
+		URL:        url,
+	}
+}
+
+// stringMapToHash 将字符串映射转换为哈希对象
+func stringMapToHash(m map[string]string) *Hash {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make(map[HashKey]HashPair, len(m))
+	for _, k := range keys {
+		key := &String{Value: k}
+		pairs[key.HashKey()] = HashPair{Key: key, Value: &String{Value: m[k]}}
+	}
+	return &Hash{Pairs: pairs}
+}
+
+// newNetworkError 在节点位置创建网络错误
+func newNetworkError(node ast.Node, format string, a ...interface{}) *Error {
+	return newErrorOfKind(errors.NetworkError, node, format, a...)
+}
+
+// evalExtractExpression 使用选择器从数据源中提取元素，返回元素数组
+// 数据源为元素数组时分别在每个元素内提取，返回与之一一对应的数组的数组
+// input 不为 nil 时表示管道左侧传入的数据源
+func evalExtractExpression(node *ast.ExtractExpression, input Object, env *Environment) Object {
+	source, errObj := evalSource(node, node.Source, input, env)
+	if errObj != nil {
+		return errObj
+	}
+
+	selector := Eval(node.Selector, env)
+	if isError(selector) {
+		return selector
+	}
+	sel, errObj := selectorValue(node.Selector, selector)
+	if errObj != nil {
+		return errObj
+	}
+
+	if list, ok := source.(*Array); ok {
+		scopes, errObj := extractScopes(node, list)
+		if errObj != nil {
+			return errObj
+		}
+		out := make([]Object, len(scopes))
+		for i, scope := range scopes {
+			out[i] = extractElements(node, scope, sel, false)
+			if isError(out[i]) {
+				return out[i]
+			}
+		}
+		return &Array{Elements: out}
+	}
+
+	scope, errObj := extractScope(node, source)
+	if errObj != nil {
+		return errObj
+	}
+	return extractElements(node, scope, sel, false)
+}
+
+// collectField 表示 collect 结果中的一个字段
+type collectField struct {
+	name     string
+	selector string
+	node     ast.Node
+}
+
+// evalCollectExpression 对多个选择器分别提取，并将结果组合为哈希数组
+// 每个选择器对应结果中的一个字段，字段名为选择器本身；
+// 也可以传入 {name: @sel} 形式的哈希来指定字段名
+//
+// 数据源为文档或单个元素时按行组合各选择器的第 i 个结果；
+// 数据源为元素数组时每个元素生成一条记录，字段取该元素内的第一个匹配，
+// 缺少的字段为 null，因此记录始终与元素对齐
+func evalCollectExpression(node *ast.CollectExpression, input Object, env *Environment) Object {
+	source, errObj := evalSource(node, node.Source, input, env)
+	if errObj != nil {
+		return errObj
+	}
+
+	fields, errObj := evalCollectFields(node, env)
+	if errObj != nil {
+		return errObj
+	}
+
+	if list, ok := source.(*Array); ok {
+		scopes, errObj := extractScopes(node, list)
+		if errObj != nil {
+			return errObj
+		}
+		records := make([]Object, len(scopes))
+		for i, scope := range scopes {
+			values := make([]Object, len(fields))
+			for j, field := range fields {
+				extracted := extractElements(field.node, scope, field.selector, true)
+				if isError(extracted) {
+					return extracted
+				}
+				values[j] = NULL
+				if elements := extracted.(*Array).Elements; len(elements) > 0 {
+					values[j] = elements[0]
+				}
+			}
+			records[i] = newRecord(fields, values)
+		}
+		return &Array{Elements: records}
+	}
+
+	scope, errObj := extractScope(node, source)
+	if errObj != nil {
+		return errObj
+	}
+
+	columns := make([][]Object, len(fields))
+	rows := 0
+	for i, field := range fields {
+		extracted := extractElements(field.node, scope, field.selector, true)
+		if isError(extracted) {
+			return extracted
+		}
+		columns[i] = extracted.(*Array).Elements
+		if len(columns[i]) > rows {
+			rows = len(columns[i])
+		}
+	}
+
+	records := make([]Object, rows)
+	for row := 0; row < rows; row++ {
+		values := make([]Object, len(fields))
+		for i := range fields {
+			values[i] = NULL
+			if row < len(columns[i]) {
+				values[i] = columns[i][row]
+			}
+		}
+		records[row] = newRecord(fields, values)
+	}
+
+	return &Array{Elements: records}
+}
+
+// evalCollectFields 计算 collect 的选择器参数
+func evalCollectFields(node *ast.CollectExpression, env *Environment) ([]collectField, *Error) {
+	var fields []collectField
+	for _, selNode := range node.Selectors {
+		val := Eval(selNode, env)
+		if errObj, ok := val.(*Error); ok {
+			return nil, errObj
+		}
+
+		if hash, ok := val.(*Hash); ok {
+			for _, pair := range sortedPairs(hash) {
+				name, ok := pair.Key.(*String)
+				if !ok {
+					return nil, newTypeError(selNode, "collect: field name must be STRING, got %s", pair.Key.Type())
+				}
+				sel, errObj := selectorValue(selNode, pair.Value)
+				if errObj != nil {
+					return nil, errObj
+				}
+				fields = append(fields, collectField{name: name.Value, selector: sel, node: selNode})
+			}
+			continue
+		}
+
+		sel, errObj := selectorValue(selNode, val)
+		if errObj != nil {
+			return nil, errObj
+		}
+		fields = append(fields, collectField{name: sel, selector: sel, node: selNode})
+	}
+	return fields, nil
+}
+
+// newRecord 以字段名为键创建记录
+func newRecord(fields []collectField, values []Object) *Hash {
+	pairs := make(map[HashKey]HashPair, len(fields))
+	for i, field := range fields {
+		key := &String{Value: field.name}
+		pairs[key.HashKey()] = HashPair{Key: key, Value: values[i]}
+	}
+	return &Hash{Pairs: pairs}
+}
+
+// evalSourceAndOptions 计算 open、crawl 的数据源与选项
+// 只给出一个可选参数时按运行时的类型区分：哈希是选项，此时数据源来自管道；
+// 其余值是数据源，此时不能同时有管道输入。没有数据源时返回的 source 为 nil
+func evalSourceAndOptions(node ast.Node, sourceNode, optionsNode ast.Expression, input Object, env *Environment) (source Object, options *Hash, errObj *Error) {
+	name := node.TokenLiteral()
+	source = input
+	if sourceNode != nil {
+		source = Eval(sourceNode, env)
+		if errObj, ok := source.(*Error); ok {
+			return nil, nil, errObj
+		}
+	}
+
+	switch {
+	case optionsNode != nil:
+		obj := Eval(optionsNode, env)
+		if errObj, ok := obj.(*Error); ok {
+			return nil, nil, errObj
+		}
+		hash, ok := obj.(*Hash)
+		if !ok {
+			return nil, nil, newTypeError(node, "%s: options must be HASH, got %s", name, obj.Type())
+		}
+		return source, hash, nil
+	case sourceNode == nil:
+		return source, nil, nil
+	}
+
+	if hash, ok := source.(*Hash); ok {
+		return input, hash, nil
+	}
+	if input != nil {
+		return nil, nil, newTypeError(node, "%s: piped value and %s argument both given; in a pipeline the argument must be an options HASH", name, source.Type())
+	}
+	return source, nil, nil
+}
+
+// evalSource 计算提取操作的数据源
+func evalSource(node ast.Node, sourceNode ast.Expression, input Object, env *Environment) (Object, *Error) {
+	source := input
+	if sourceNode != nil {
+		source = Eval(sourceNode, env)
+		if errObj, ok := source.(*Error); ok {
+			return nil, errObj
+		}
+	}
+	if source == nil {
+		return nil, newError(node, "%s requires a source document", node.TokenLiteral())
+	}
+	return source, nil
+}
+
+// extractFunc 在某个作用域（整个文档或其中的元素）内按选择器提取
+type extractFunc func(selector string) ([]*extract.Result, error)
+
+// extractScope 返回数据源对应的提取作用域
+// HTML 文档与响应会缓存解析结果，字符串数据源每次重新解析，元素在其子树内提取
+func extractScope(node ast.Node, source Object) (extractFunc, *Error) {
+	var doc *HTMLDocument
+	switch source := source.(type) {
+	case *Element:
+		return source.Result.Extract, nil
+	case *HTMLDocument:
+		doc = source
+	case *HTTPResponse:
+		doc = source.Document()
+	case *String:
+		doc = &HTMLDocument{Content: source.Value}
+	default:
+		return nil, newTypeError(node, "%s: source must be HTML_DOC, HTTP_RESPONSE, ELEMENT or STRING, got %s",
+			node.TokenLiteral(), source.Type())
+	}
+
+	tree, err := doc.Tree()
+	if err != nil {
+		return nil, newError(node, "%s: cannot parse HTML: %v", node.TokenLiteral(), err)
+	}
+	return func(selector string) ([]*extract.Result, error) {
+		return extract.Extract(tree, selector)
+	}, nil
+}
+
+// extractScopes 返回数组中每个数据源对应的提取作用域
+func extractScopes(node ast.Node, list *Array) ([]extractFunc, *Error) {
+	scopes := make([]extractFunc, len(list.Elements))
+	for i, elem := range list.Elements {
+		scope, errObj := extractScope(node, elem)
+		if errObj != nil {
+			return nil, errObj
+		}
+		scopes[i] = scope
+	}
+	return scopes, nil
+}
+
+// selectorValue 将选择器对象或字符串转换为选择器文本
+func selectorValue(node ast.Node, obj Object) (string, *Error) {
+	switch obj := obj.(type) {
+	case *Selector:
+		return obj.Value, nil
+	case *String:
+		return obj.Value, nil
+	}
+	return "", newTypeError(node, "selector must be SELECTOR or STRING, got %s", obj.Type())
+}
+
+// extractElements 执行提取并将结果转换为元素数组
+// 选择器带有 ::text、::attr(name) 等后缀时返回字符串数组；缺少该属性的元素被跳过，
+// keepMissing 为 true 时改为 null，使 collect 的各列按匹配的元素对齐
+func extractElements(node ast.Node, scope extractFunc, selector string, keepMissing bool) Object {
+	base, suffix, err := extract.ParseSuffix(selector)
+	if err != nil {
+		return newSelectorError(node, "%v", err)
+	}
+
+	results, err := scope(base)
+	if err != nil {
+		return newSelectorError(node, "%v", err)
+	}
+
+	elements := make([]Object, 0, len(results))
+	for _, result := range results {
+		if suffix == nil {
+			elements = append(elements, &Element{Result: result})
+		} else if val, ok := result.SuffixValue(suffix); ok {
+			elements = append(elements, &String{Value: val})
+		} else if keepMissing {
+			elements = append(elements, NULL)
+		}
+	}
+	return &Array{Elements: elements}
+}
+
+// newSelectorError 在节点位置创建选择器错误
+func newSelectorError(node ast.Node, format string, a ...interface{}) *Error {
+	return newErrorOfKind(errors.SelectorError, node, format, a...)
+}
@@ -0,0 +1,936 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/btrobot/mydsl/crawler/fetch"
+	"github.com/btrobot/mydsl/crawler/frontier"
+	"github.com/btrobot/mydsl/errors"
+	"github.com/btrobot/mydsl/lexer"
+	"github.com/btrobot/mydsl/parser"
+)
+
+// testEvalWithRuntime 使用不重试的抓取器执行脚本，url 变量指向测试服务器
+func testEvalWithRuntime(t *testing.T, input string, url string) Object {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors()[0])
+	}
+
+	options := fetch.DefaultOptions()
+	options.MaxRetries = 0
+
+	env := NewEnvironment()
+	env.SetRuntime(NewRuntime(context.Background(), fetch.NewFetcher(options)))
+	env.Set("url", &String{Value: url})
+
+	return Eval(program, env)
+}
+
+const listHTML = `<html><body><ul>
+<li class="item"><a href="/a">A</a><span class="price">1</span></li>
+<li class="item"><a href="/b">B</a><span class="price">2</span></li>
+<li class="item"><a href="/c">C</a></li>
+</ul></body></html>`
+
+func newTestServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body><h1>Title</h1></body></html>"))
+	})
+	mux.HandleFunc("/list", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(listHTML))
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page", http.StatusFound)
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestOpen(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+
+	evaluated := testEvalWithRuntime(t, `open(url + "/redirect")`, server.URL)
+
+	resp, ok := evaluated.(*HTTPResponse)
+	if !ok {
+		t.Fatalf("object is not HTTPResponse. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("StatusCode wrong. got=%d", resp.StatusCode)
+	}
+	if resp.URL != server.URL+"/page" {
+		t.Errorf("URL should be the final URL. got=%q", resp.URL)
+	}
+	if resp.Headers["Content-Type"] != "text/html" {
+		t.Errorf("Content-Type header wrong. got=%q", resp.Headers["Content-Type"])
+	}
+	if resp.Document().Content != resp.Body {
+		t.Errorf("Document content does not match body")
+	}
+}
+
+func TestOpenMembers(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`open(url + "/page").status`, int64(200)},
+		{`open(url + "/missing").status`, int64(404)},
+		{`open(url + "/missing").ok`, false},
+		{`(url + "/page") | open() | type`, "http_response"},
+		{`open(url + "/page").headers["Content-Type"]`, "text/html"},
+		{`contains(open(url + "/page").body, "<h1>")`, true},
+		{`type(open(url + "/page").doc)`, "html_doc"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalWithRuntime(t, tt.input, server.URL)
+		switch expected := tt.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case string:
+			testStringObject(t, evaluated, expected)
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		}
+	}
+}
+
+func TestOpenNetworkError(t *testing.T) {
+	server := newTestServer()
+	closedURL := server.URL
+	server.Close()
+
+	evaluated := testEvalWithRuntime(t, "let x = 1\n  open(url)", closedURL)
+
+	errObj, ok := evaluated.(*Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if errObj.Kind != errors.NetworkError {
+		t.Errorf("error kind wrong. got=%d, want=%d", errObj.Kind, errors.NetworkError)
+	}
+	if errObj.Line != 2 || errObj.Column != 3 {
+		t.Errorf("error position wrong. got=%d:%d, want=2:3", errObj.Line, errObj.Column)
+	}
+}
+
+func TestOpenDisallowedByRobots(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+			return
+		}
+		w.Write([]byte("<html><body>ok</body></html>"))
+	}))
+	defer server.Close()
+
+	evaluated := testEvalWithRuntime(t, `open(url + "/private/page")`, server.URL)
+	errObj, ok := evaluated.(*Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Kind != errors.NetworkError {
+		t.Errorf("error kind wrong. got=%d, want=%d", errObj.Kind, errors.NetworkError)
+	}
+	if !strings.Contains(errObj.Message, "disallowed by robots.txt") {
+		t.Errorf("error message wrong. got=%q", errObj.Message)
+	}
+
+	testStringObject(t, testEvalWithRuntime(t, `open(url + "/public").status | str`, server.URL), "200")
+}
+
+func TestExtract(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`len(extract(open(url + "/list"), @"li"))`, 3},
+		{`extract(open(url + "/list"), @"a")[1]["href"]`, "/b"},
+		{`extract(open(url + "/list"), "a")[0].attrs.href`, "/a"},
+		{`extract(open(url + "/list"), @"li")[0].tag`, "li"},
+		{`extract(open(url + "/list"), @"a")[2].text`, "C"},
+		{`type(extract(open(url + "/list"), @"a")[0])`, "element"},
+		{`extract(open(url + "/list"), @"a")[0]["title"]`, nil},
+		{`len(open(url + "/list") | extract(@"span"))`, 2},
+		{`len(extract("<p>1</p><p>2</p>", @"p"))`, 2},
+		{`len(extract("<p>1</p>", @"div"))`, 0},
+		{`extract(open(url + "/list"), @"li.item:nth-child(2) > a")[0].text`, "B"},
+		{`extract(open(url + "/list"), @xpath:"//li[span > 1]/a")[0]["href"]`, "/b"},
+		{`extract(open(url + "/list"), @xpath:"//a/@href")[2].text`, "/c"},
+		{`len(extract(open(url + "/list"), "xpath://li[not(span)]"))`, 1},
+		{`let q = "//a"; len(extract(open(url + "/list"), @xpath:q))`, 3},
+		{`str(@xpath:"//a")`, "@xpath://a"},
+		{`extract(open(url + "/list"), @"a::attr(href)")[1]`, "/b"},
+		{`join(extract(open(url + "/list"), @"a::text"), ",")`, "A,B,C"},
+		{`type(extract(open(url + "/list"), @"a::text")[0])`, "string"},
+		{`len(extract(open(url + "/list"), @"a::attr(title)"))`, 0},
+		{`extract(open(url + "/list"), @"li::own-text")[0]`, ""},
+		{`extract(open(url + "/list"), @"span::html")[0]`, `<span class="price">1</span>`},
+		{`extract(open(url + "/list"), @xpath:"//li[2]/a::text")[0]`, "B"},
+		{`let s = "a::" + "attr(href)"; extract(open(url + "/list"), @s)[2]`, "/c"},
+		{`(open(url + "/list") | collect({link: @"a::attr(href)", price: @"span::text"}))[1].price`, "2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalWithRuntime(t, tt.input, server.URL)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			testStringObject(t, evaluated, expected)
+		case nil:
+			if evaluated != NULL {
+				t.Errorf("input %q: expected NULL. got=%T (%+v)", tt.input, evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestCollect(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`len(collect(open(url + "/list"), @"a", @"span"))`, 3},
+		{`collect(open(url + "/list"), @"a", @"span")[2]["a"]["href"]`, "/c"},
+		{`collect(open(url + "/list"), @"a", @"span")[2]["span"]`, nil},
+		{`keys(collect(open(url + "/list"), {link: @"a", price: "span"})[0])`, "[link, price]"},
+		{`(open(url + "/list") | collect({link: @"a"}))[1].link.attrs.href`, "/b"},
+		{`len(collect("<p>x</p>", @"div"))`, 0},
+		{`collect("<a>x</a><a href='/y'>y</a>", @"a::text", @"a::attr(href)")[1]["a::text"]`, "y"},
+		{`collect("<a>x</a><a href='/y'>y</a>", @"a::text", @"a::attr(href)")[0]["a::attr(href)"]`, nil},
+		{`collect("<a>x</a><a href='/y'>y</a>", {t: @"a::text", h: @"a::attr(href)"})[1].h`, "/y"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalWithRuntime(t, tt.input, server.URL)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			if arr, ok := evaluated.(*Array); ok {
+				if arr.Inspect() != expected {
+					t.Errorf("input %q: expected=%s, got=%s", tt.input, expected, arr.Inspect())
+				}
+				continue
+			}
+			testStringObject(t, evaluated, expected)
+		case nil:
+			if evaluated != NULL {
+				t.Errorf("input %q: expected NULL. got=%T (%+v)", tt.input, evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestExtractErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		kind  errors.ErrorType
+	}{
+		{`extract(1, @"a")`, errors.TypeError},
+		{`extract("<a></a>", 1)`, errors.TypeError},
+		{`collect("<a></a>", {link: 1})`, errors.TypeError},
+		{`extract("<a></a>", @"a[href")`, errors.SelectorError},
+		{`collect("<a></a>", @"a", @"p:bogus")`, errors.SelectorError},
+		{`extract("<a></a>", @xpath:"//a[")`, errors.SelectorError},
+		{`extract("<a></a>", @xpath:"count(//a)")`, errors.SelectorError},
+		{`let s = "a::bogus"; extract("<a></a>", @s)`, errors.SelectorError},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalWithRuntime(t, tt.input, "")
+		errObj, ok := evaluated.(*Error)
+		if !ok {
+			t.Errorf("input %q: no error object returned. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Kind != tt.kind {
+			t.Errorf("input %q: error kind wrong. got=%d, want=%d", tt.input, errObj.Kind, tt.kind)
+		}
+	}
+}
+
+func TestHTMLDocumentParsedOnce(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+
+	evaluated := testEvalWithRuntime(t, `
+let page = open(url + "/list")
+let links = extract(page, @"a")
+let prices = collect(page, @"span")
+page`, server.URL)
+
+	resp, ok := evaluated.(*HTTPResponse)
+	if !ok {
+		t.Fatalf("object is not HTTPResponse. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	tree := resp.Document().tree
+	if tree == nil {
+		t.Fatalf("extract should cache the parsed tree on the document")
+	}
+	if again, _ := resp.Document().Tree(); again != tree {
+		t.Errorf("document tree should be parsed once and reused")
+	}
+	if got := len(tree.ElementsByTag("a")); got != 3 {
+		t.Errorf("parsed tree has %d links, want 3", got)
+	}
+}
+
+func TestScopedExtraction(t *testing.T) {
+	html := `<div class="product"><h2>One</h2><span class="price">10</span></div>` +
+		`<div class="product"><h2>Two</h2></div>` +
+		`<div class="product"><h2>Three</h2><span class="price">30</span></div>`
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let cards = extract(page, @".product"); extract(cards[1], @"h2")[0].text`, "Two"},
+		{`let cards = extract(page, @".product"); len(extract(cards[1], @".price"))`, 0},
+		{`let cards = extract(page, @".product"); extract(cards[2], @":scope > h2")[0].text`, "Three"},
+		{`let cards = extract(page, @".product"); extract(cards[0], @xpath:"./span")[0].text`, "10"},
+		{`len(extract(page, @".product") | extract(@".price"))`, 3},
+		{`len((extract(page, @".product") | extract(@".price"))[1])`, 0},
+		{`len(extract(page, @".product") | collect({name: @"h2", price: @".price"}))`, 3},
+		{`(extract(page, @".product") | collect({name: @"h2", price: @".price"}))[1].price`, nil},
+		{`(extract(page, @".product") | collect({name: @"h2", price: @".price"}))[2].price.text`, "30"},
+		{`(extract(page, @".product") | collect({name: @"h2", price: @".price"}))[2].name.text`, "Three"},
+		{`let card = extract(page, @".product")[2]; collect(card, @"h2", @"span")[0]["span"].text`, "30"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalWithRuntime(t, "let page = '"+html+"';\n"+tt.input, "")
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			testStringObject(t, evaluated, expected)
+		case nil:
+			if evaluated != NULL {
+				t.Errorf("input %q: expected NULL. got=%T (%+v)", tt.input, evaluated, evaluated)
+			}
+		}
+	}
+
+	evaluated := testEvalWithRuntime(t, `extract([1], @"a")`, "")
+	if errObj, ok := evaluated.(*Error); !ok || errObj.Kind != errors.TypeError {
+		t.Errorf("expected TypeError for non-element array source. got=%T (%+v)", evaluated, evaluated)
+	}
+}
+
+func TestTableBuiltin(t *testing.T) {
+	html := `<div><table><thead><tr><th>Name</th><th>Price</th></tr></thead>` +
+		`<tbody><tr><td>Apple</td><td rowspan="2">1</td></tr></tbody>` +
+		`<tbody><tr><td>Pear</td></tr></tbody></table></div>`
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`len(table(page))`, 2},
+		{`table(page)[0]["Name"]`, "Apple"},
+		{`table(page)[1]["Name"]`, "Pear"},
+		{`table(page)[1]["Price"]`, ""}, // rowspan 不跨出所在的 tbody
+		{`table(extract(page, @"table")[0])[0]["Price"]`, "1"},
+		{`len(keys(table(extract(page, @"div")[0])[0]))`, 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalWithRuntime(t, "let page = '"+html+"';\n"+tt.input, "")
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			testStringObject(t, evaluated, expected)
+		}
+	}
+
+	errorTests := []struct {
+		input string
+		kind  errors.ErrorType
+	}{
+		{`table("<p>no table</p>")`, errors.SelectorError},
+		{`table(1)`, errors.TypeError},
+		{`table()`, errors.TypeError},
+	}
+	for _, tt := range errorTests {
+		evaluated := testEvalWithRuntime(t, tt.input, "")
+		errObj, ok := evaluated.(*Error)
+		if !ok {
+			t.Errorf("input %q: expected error. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Kind != tt.kind {
+			t.Errorf("input %q: wrong error kind. want=%d, got=%d", tt.input, tt.kind, errObj.Kind)
+		}
+	}
+}
+
+func TestMetadataBuiltin(t *testing.T) {
+	html := `<head><meta property="og:title" content="Widget"><meta name="author" content="Ann">` +
+		`<script type="application/ld+json">{"@graph": [{"@type": "Product", "offers": {"price": 9.5, "stock": 3}}]}</script>` +
+		`</head><body><div itemscope itemtype="Book"><span itemprop="name">Go</span></div></body>`
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`metadata(page).opengraph.title`, "Widget"},
+		{`metadata(page).meta.author`, "Ann"},
+		{`metadata(page).jsonld[0]["@type"]`, "Product"},
+		{`metadata(page).jsonld[0].offers.stock`, 3},
+		{`metadata(page).jsonld[0].offers.price`, 9.5},
+		{`metadata(page).microdata[0].name`, "Go"},
+		{`metadata(page).microdata[0]["@type"]`, "Book"},
+		{`len(metadata("<p>plain</p>").jsonld)`, 0},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalWithRuntime(t, "let page = '"+html+"';\n"+tt.input, "")
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case float64:
+			f, ok := evaluated.(*Float)
+			if !ok || f.Value != expected {
+				t.Errorf("input %q: expected %g. got=%T (%+v)", tt.input, expected, evaluated, evaluated)
+			}
+		case string:
+			testStringObject(t, evaluated, expected)
+		}
+	}
+
+	evaluated := testEvalWithRuntime(t, `metadata(1)`, "")
+	if errObj, ok := evaluated.(*Error); !ok || errObj.Kind != errors.TypeError {
+		t.Errorf("expected TypeError. got=%T (%+v)", evaluated, evaluated)
+	}
+}
+
+func TestArticleBuiltin(t *testing.T) {
+	html := `<head><title>A quiet day in the harbour | Gazette</title></head><body>` +
+		`<div class="menu"><a href="/">Home</a></div>` +
+		`<div class="story"><p class="byline">By Kim Park</p>` +
+		`<p>Boats stayed in the harbour on Sunday, as strong winds kept the fishing fleet ashore.</p>` +
+		`<p>Harbour staff said the weather should improve, and boats could leave again on Tuesday.</p></div></body>`
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`article(page).title`, "A quiet day in the harbour"},
+		{`article(page).byline`, "By Kim Park"},
+		{`article(page).published`, nil},
+		{`contains(article(page).text, "strong winds")`, true},
+		{`contains(article(page).text, "Home")`, false},
+		{`contains(article(page).content_html, "<p>Boats")`, true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalWithRuntime(t, "let page = '"+html+"';\n"+tt.input, "")
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			testStringObject(t, evaluated, expected)
+		case nil:
+			if evaluated != NULL {
+				t.Errorf("input %q: expected NULL. got=%T (%+v)", tt.input, evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestMarkdownBuiltin(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`markdown(extract(open(url + "/list"), @"li")[1])`, "[B](" + server.URL + "/b)2"},
+		{`markdown(open(url + "/list"))`,
+			"- [A](" + server.URL + "/a)1\n- [B](" + server.URL + "/b)2\n- [C](" + server.URL + "/c)"},
+		{`markdown("<h2>Hi</h2><p><a href=\"/x\">x</a></p>")`, "## Hi\n\n[x](/x)"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalWithRuntime(t, tt.input, server.URL)
+		testStringObject(t, evaluated, tt.expected)
+	}
+
+	evaluated := testEvalWithRuntime(t, `markdown([])`, "")
+	if errObj, ok := evaluated.(*Error); !ok || errObj.Kind != errors.TypeError {
+		t.Errorf("expected TypeError. got=%T (%+v)", evaluated, evaluated)
+	}
+}
+
+func TestLinksBuiltin(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+
+	page := `<head><base href="http://Example.com:80/docs/"></head><body>` +
+		`<a href="intro#top">Intro</a><div class="next"><a href="../p/2?b=1&a=2">Next</a></div>` +
+		`<a href="mailto:me@example.com">Mail</a></body>`
+
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`links(open(url + "/list"))`, []string{server.URL + "/a", server.URL + "/b", server.URL + "/c"}},
+		{`links(open(url + "/list"), @"li:nth-child(2)")`, []string{server.URL + "/b"}},
+		{`links(extract(open(url + "/list"), @"li")[2])`, []string{server.URL + "/c"}},
+		{`links(page)`, []string{"http://example.com/docs/intro", "http://example.com/p/2?b=1&a=2"}},
+		{`links(page, @".next a", {sort_query: true})`, []string{"http://example.com/p/2?a=2&b=1"}},
+		{`links(page, {schemes: ["mailto"]})`, []string{"mailto:me@example.com"}},
+		{`links("<a href=\"/relative\">x</a><a href=\"https://a.org\">y</a>")`, []string{"https://a.org/"}},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalWithRuntime(t, "let page = '"+page+"';\n"+tt.input, server.URL)
+		arr, ok := evaluated.(*Array)
+		if !ok {
+			t.Errorf("input %q: expected ARRAY. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if len(arr.Elements) != len(tt.expected) {
+			t.Errorf("input %q: expected %d links. got=%s", tt.input, len(tt.expected), arr.Inspect())
+			continue
+		}
+		for i, want := range tt.expected {
+			testStringObject(t, arr.Elements[i], want)
+		}
+	}
+
+	errorTests := []struct {
+		input string
+		kind  errors.ErrorType
+	}{
+		{`links("<a></a>", @"a[")`, errors.SelectorError},
+		{`links("<a></a>", {depth: 1})`, errors.TypeError},
+		{`links("<a></a>", {schemes: "http"})`, errors.TypeError},
+		{`links("<a></a>", {}, @"a")`, errors.TypeError},
+		{`links(1)`, errors.TypeError},
+	}
+	for _, tt := range errorTests {
+		evaluated := testEvalWithRuntime(t, tt.input, "")
+		errObj, ok := evaluated.(*Error)
+		if !ok {
+			t.Errorf("input %q: expected error. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Kind != tt.kind {
+			t.Errorf("input %q: wrong error kind. want=%d, got=%d", tt.input, tt.kind, errObj.Kind)
+		}
+	}
+}
+
+// newCrawlServer 创建带分页和站外链接的测试站点
+func newCrawlServer() *httptest.Server {
+	pages := map[string]string{
+		"/":       `<a class="next" href="/page/2">next</a><a href="/about">about</a><a href="https://elsewhere.example/">out</a>`,
+		"/page/2": `<a class="next" href="/page/3#top">next</a><a href="/">home</a>`,
+		"/page/3": `<p>last</p><a href="/missing">broken</a>`,
+		"/about":  `<h1>About</h1>`,
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("<html><body>" + body + "</body></html>"))
+	}))
+}
+
+func TestCrawl(t *testing.T) {
+	server := newCrawlServer()
+	defer server.Close()
+
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`crawl(url, function(page, depth) { page.url + "@" + str(depth) })`,
+			[]string{"/@0", "/page/2@1", "/about@1", "/page/3@2", "/missing@3"}},
+		{`crawl(url, {depth: 1}, function(page) { page.url })`,
+			[]string{"/", "/page/2", "/about"}},
+		{`crawl([url], {follow: @"a.next"}, function(page) { page.url })`,
+			[]string{"/", "/page/2", "/page/3"}},
+		{`crawl(url, {strategy: "dfs", depth: 2}, function(page) { page.url })`,
+			[]string{"/", "/page/2", "/page/3", "/about"}},
+		{`crawl(url, {priority: function(u, d) { contains(u, "about") ? 10 : 0 }}, function(page) { page.url })`,
+			[]string{"/", "/about", "/page/2", "/page/3", "/missing"}},
+		{`crawl(url, {deny: "page/3"}, function(page) { page.url })`,
+			[]string{"/", "/page/2", "/about"}},
+		{`crawl(url, {allow: ["page"], limit: 2}, function(page) { page.url })`,
+			[]string{"/", "/page/2"}},
+		{`crawl(url, {depth: 1}, function(page) { if (page.status == 200) { extract(page, @"a::text") } })[2]`,
+			nil},
+		{`url | crawl({follow: @"a.next"}, function(page) { page.url })`,
+			[]string{"/", "/page/2", "/page/3"}},
+		{`let opts = {follow: @"a.next"}; url | crawl(opts, function(page) { page.url })`,
+			[]string{"/", "/page/2", "/page/3"}},
+		{`let seeds = [url]; crawl(seeds, function(page) { page.url })`,
+			[]string{"/", "/page/2", "/about", "/page/3", "/missing"}},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalWithRuntime(t, tt.input, server.URL+"/")
+		arr, ok := evaluated.(*Array)
+		if !ok {
+			t.Errorf("input %q: expected ARRAY. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if tt.expected == nil {
+			continue
+		}
+		if len(arr.Elements) != len(tt.expected) {
+			t.Errorf("input %q: expected %d pages. got=%s", tt.input, len(tt.expected), arr.Inspect())
+			continue
+		}
+		for i, want := range tt.expected {
+			testStringObject(t, arr.Elements[i], server.URL+want)
+		}
+	}
+}
+
+func TestCrawlFollowsOnlyHTML(t *testing.T) {
+	pages := map[string]struct{ contentType, body string }{
+		"/":           {"text/html; charset=utf-8", `<a href="/feed.json">feed</a><a href="/page.xhtml">x</a><a href="/raw">raw</a>`},
+		"/feed.json":  {"application/json", `{"html": "<a href='/from-json'>x</a>"}`},
+		"/page.xhtml": {"Application/XHTML+XML", `<html><body><a href="/from-xhtml">x</a></body></html>`},
+		"/raw":        {"", `<a href="/from-raw">x</a>`},
+		"/from-xhtml": {"text/html", `done`},
+		"/from-raw":   {"text/html", `done`},
+		"/from-json":  {"text/html", `done`},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if page.contentType == "" {
+			w.Header()["Content-Type"] = nil // 不让服务器按内容推断类型
+		} else {
+			w.Header().Set("Content-Type", page.contentType)
+		}
+		w.Write([]byte(page.body))
+	}))
+	defer server.Close()
+
+	evaluated := testEvalWithRuntime(t, `crawl(url, function(page) { page.url })`, server.URL+"/")
+	arr, ok := evaluated.(*Array)
+	if !ok {
+		t.Fatalf("expected ARRAY. got=%T (%+v)", evaluated, evaluated)
+	}
+	want := []string{"/", "/feed.json", "/page.xhtml", "/raw", "/from-xhtml", "/from-raw"}
+	if len(arr.Elements) != len(want) {
+		t.Fatalf("expected %d pages. got=%s", len(want), arr.Inspect())
+	}
+	for i, path := range want {
+		testStringObject(t, arr.Elements[i], server.URL+path)
+	}
+}
+
+func TestCrawlErrors(t *testing.T) {
+	server := newCrawlServer()
+	defer server.Close()
+
+	tests := []struct {
+		input string
+		kind  errors.ErrorType
+	}{
+		{`crawl(1, function(p) { p })`, errors.TypeError},
+		{`crawl([1], function(p) { p })`, errors.TypeError},
+		{`crawl(url, 1)`, errors.TypeError},
+		{`crawl(url, {depth: "x"}, function(p) { p })`, errors.TypeError},
+		{`crawl(url, {bogus: 1}, function(p) { p })`, errors.TypeError},
+		{`crawl(url, {strategy: "random"}, function(p) { p })`, errors.TypeError},
+		{`crawl(url, {follow: @"a["}, function(p) { p })`, errors.SelectorError},
+		{`crawl(url, {allow: "("}, function(p) { p })`, errors.RuntimeError},
+		{`crawl("not a url", function(p) { p })`, errors.RuntimeError},
+		{`crawl(url, function(p) { len(1) })`, errors.TypeError},
+		{`crawl(url, {priority: function(u) { undefinedName }}, function(p) { p })`, errors.ReferenceError},
+		{`let opts = {depth: 1}; crawl(opts, function(p) { p })`, errors.RuntimeError},
+		{`let seeds = [url]; url | crawl(seeds, function(p) { p })`, errors.TypeError},
+	}
+	for _, tt := range tests {
+		evaluated := testEvalWithRuntime(t, tt.input, server.URL)
+		errObj, ok := evaluated.(*Error)
+		if !ok {
+			t.Errorf("input %q: expected error. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Kind != tt.kind {
+			t.Errorf("input %q: wrong error kind. want=%d, got=%d (%s)", tt.input, tt.kind, errObj.Kind, errObj.Message)
+		}
+	}
+}
+
+func TestCrawlResume(t *testing.T) {
+	server := newCrawlServer()
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "state.jsonl")
+	input := `crawl(url, {limit: 2}, function(page) { page.url })`
+
+	// 每次运行相当于重新启动脚本，已完成的页面不再抓取
+	runs := [][]string{
+		{"/", "/page/2"},
+		{"/about", "/page/3"},
+		{"/missing"},
+		{},
+	}
+	for i, want := range runs {
+		cp, err := frontier.OpenCheckpoint(path, 0)
+		if err != nil {
+			t.Fatalf("run %d: OpenCheckpoint: %v", i, err)
+		}
+
+		p := parser.New(lexer.New(input))
+		program := p.ParseProgram()
+		options := fetch.DefaultOptions()
+		options.MaxRetries = 0
+		rt := NewRuntime(context.Background(), fetch.NewFetcher(options))
+		rt.Checkpoint = cp
+		env := NewEnvironment()
+		env.SetRuntime(rt)
+		env.Set("url", &String{Value: server.URL + "/"})
+
+		evaluated := Eval(program, env)
+		if err := cp.Close(); err != nil {
+			t.Fatalf("run %d: Close: %v", i, err)
+		}
+		arr, ok := evaluated.(*Array)
+		if !ok {
+			t.Fatalf("run %d: expected ARRAY. got=%T (%+v)", i, evaluated, evaluated)
+		}
+		if len(arr.Elements) != len(want) {
+			t.Fatalf("run %d: expected %d pages. got=%s", i, len(want), arr.Inspect())
+		}
+		for j, w := range want {
+			testStringObject(t, arr.Elements[j], server.URL+w)
+		}
+	}
+}
+
+func TestCookieBuiltins(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: "secret", Path: "/"})
+	})
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		c, err := r.Cookie("lang")
+		if err == nil {
+			w.Write([]byte(c.Value))
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "cookies.json")
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`open(url + "/login"); cookies(url)["sid"]`, "secret"},
+		{`set_cookie(url, "lang", "zh"); open(url + "/echo").body`, "zh"},
+		{`set_cookie(url, "lang", "zh", {path: "/other"}); open(url + "/echo").body`, ""},
+		{`set_cookie(url, "lang", "zh"); set_cookie(url, "lang", "", {max_age: 0}); len(cookies(url)) | str`, "0"},
+		{`session()`, "default"},
+		{`open(url + "/login"); let prev = session("guest"); prev + ":" + session() + ":" + str(len(cookies(url)))`, "default:guest:0"},
+		{`session("member"); open(url + "/login"); session("default"); str(len(cookies(url))) + (session("member") | str) + cookies(url)["sid"]`, "0defaultsecret"},
+		{fmt.Sprintf(`open(url + "/login"); save_cookies(%q); "saved"`, path), "saved"},
+		{fmt.Sprintf(`load_cookies(%q); cookies(url)["sid"]`, path), "secret"},
+	}
+	for _, tt := range tests {
+		testStringObject(t, testEvalWithRuntime(t, tt.input, server.URL), tt.expected)
+	}
+
+	errorTests := []struct {
+		input string
+		kind  errors.ErrorType
+	}{
+		{`cookies("not a url")`, errors.RuntimeError},
+		{`cookies(1)`, errors.TypeError},
+		{`set_cookie(url, "a")`, errors.TypeError},
+		{`set_cookie(url, "a", "b", {bogus: 1})`, errors.TypeError},
+		{`session("")`, errors.RuntimeError},
+		{fmt.Sprintf(`load_cookies(%q)`, filepath.Join(t.TempDir(), "missing.json")), errors.RuntimeError},
+	}
+	for _, tt := range errorTests {
+		evaluated := testEvalWithRuntime(t, tt.input, server.URL)
+		errObj, ok := evaluated.(*Error)
+		if !ok {
+			t.Errorf("input %q: expected error. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Kind != tt.kind {
+			t.Errorf("input %q: wrong error kind. want=%d, got=%d (%s)", tt.input, tt.kind, errObj.Kind, errObj.Message)
+		}
+	}
+}
+
+// newFormServer 返回带登录表单和搜索表单的测试服务器，/echo 回显请求
+func newFormServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s %s %s", r.Method, r.URL.RequestURI(), r.Header.Get("X-Test"), body)
+	})
+	mux.HandleFunc("/forms", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body>
+<form id="login" action="/login" method="post">
+<input type="hidden" name="csrf" value="tok123">
+<input name="user" value="guest"><input type="password" name="pass">
+<input type="submit" value="Sign in">
+</form>
+<form id="search" action="/echo?old=1"><input name="q" value="default"><input name="page" value="1"></form>
+<form id="upload" action="/upload" method="post" enctype="multipart/form-data"><input type="hidden" name="csrf" value="tok123"></form>
+</body></html>`))
+	})
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.FormValue("csrf") != "tok123" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, "welcome %s/%s", r.FormValue("user"), r.FormValue("pass"))
+	})
+	mux.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {
+		file, header, err := r.FormFile("doc")
+		if err != nil || r.FormValue("csrf") != "tok123" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(file)
+		fmt.Fprintf(w, "%s:%s", header.Filename, data)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestOpenWithOptions(t *testing.T) {
+	server := newFormServer()
+	defer server.Close()
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`open(url + "/echo").body`, "GET /echo  "},
+		{`open(url + "/echo", {method: "POST", form: {a: "1", b: [2, true]}}).body`, "POST /echo  a=1&b=2&b=true"},
+		{`open(url + "/echo", {form: {a: "1"}}).body`, "POST /echo  a=1"},
+		{`open(url + "/echo", {json: [1]}).body`, "POST /echo  [1]"},
+		{`open(url + "/echo", {query: {a: "1"}}).body`, "GET /echo?a=1  "},
+		{`open(url + "/echo", {method: "put", json: {x: [1, 2.5, null]}}).body`, `PUT /echo  {"x":[1,2.5,null]}`},
+		{`open(url + "/echo", {method: "POST", body: "raw", headers: {"X-Test": "yes"}}).body`, "POST /echo yes raw"},
+		{`(url + "/echo?a=1") | open({query: {q: "a b"}}) | (r) => r.body`, "GET /echo?a=1&q=a+b  "},
+		{`let opts = {method: "DELETE"}; (url + "/echo") | open(opts) | (r) => r.body`, "DELETE /echo  "},
+		{`let u = url + "/echo"; open(u).body`, "GET /echo  "},
+	}
+	for _, tt := range tests {
+		testStringObject(t, testEvalWithRuntime(t, tt.input, server.URL), tt.expected)
+	}
+
+	errorTests := []struct {
+		input string
+		kind  errors.ErrorType
+	}{
+		{`open(url, 1)`, errors.TypeError},
+		{`open(url, {bogus: 1})`, errors.TypeError},
+		{`open(url, {method: 1})`, errors.TypeError},
+		{`open(url, {form: {a: {}}})`, errors.TypeError},
+		{`open(url, {form: {a: "1"}, json: {}})`, errors.TypeError},
+		{`open(url, {json: [len]})`, errors.TypeError},
+		{`let opts = {method: "POST"}; open(opts)`, errors.RuntimeError},
+		{`let u = url; url | open(u)`, errors.TypeError},
+	}
+	for _, tt := range errorTests {
+		evaluated := testEvalWithRuntime(t, tt.input, server.URL)
+		errObj, ok := evaluated.(*Error)
+		if !ok {
+			t.Errorf("input %q: expected error. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Kind != tt.kind {
+			t.Errorf("input %q: wrong error kind. want=%d, got=%d (%s)", tt.input, tt.kind, errObj.Kind, errObj.Message)
+		}
+		if errObj.Line == 0 {
+			t.Errorf("input %q: error has no position", tt.input)
+		}
+	}
+}
+
+func TestSubmitBuiltin(t *testing.T) {
+	server := newFormServer()
+	defer server.Close()
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`submit(open(url + "/forms"), {user: "bob", pass: "pw"}).body`, "welcome bob/pw"},
+		{`submit(open(url + "/forms")).body`, "welcome guest/"},
+		{`let page = open(url + "/forms"); submit(extract(page, @"#search")[0], {q: "go"}).body`, "GET /echo?page=1&q=go  "},
+		{`let page = open(url + "/forms"); submit(extract(page, @"#search input")[1], {page: null, q: ["a", "b"]}).body`, "GET /echo?q=a&q=b  "},
+		{`let page = open(url + "/forms"); submit(extract(page, @"#upload")[0], {doc: {filename: "a.txt", content: "hi"}}).body`, "a.txt:hi"},
+	}
+	for _, tt := range tests {
+		testStringObject(t, testEvalWithRuntime(t, tt.input, server.URL), tt.expected)
+	}
+
+	errorTests := []struct {
+		input string
+		kind  errors.ErrorType
+	}{
+		{`submit("<p>no form</p>")`, errors.SelectorError},
+		{`submit("<form action='/x'></form>")`, errors.RuntimeError},
+		{`submit(open(url + "/forms"), 1)`, errors.TypeError},
+		{`submit(open(url + "/forms"), {doc: {filename: "a"}})`, errors.RuntimeError},
+		{`submit(1)`, errors.TypeError},
+	}
+	for _, tt := range errorTests {
+		evaluated := testEvalWithRuntime(t, tt.input, server.URL)
+		errObj, ok := evaluated.(*Error)
+		if !ok {
+			t.Errorf("input %q: expected error. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Kind != tt.kind {
+			t.Errorf("input %q: wrong error kind. want=%d, got=%d (%s)", tt.input, tt.kind, errObj.Kind, errObj.Message)
+		}
+	}
+}
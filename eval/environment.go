@@ -2,14 +2,16 @@ package eval
 
 // Environment 表示执行环境
 type Environment struct {
-    store map[string]Object
-    outer *Environment
+    store   map[string]Object
+    consts  map[string]bool
+    outer   *Environment
+    runtime *Runtime
 }
 
 // NewEnvironment 创建新的环境
 func NewEnvironment() *Environment {
     s := make(map[string]Object)
-    return &Environment{store: s, outer: nil}
+    return &Environment{store: s, consts: make(map[string]bool), outer: nil}
 }
 
 // NewEnclosedEnvironment 创建嵌套环境
@@ -34,6 +36,57 @@ func (e *Environment) Set(name string, val Object) Object {
     return val
 }
 
+// SetConst 在当前作用域声明常量
+func (e *Environment) SetConst(name string, val Object) Object {
+    e.store[name] = val
+    e.consts[name] = true
+    return val
+}
+
+// IsConst 判断变量是否为常量
+func (e *Environment) IsConst(name string) bool {
+    if scope := e.scopeOf(name); scope != nil {
+        return scope.consts[name]
+    }
+    return false
+}
+
+// Assign 为已声明的变量重新赋值，赋值发生在声明该变量的作用域中
+// 变量未声明时返回 false
+func (e *Environment) Assign(name string, val Object) bool {
+    scope := e.scopeOf(name)
+    if scope == nil {
+        return false
+    }
+    scope.store[name] = val
+    return true
+}
+
+// scopeOf 返回声明了指定变量的作用域
+func (e *Environment) scopeOf(name string) *Environment {
+    for env := e; env != nil; env = env.outer {
+        if _, ok := env.store[name]; ok {
+            return env
+        }
+    }
+    return nil
+}
+
+// SetRuntime 设置当前环境及其嵌套环境使用的运行时
+func (e *Environment) SetRuntime(rt *Runtime) {
+    e.runtime = rt
+}
+
+// Runtime 返回环境使用的运行时，沿作用域链查找，未设置时返回默认运行时
+func (e *Environment) Runtime() *Runtime {
+    for env := e; env != nil; env = env.outer {
+        if env.runtime != nil {
+            return env.runtime
+        }
+    }
+    return DefaultRuntime()
+}
+
 // GetAll 获取所有变量
 func (e *Environment) GetAll() map[string]Object {
     return e.store
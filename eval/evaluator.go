@@ -0,0 +1,925 @@
+package eval
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/btrobot/mydsl/ast"
+	"github.com/btrobot/mydsl/errors"
+)
+
+// 单例对象
+var (
+	NULL  = &Null{}
+	TRUE  = &Boolean{Value: true}
+	FALSE = &Boolean{Value: false}
+)
+
+// LOOP_SIGNAL_OBJ 是 break/continue 控制流信号的对象类型
+const LOOP_SIGNAL_OBJ = "LOOP_SIGNAL"
+
+// loopSignal 表示 break/continue 控制流信号，只在循环体内部传递
+type loopSignal struct {
+	isBreak bool
+	node    ast.Node
+}
+
+func (ls *loopSignal) Type() ObjectType { return LOOP_SIGNAL_OBJ }
+func (ls *loopSignal) Inspect() string {
+	if ls.isBreak {
+		return "break"
+	}
+	return "continue"
+}
+
+// Eval 对 AST 节点求值
+func Eval(node ast.Node, env *Environment) Object {
+	switch node := node.(type) {
+
+	// 语句
+	case *ast.Program:
+		return evalProgram(node, env)
+	case *ast.ExpressionStatement:
+		return Eval(node.Expression, env)
+	case *ast.BlockStatement:
+		return evalBlockStatement(node, env)
+	case *ast.LetStatement:
+		return evalLetStatement(node, env)
+	case *ast.ReturnStatement:
+		if node.ReturnValue == nil {
+			return &ReturnValue{Value: NULL}
+		}
+		val := Eval(node.ReturnValue, env)
+		if isError(val) {
+			return val
+		}
+		return &ReturnValue{Value: val}
+	case *ast.BreakStatement:
+		return &loopSignal{isBreak: true, node: node}
+	case *ast.ContinueStatement:
+		return &loopSignal{isBreak: false, node: node}
+
+	// 字面量
+	case *ast.IntegerLiteral:
+		return &Integer{Value: node.Value}
+	case *ast.FloatLiteral:
+		return &Float{Value: node.Value}
+	case *ast.StringLiteral:
+		return &String{Value: node.Value}
+	case *ast.BooleanLiteral:
+		return nativeBoolToBooleanObject(node.Value)
+	case *ast.NullLiteral:
+		return NULL
+	case *ast.ArrayLiteral:
+		elements := evalExpressions(node.Elements, env)
+		if len(elements) == 1 && isError(elements[0]) {
+			return elements[0]
+		}
+		return &Array{Elements: elements}
+	case *ast.ObjectLiteral:
+		return evalObjectLiteral(node, env)
+	case *ast.FunctionLiteral:
+		fn := &Function{Name: node.Name, Parameters: node.Parameters, Body: node.Body, Env: env}
+		if node.Name != "" {
+			env.Set(node.Name, fn)
+		}
+		return fn
+
+	// 表达式
+	case *ast.Identifier:
+		return evalIdentifier(node, env)
+	case *ast.PrefixExpression:
+		right := Eval(node.Right, env)
+		if isError(right) {
+			return right
+		}
+		return evalPrefixExpression(node, right)
+	case *ast.InfixExpression:
+		return evalInfixExpression(node, env)
+	case *ast.AssignExpression:
+		return evalAssignExpression(node, env)
+	case *ast.ConditionalExpression:
+		condition := Eval(node.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if isTruthy(condition) {
+			return Eval(node.Consequence, env)
+		}
+		return Eval(node.Alternative, env)
+	case *ast.IfExpression:
+		return evalIfExpression(node, env)
+	case *ast.WhileExpression:
+		return evalWhileExpression(node, env)
+	case *ast.ForExpression:
+		return evalForExpression(node, env)
+	case *ast.CallExpression:
+		function := Eval(node.Function, env)
+		if isError(function) {
+			return function
+		}
+		args := evalExpressions(node.Arguments, env)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+		return applyFunction(node, function, args)
+	case *ast.IndexExpression:
+		left := Eval(node.Left, env)
+		if isError(left) {
+			return left
+		}
+		index := Eval(node.Index, env)
+		if isError(index) {
+			return index
+		}
+		return evalIndexExpression(node, left, index)
+	case *ast.MemberExpression:
+		object := Eval(node.Object, env)
+		if isError(object) {
+			return object
+		}
+		return evalMemberExpression(node, object)
+
+	// 爬虫相关表达式
+	case *ast.AtExpression:
+		return evalAtExpression(node, env)
+	case *ast.PipeExpression:
+		input := Eval(node.Left, env)
+		if isError(input) {
+			return input
+		}
+		return evalPipeStage(node.Right, input, env)
+	case *ast.OpenExpression:
+		return evalOpenExpression(node, nil, env)
+	case *ast.ExtractExpression:
+		return evalExtractExpression(node, nil, env)
+	case *ast.CollectExpression:
+		return evalCollectExpression(node, nil, env)
+	case *ast.CrawlExpression:
+		return evalCrawlExpression(node, nil, env)
+	}
+
+	if node == nil {
+		return NULL
+	}
+	return newError(node, "unknown node type %T", node)
+}
+
+// evalProgram 依次执行程序中的语句，遇到 return 或错误时提前结束
+func evalProgram(program *ast.Program, env *Environment) Object {
+	var result Object = NULL
+
+	for _, statement := range program.Statements {
+		result = Eval(statement, env)
+
+		switch result := result.(type) {
+		case *ReturnValue:
+			return result.Value
+		case *Error:
+			return result
+		case *loopSignal:
+			return newError(result.node, "%s outside loop", result.Inspect())
+		}
+	}
+
+	return result
+}
+
+// evalBlockStatement 执行代码块，return、错误和循环控制信号会向外传递
+func evalBlockStatement(block *ast.BlockStatement, env *Environment) Object {
+	var result Object = NULL
+
+	for _, statement := range block.Statements {
+		result = Eval(statement, env)
+
+		switch result.Type() {
+		case RETURN_VALUE_OBJ, ERROR_OBJ, LOOP_SIGNAL_OBJ:
+			return result
+		}
+	}
+
+	return result
+}
+
+// evalLetStatement 在当前作用域声明变量或常量
+func evalLetStatement(node *ast.LetStatement, env *Environment) Object {
+	if _, ok := env.store[node.Name.Value]; ok && env.consts[node.Name.Value] {
+		return newTypeError(node.Name, "cannot redeclare constant %s", node.Name.Value)
+	}
+
+	val := Eval(node.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	if node.Token.Literal == "const" {
+		env.SetConst(node.Name.Value, val)
+	} else {
+		env.Set(node.Name.Value, val)
+	}
+
+	return NULL
+}
+
+func evalExpressions(exps []ast.Expression, env *Environment) []Object {
+	result := make([]Object, 0, len(exps))
+
+	for _, e := range exps {
+		evaluated := Eval(e, env)
+		if isError(evaluated) {
+			return []Object{evaluated}
+		}
+		result = append(result, evaluated)
+	}
+
+	return result
+}
+
+// evalObjectLiteral 将对象字面量转换为哈希对象
+func evalObjectLiteral(node *ast.ObjectLiteral, env *Environment) Object {
+	pairs := make(map[HashKey]HashPair)
+
+	for _, keyNode := range node.Keys {
+		valueNode := node.Pairs[keyNode]
+		key := Eval(keyNode, env)
+		if isError(key) {
+			return key
+		}
+
+		hashKey, ok := key.(Hashable)
+		if !ok {
+			return newTypeError(keyNode, "unusable as hash key: %s", key.Type())
+		}
+
+		value := Eval(valueNode, env)
+		if isError(value) {
+			return value
+		}
+
+		pairs[hashKey.HashKey()] = HashPair{Key: key, Value: value}
+	}
+
+	return &Hash{Pairs: pairs}
+}
+
+// evalIdentifier 先在环境中查找变量，再查找内置函数
+func evalIdentifier(node *ast.Identifier, env *Environment) Object {
+	if val, ok := env.Get(node.Value); ok {
+		return val
+	}
+
+	if builtin, ok := builtins[node.Value]; ok {
+		return builtin
+	}
+
+	if fn, ok := runtimeBuiltins[node.Value]; ok {
+		return bindRuntime(node.Value, fn, env.Runtime())
+	}
+
+	return newReferenceError(node, "identifier not found: %s", node.Value)
+}
+
+func evalPrefixExpression(node *ast.PrefixExpression, right Object) Object {
+	switch node.Operator {
+	case "!":
+		return nativeBoolToBooleanObject(!isTruthy(right))
+	case "-":
+		switch right := right.(type) {
+		case *Integer:
+			return &Integer{Value: -right.Value}
+		case *Float:
+			return &Float{Value: -right.Value}
+		}
+		return newTypeError(node, "unknown operator: -%s", right.Type())
+	}
+	return newError(node, "unknown operator: %s%s", node.Operator, right.Type())
+}
+
+// evalInfixExpression 对中缀表达式求值，&&、|| 和 ?? 采用短路求值
+func evalInfixExpression(node *ast.InfixExpression, env *Environment) Object {
+	left := Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	switch node.Operator {
+	case "&&":
+		if !isTruthy(left) {
+			return FALSE
+		}
+		right := Eval(node.Right, env)
+		if isError(right) {
+			return right
+		}
+		return nativeBoolToBooleanObject(isTruthy(right))
+	case "||":
+		if isTruthy(left) {
+			return TRUE
+		}
+		right := Eval(node.Right, env)
+		if isError(right) {
+			return right
+		}
+		return nativeBoolToBooleanObject(isTruthy(right))
+	case "??":
+		if left.Type() != NULL_OBJ {
+			return left
+		}
+		return Eval(node.Right, env)
+	}
+
+	right := Eval(node.Right, env)
+	if isError(right) {
+		return right
+	}
+
+	return evalBinaryOperation(node, node.Operator, left, right)
+}
+
+// evalBinaryOperation 计算二元运算，整数与浮点数混合运算时提升为浮点数
+func evalBinaryOperation(node ast.Node, operator string, left, right Object) Object {
+	switch {
+	case left.Type() == INTEGER_OBJ && right.Type() == INTEGER_OBJ:
+		return evalIntegerInfixExpression(node, operator, left.(*Integer).Value, right.(*Integer).Value)
+	case isNumber(left) && isNumber(right):
+		return evalFloatInfixExpression(node, operator, toFloat(left), toFloat(right))
+	case left.Type() == STRING_OBJ && right.Type() == STRING_OBJ:
+		return evalStringInfixExpression(node, operator, left.(*String).Value, right.(*String).Value)
+	case operator == "+" && (left.Type() == STRING_OBJ || right.Type() == STRING_OBJ):
+		return &String{Value: left.Inspect() + right.Inspect()}
+	case operator == "+" && left.Type() == ARRAY_OBJ && right.Type() == ARRAY_OBJ:
+		elements := make([]Object, 0, len(left.(*Array).Elements)+len(right.(*Array).Elements))
+		elements = append(elements, left.(*Array).Elements...)
+		elements = append(elements, right.(*Array).Elements...)
+		return &Array{Elements: elements}
+	case operator == "==":
+		return nativeBoolToBooleanObject(objectsEqual(left, right))
+	case operator == "!=":
+		return nativeBoolToBooleanObject(!objectsEqual(left, right))
+	}
+
+	return newTypeError(node, "unsupported operand types: %s %s %s", left.Type(), operator, right.Type())
+}
+
+func evalIntegerInfixExpression(node ast.Node, operator string, left, right int64) Object {
+	switch operator {
+	case "+":
+		return &Integer{Value: left + right}
+	case "-":
+		return &Integer{Value: left - right}
+	case "*":
+		return &Integer{Value: left * right}
+	case "/":
+		if right == 0 {
+			return newError(node, "division by zero")
+		}
+		return &Integer{Value: left / right}
+	case "%":
+		if right == 0 {
+			return newError(node, "division by zero")
+		}
+		return &Integer{Value: left % right}
+	case "<":
+		return nativeBoolToBooleanObject(left < right)
+	case ">":
+		return nativeBoolToBooleanObject(left > right)
+	case "<=":
+		return nativeBoolToBooleanObject(left <= right)
+	case ">=":
+		return nativeBoolToBooleanObject(left >= right)
+	case "==":
+		return nativeBoolToBooleanObject(left == right)
+	case "!=":
+		return nativeBoolToBooleanObject(left != right)
+	}
+	return newTypeError(node, "unknown operator: INTEGER %s INTEGER", operator)
+}
+
+func evalFloatInfixExpression(node ast.Node, operator string, left, right float64) Object {
+	switch operator {
+	case "+":
+		return &Float{Value: left + right}
+	case "-":
+		return &Float{Value: left - right}
+	case "*":
+		return &Float{Value: left * right}
+	case "/":
+		if right == 0 {
+			return newError(node, "division by zero")
+		}
+		return &Float{Value: left / right}
+	case "%":
+		if right == 0 {
+			return newError(node, "division by zero")
+		}
+		return &Float{Value: math.Mod(left, right)}
+	case "<":
+		return nativeBoolToBooleanObject(left < right)
+	case ">":
+		return nativeBoolToBooleanObject(left > right)
+	case "<=":
+		return nativeBoolToBooleanObject(left <= right)
+	case ">=":
+		return nativeBoolToBooleanObject(left >= right)
+	case "==":
+		return nativeBoolToBooleanObject(left == right)
+	case "!=":
+		return nativeBoolToBooleanObject(left != right)
+	}
+	return newTypeError(node, "unknown operator: FLOAT %s FLOAT", operator)
+}
+
+func evalStringInfixExpression(node ast.Node, operator string, left, right string) Object {
+	switch operator {
+	case "+":
+		return &String{Value: left + right}
+	case "<":
+		return nativeBoolToBooleanObject(left < right)
+	case ">":
+		return nativeBoolToBooleanObject(left > right)
+	case "<=":
+		return nativeBoolToBooleanObject(left <= right)
+	case ">=":
+		return nativeBoolToBooleanObject(left >= right)
+	case "==":
+		return nativeBoolToBooleanObject(left == right)
+	case "!=":
+		return nativeBoolToBooleanObject(left != right)
+	}
+	return newTypeError(node, "unknown operator: STRING %s STRING", operator)
+}
+
+// objectsEqual 比较两个对象是否相等：标量按值比较，其他对象按引用比较
+func objectsEqual(left, right Object) bool {
+	if isNumber(left) && isNumber(right) {
+		return toFloat(left) == toFloat(right)
+	}
+	if left.Type() != right.Type() {
+		return false
+	}
+	switch left := left.(type) {
+	case *String:
+		return left.Value == right.(*String).Value
+	case *Boolean:
+		return left.Value == right.(*Boolean).Value
+	case *Null:
+		return true
+	case *Selector:
+		return left.Value == right.(*Selector).Value
+	}
+	return left == right
+}
+
+// evalAssignExpression 对变量、数组元素或哈希成员赋值
+func evalAssignExpression(node *ast.AssignExpression, env *Environment) Object {
+	val := Eval(node.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		if env.IsConst(target.Value) {
+			return newTypeError(target, "cannot assign to constant %s", target.Value)
+		}
+		if !env.Assign(target.Value, val) {
+			return newReferenceError(target, "identifier not found: %s", target.Value)
+		}
+		return val
+
+	case *ast.IndexExpression:
+		container := Eval(target.Left, env)
+		if isError(container) {
+			return container
+		}
+		index := Eval(target.Index, env)
+		if isError(index) {
+			return index
+		}
+		return assignIndex(target, container, index, val)
+
+	case *ast.MemberExpression:
+		container := Eval(target.Object, env)
+		if isError(container) {
+			return container
+		}
+		return assignIndex(target, container, &String{Value: target.Property.Value}, val)
+	}
+
+	return newError(node, "invalid assignment target %s", node.Target.String())
+}
+
+func assignIndex(node ast.Node, container, index, val Object) Object {
+	switch container := container.(type) {
+	case *Array:
+		idx, ok := index.(*Integer)
+		if !ok {
+			return newTypeError(node, "array index must be INTEGER, got %s", index.Type())
+		}
+		i := idx.Value
+		if i < 0 {
+			i += int64(len(container.Elements))
+		}
+		if i < 0 || i >= int64(len(container.Elements)) {
+			return newError(node, "array index out of range: %d", idx.Value)
+		}
+		container.Elements[i] = val
+		return val
+
+	case *Hash:
+		key, ok := index.(Hashable)
+		if !ok {
+			return newTypeError(node, "unusable as hash key: %s", index.Type())
+		}
+		container.Pairs[key.HashKey()] = HashPair{Key: index, Value: val}
+		return val
+	}
+
+	return newTypeError(node, "cannot assign index on %s", container.Type())
+}
+
+func evalIfExpression(ie *ast.IfExpression, env *Environment) Object {
+	condition := Eval(ie.Condition, env)
+	if isError(condition) {
+		return condition
+	}
+
+	if isTruthy(condition) {
+		return Eval(ie.Consequence, NewEnclosedEnvironment(env))
+	} else if ie.Alternative != nil {
+		return Eval(ie.Alternative, NewEnclosedEnvironment(env))
+	}
+
+	return NULL
+}
+
+func evalWhileExpression(we *ast.WhileExpression, env *Environment) Object {
+	for {
+		condition := Eval(we.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if !isTruthy(condition) {
+			return NULL
+		}
+
+		result := Eval(we.Body, NewEnclosedEnvironment(env))
+		if stop, value := loopResult(result); stop {
+			return value
+		}
+	}
+}
+
+// evalForExpression 遍历数组元素、哈希键（按键排序）或字符串中的字符
+func evalForExpression(fe *ast.ForExpression, env *Environment) Object {
+	iterable := Eval(fe.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	var items []Object
+	switch iterable := iterable.(type) {
+	case *Array:
+		items = append(items, iterable.Elements...)
+	case *Hash:
+		for _, pair := range sortedPairs(iterable) {
+			items = append(items, pair.Key)
+		}
+	case *String:
+		for _, r := range iterable.Value {
+			items = append(items, &String{Value: string(r)})
+		}
+	case *Null:
+	default:
+		return newTypeError(fe.Iterable, "%s is not iterable", iterable.Type())
+	}
+
+	for _, item := range items {
+		loopEnv := NewEnclosedEnvironment(env)
+		loopEnv.Set(fe.Identifier.Value, item)
+
+		result := Eval(fe.Body, loopEnv)
+		if stop, value := loopResult(result); stop {
+			return value
+		}
+	}
+
+	return NULL
+}
+
+// loopResult 处理循环体的执行结果，返回是否需要结束循环以及循环的结果值
+func loopResult(result Object) (bool, Object) {
+	switch result := result.(type) {
+	case *ReturnValue, *Error:
+		return true, result
+	case *loopSignal:
+		if result.isBreak {
+			return true, NULL
+		}
+	}
+	return false, nil
+}
+
+// applyFunction 调用用户函数或内置函数
+func applyFunction(node ast.Node, fn Object, args []Object) Object {
+	switch fn := fn.(type) {
+	case *Function:
+		extendedEnv := extendFunctionEnv(fn, args)
+		evaluated := Eval(fn.Body, extendedEnv)
+		if signal, ok := evaluated.(*loopSignal); ok {
+			return newError(signal.node, "%s outside loop", signal.Inspect())
+		}
+		return unwrapReturnValue(evaluated)
+
+	case *Builtin:
+		result := fn.Fn(args...)
+		if err, ok := result.(*Error); ok && err.Line == 0 {
+			// 内置函数无法得知调用位置，由调用处补充
+			err.Line, err.Column = node.Position()
+		}
+		if result == nil {
+			return NULL
+		}
+		return result
+	}
+
+	return newTypeError(node, "not a function: %s", fn.Type())
+}
+
+// extendFunctionEnv 创建函数调用的环境，缺少的参数绑定为 null
+func extendFunctionEnv(fn *Function, args []Object) *Environment {
+	env := NewEnclosedEnvironment(fn.Env)
+
+	for i, param := range fn.Parameters {
+		if i < len(args) {
+			env.Set(param.Value, args[i])
+		} else {
+			env.Set(param.Value, NULL)
+		}
+	}
+
+	return env
+}
+
+func unwrapReturnValue(obj Object) Object {
+	if returnValue, ok := obj.(*ReturnValue); ok {
+		return returnValue.Value
+	}
+	return obj
+}
+
+// evalIndexExpression 对数组、字符串和哈希进行索引，负数下标从末尾计数
+func evalIndexExpression(node ast.Node, left, index Object) Object {
+	switch left := left.(type) {
+	case *Array:
+		idx, ok := index.(*Integer)
+		if !ok {
+			return newTypeError(node, "array index must be INTEGER, got %s", index.Type())
+		}
+		i := idx.Value
+		if i < 0 {
+			i += int64(len(left.Elements))
+		}
+		if i < 0 || i >= int64(len(left.Elements)) {
+			return NULL
+		}
+		return left.Elements[i]
+
+	case *String:
+		idx, ok := index.(*Integer)
+		if !ok {
+			return newTypeError(node, "string index must be INTEGER, got %s", index.Type())
+		}
+		runes := []rune(left.Value)
+		i := idx.Value
+		if i < 0 {
+			i += int64(len(runes))
+		}
+		if i < 0 || i >= int64(len(runes)) {
+			return NULL
+		}
+		return &String{Value: string(runes[i])}
+
+	case *Hash:
+		key, ok := index.(Hashable)
+		if !ok {
+			return newTypeError(node, "unusable as hash key: %s", index.Type())
+		}
+		pair, ok := left.Pairs[key.HashKey()]
+		if !ok {
+			return NULL
+		}
+		return pair.Value
+
+	case *Null:
+		return newTypeError(node, "cannot index null")
+
+	case *Element:
+		name, ok := index.(*String)
+		if !ok {
+			return newTypeError(node, "attribute name must be STRING, got %s", index.Type())
+		}
+		if val, ok := left.Attr(name.Value); ok {
+			return &String{Value: val}
+		}
+		return NULL
+	}
+
+	if accessor, ok := left.(MemberAccessor); ok {
+		if name, ok := index.(*String); ok {
+			if val, ok := accessor.Member(name.Value); ok {
+				return val
+			}
+			return NULL
+		}
+	}
+
+	return newTypeError(node, "index operator not supported: %s", left.Type())
+}
+
+// evalMemberExpression 处理 obj.name 和 obj?.name
+func evalMemberExpression(node *ast.MemberExpression, object Object) Object {
+	name := node.Property.Value
+
+	switch object := object.(type) {
+	case *Null:
+		if node.Optional {
+			return NULL
+		}
+		return newTypeError(node, "cannot read property %s of null", name)
+	case *Hash:
+		pair, ok := object.Pairs[(&String{Value: name}).HashKey()]
+		if !ok {
+			return NULL
+		}
+		return pair.Value
+	case *Array:
+		if name == "length" {
+			return &Integer{Value: int64(len(object.Elements))}
+		}
+	case *String:
+		if name == "length" {
+			return &Integer{Value: int64(len([]rune(object.Value)))}
+		}
+	case MemberAccessor:
+		if val, ok := object.Member(name); ok {
+			return val
+		}
+		return NULL
+	}
+
+	if node.Optional {
+		return NULL
+	}
+	return newTypeError(node, "%s has no property %s", object.Type(), name)
+}
+
+// evalAtExpression 将 @ 表达式转换为选择器对象，语言前缀保留在选择器文本中
+func evalAtExpression(node *ast.AtExpression, env *Environment) Object {
+	val := Eval(node.Selector, env)
+	if isError(val) {
+		return val
+	}
+
+	var value string
+	switch val := val.(type) {
+	case *Selector:
+		if node.Kind == "" {
+			return val
+		}
+		value = val.Value
+	case *String:
+		value = val.Value
+	default:
+		return newTypeError(node, "selector must be STRING, got %s", val.Type())
+	}
+
+	// 带语言前缀的选择器以 "kind:" 开头保存，由提取器据此选择求值方式
+	if node.Kind != "" {
+		value = node.Kind + ":" + value
+	}
+	return &Selector{Value: value}
+}
+
+// evalPipeStage 以管道左侧的值作为输入执行管道的一个阶段
+// 调用表达式会把输入作为第一个参数，其余表达式被当作单参数函数调用
+func evalPipeStage(stage ast.Expression, input Object, env *Environment) Object {
+	switch stage := stage.(type) {
+	case *ast.CallExpression:
+		function := Eval(stage.Function, env)
+		if isError(function) {
+			return function
+		}
+		args := evalExpressions(stage.Arguments, env)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+		return applyFunction(stage, function, append([]Object{input}, args...))
+	case *ast.OpenExpression:
+		if stage.URL == nil || stage.Options == nil {
+			return evalOpenExpression(stage, input, env)
+		}
+	case *ast.ExtractExpression:
+		if stage.Source == nil {
+			return evalExtractExpression(stage, input, env)
+		}
+	case *ast.CollectExpression:
+		if stage.Source == nil {
+			return evalCollectExpression(stage, input, env)
+		}
+	case *ast.CrawlExpression:
+		if stage.Seeds == nil || stage.Options == nil {
+			return evalCrawlExpression(stage, input, env)
+		}
+	}
+
+	function := Eval(stage, env)
+	if isError(function) {
+		return function
+	}
+	return applyFunction(stage, function, []Object{input})
+}
+
+// isTruthy 判断对象的真值：null、false、0、空字符串、空数组和空哈希为假
+func isTruthy(obj Object) bool {
+	switch obj := obj.(type) {
+	case *Null:
+		return false
+	case *Boolean:
+		return obj.Value
+	case *Integer:
+		return obj.Value != 0
+	case *Float:
+		return obj.Value != 0
+	case *String:
+		return obj.Value != ""
+	case *Array:
+		return len(obj.Elements) > 0
+	case *Hash:
+		return len(obj.Pairs) > 0
+	}
+	return true
+}
+
+func nativeBoolToBooleanObject(input bool) *Boolean {
+	if input {
+		return TRUE
+	}
+	return FALSE
+}
+
+func isNumber(obj Object) bool {
+	return obj.Type() == INTEGER_OBJ || obj.Type() == FLOAT_OBJ
+}
+
+func toFloat(obj Object) float64 {
+	switch obj := obj.(type) {
+	case *Integer:
+		return float64(obj.Value)
+	case *Float:
+		return obj.Value
+	}
+	return 0
+}
+
+// sortedPairs 返回按键排序的哈希键值对，使遍历顺序稳定
+func sortedPairs(h *Hash) []HashPair {
+	pairs := make([]HashPair, 0, len(h.Pairs))
+	for _, pair := range h.Pairs {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Key.Inspect() < pairs[j].Key.Inspect()
+	})
+	return pairs
+}
+
+func isError(obj Object) bool {
+	return obj != nil && obj.Type() == ERROR_OBJ
+}
+
+// newError 在节点位置创建运行时错误
+func newError(node ast.Node, format string, a ...interface{}) *Error {
+	return newErrorOfKind(errors.RuntimeError, node, format, a...)
+}
+
+// newTypeError 在节点位置创建类型错误
+func newTypeError(node ast.Node, format string, a ...interface{}) *Error {
+	return newErrorOfKind(errors.TypeError, node, format, a...)
+}
+
+// newReferenceError 在节点位置创建引用错误
+func newReferenceError(node ast.Node, format string, a ...interface{}) *Error {
+	return newErrorOfKind(errors.ReferenceError, node, format, a...)
+}
+
+func newErrorOfKind(kind errors.ErrorType, node ast.Node, format string, a ...interface{}) *Error {
+	line, column := node.Position()
+	return &Error{
+		Message: fmt.Sprintf(format, a...),
+		Line:    line,
+		Column:  column,
+		Kind:    kind,
+	}
+}
@@ -0,0 +1,371 @@
+package eval
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btrobot/mydsl/errors"
+	"github.com/btrobot/mydsl/lexer"
+	"github.com/btrobot/mydsl/parser"
+)
+
+func testEval(t *testing.T, input string) Object {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors()[0])
+	}
+
+	return Eval(program, NewEnvironment())
+}
+
+func testIntegerObject(t *testing.T, obj Object, expected int64) {
+	t.Helper()
+
+	result, ok := obj.(*Integer)
+	if !ok {
+		t.Errorf("object is not Integer. got=%T (%+v)", obj, obj)
+		return
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%d, want=%d", result.Value, expected)
+	}
+}
+
+func testFloatObject(t *testing.T, obj Object, expected float64) {
+	t.Helper()
+
+	result, ok := obj.(*Float)
+	if !ok {
+		t.Errorf("object is not Float. got=%T (%+v)", obj, obj)
+		return
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%g, want=%g", result.Value, expected)
+	}
+}
+
+func testBooleanObject(t *testing.T, obj Object, expected bool) {
+	t.Helper()
+
+	result, ok := obj.(*Boolean)
+	if !ok {
+		t.Errorf("object is not Boolean. got=%T (%+v)", obj, obj)
+		return
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%t, want=%t", result.Value, expected)
+	}
+}
+
+func testStringObject(t *testing.T, obj Object, expected string) {
+	t.Helper()
+
+	result, ok := obj.(*String)
+	if !ok {
+		t.Errorf("object is not String. got=%T (%+v)", obj, obj)
+		return
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%q, want=%q", result.Value, expected)
+	}
+}
+
+func TestEvalArithmetic(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"5", int64(5)},
+		{"-10", int64(-10)},
+		{"2 * (5 + 10)", int64(30)},
+		{"7 / 2", int64(3)},
+		{"7 % 3", int64(1)},
+		{"7 / 2.0", 3.5},
+		{"1.5 + 1", 2.5},
+		{"-2.5 * 2", -5.0},
+		{"5.5 % 2", 1.5},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		switch expected := tt.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case float64:
+			testFloatObject(t, evaluated, expected)
+		}
+	}
+}
+
+func TestEvalBooleanExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"true", true},
+		{"1 < 2", true},
+		{"1 == 1.0", true},
+		{"2.5 >= 3", false},
+		{`"a" < "b"`, true},
+		{`"a" == "a"`, true},
+		{`"a" != 1`, true},
+		{"null == null", true},
+		{"true && false", false},
+		{"false || 1", true},
+		{"!0", true},
+		{`!""`, true},
+		{"![]", true},
+		{"!!{a: 1}", true},
+		{"!null", true},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestShortCircuit(t *testing.T) {
+	// 右侧引用未定义变量，短路时不应求值
+	testBooleanObject(t, testEval(t, "false && undefined"), false)
+	testBooleanObject(t, testEval(t, "true || undefined"), true)
+	testIntegerObject(t, testEval(t, "null ?? 5"), 5)
+	testIntegerObject(t, testEval(t, "3 ?? undefined"), 3)
+	testIntegerObject(t, testEval(t, "let h = {}; h?.a?.b ?? 7"), 7)
+}
+
+func TestStringConcatenation(t *testing.T) {
+	testStringObject(t, testEval(t, `"Hello" + " " + "World!"`), "Hello World!")
+	testStringObject(t, testEval(t, `"page " + 2`), "page 2")
+	testStringObject(t, testEval(t, `1.5 + "x"`), "1.5x")
+}
+
+func TestConditionals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"if (true) { 10 }", int64(10)},
+		{"if (false) { 10 }", nil},
+		{"if (1 > 2) { 10 } else { 20 }", int64(20)},
+		{"if 0 { 1 } else if 2 { 2 } else { 3 }", int64(2)},
+		{"1 > 2 ? 1 : 2", int64(2)},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		if expected, ok := tt.expected.(int64); ok {
+			testIntegerObject(t, evaluated, expected)
+		} else if evaluated != NULL {
+			t.Errorf("object is not NULL. got=%T (%+v)", evaluated, evaluated)
+		}
+	}
+}
+
+func TestReturnStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"return 10; 9;", 10},
+		{"9; return 2 * 5; 9;", 10},
+		{"if (10 > 1) { if (10 > 1) { return 10; } return 1; }", 10},
+		{"let f = function(x) { for i in [1, 2, 3] { if i == x { return i * 10 } } return 0 }; f(2)", 20},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestLetAndAssign(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let a = 5; a;", 5},
+		{"let a = 5; let b = a * 2; b;", 10},
+		{"let a = 1; a = a + 1; a", 2},
+		{"let a = 1; if true { a = 3 }; a", 3},
+		{"let a = 1; if true { let a = 3 }; a", 1},
+		{"let arr = [1, 2, 3]; arr[1] = 9; arr[1]", 9},
+		{"let h = {}; h.x = 4; h[\"x\"]", 4},
+		{"const c = 8; c", 8},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let i = 0; while i < 5 { i = i + 1 }; i", 5},
+		{"let sum = 0; for x in [1, 2, 3, 4] { sum = sum + x }; sum", 10},
+		{"let sum = 0; for x in range(10) { if x == 5 { break } sum = sum + x }; sum", 10},
+		{"let sum = 0; for x in range(6) { if x % 2 == 0 { continue } sum = sum + x }; sum", 9},
+		{"let n = 0; for k in {a: 1, b: 2} { n = n + 1 }; n", 2},
+		{"let n = 0; for c in \"中文\" { n = n + 1 }; n", 2},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestFunctionsAndClosures(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let identity = function(x) { x; }; identity(5);", 5},
+		{"let add = function(x, y) { return x + y; }; add(5, add(5, 5));", 15},
+		{"function(x) { x; }(5)", 5},
+		{"let newAdder = function(x) { function(y) { x + y } }; let addTwo = newAdder(2); addTwo(3);", 5},
+		{"function fact(n) { if n <= 1 { return 1 } n * fact(n - 1) } fact(5)", 120},
+		{"let sq = x => x * x; sq(4)", 16},
+		{"let counter = function() { let c = 0; () => { c = c + 1; c } }; let next = counter(); next(); next(); next()", 3},
+		{"[1, 2, 3] | length", 3},
+		{"let add = (a, b) => a + b; 1 | add(2) | add(3)", 6},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestIndexAndMembers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[1, 2, 3][0]", int64(1)},
+		{"[1, 2, 3][-1]", int64(3)},
+		{"[1, 2, 3][3]", nil},
+		{`"héllo"[1]`, "é"},
+		{`{"foo": 5}["foo"]`, int64(5)},
+		{`{foo: 5}.foo`, int64(5)},
+		{`{foo: 5}.bar`, nil},
+		{`{1: "one", true: "yes"}[1]`, "one"},
+		{`{1: "one", true: "yes"}[true]`, "yes"},
+		{`[1, 2].length`, int64(2)},
+		{`"abc".length`, int64(3)},
+		{`let h = {a: {b: 2}}; h.a.b`, int64(2)},
+		{`{a: 1, a: 2}.a`, int64(2)},
+		{`let s = ""; let f = function(x) { s = s + x; x }; let h = {a: f("a"), b: f("b"), c: f("c")}; s`, "abc"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		switch expected := tt.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case string:
+			testStringObject(t, evaluated, expected)
+		default:
+			if evaluated != NULL {
+				t.Errorf("input %q: object is not NULL. got=%T (%+v)", tt.input, evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestErrorHandling(t *testing.T) {
+	tests := []struct {
+		input   string
+		message string
+		kind    errors.ErrorType
+		line    int
+		column  int
+	}{
+		{"5 + true;", "unsupported operand types: INTEGER + BOOLEAN", errors.TypeError, 1, 3},
+		{"-true", "unknown operator: -BOOLEAN", errors.TypeError, 1, 1},
+		{"let x = 1;\n  foobar", "identifier not found: foobar", errors.ReferenceError, 2, 3},
+		{"1 / 0", "division by zero", errors.RuntimeError, 1, 3},
+		{"if (10 > 1) { return true + false; }", "unsupported operand types: BOOLEAN + BOOLEAN", errors.TypeError, 1, 27},
+		{`{"name": "x"}[function(x) { x }];`, "unusable as hash key: FUNCTION", errors.TypeError, 1, 14},
+		{"const c = 1; c = 2", "cannot assign to constant c", errors.TypeError, 1, 14},
+		{"undefinedVar = 2", "identifier not found: undefinedVar", errors.ReferenceError, 1, 1},
+		{"break", "break outside loop", errors.RuntimeError, 1, 1},
+		{"let f = 5; f(1)", "not a function: INTEGER", errors.TypeError, 1, 13},
+		{"null.x", "cannot read property x of null", errors.TypeError, 1, 5},
+		{"length(1, 2)", "length: wrong number of arguments. got=2, want=1", errors.TypeError, 1, 7},
+		{"for x in 5 { x }", "INTEGER is not iterable", errors.TypeError, 1, 10},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+
+		errObj, ok := evaluated.(*Error)
+		if !ok {
+			t.Errorf("input %q: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.message {
+			t.Errorf("input %q: wrong error message. expected=%q, got=%q", tt.input, tt.message, errObj.Message)
+		}
+		if errObj.Kind != tt.kind {
+			t.Errorf("input %q: wrong error kind. expected=%d, got=%d", tt.input, tt.kind, errObj.Kind)
+		}
+		if errObj.Line != tt.line || errObj.Column != tt.column {
+			t.Errorf("input %q: wrong position. expected=%d:%d, got=%d:%d",
+				tt.input, tt.line, tt.column, errObj.Line, errObj.Column)
+		}
+	}
+}
+
+func TestBuiltinFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`len("")`, int64(0)},
+		{`length("four")`, int64(4)},
+		{`len([1, 2, 3])`, int64(3)},
+		{`join(keys({b: 1, a: 2}), ",")`, "a,b"},
+		{`join(values({b: 1, a: 2}), ",")`, "2,1"},
+		{`let h = {a: 1}; delete(h, "a"); len(h)`, int64(0)},
+		{`let a = [1]; push(a, 2, 3); len(a)`, int64(3)},
+		{`first([4, 5])`, int64(4)},
+		{`last([4, 5])`, int64(5)},
+		{`type(1.5)`, "float"},
+		{`str(12) + "!"`, "12!"},
+		{`int(" 42 ") + 1`, int64(43)},
+		{`join(split("a-b-c", "-"), "+")`, "a+b+c"},
+		{`trim("  x ") | upper`, "X"},
+		{`replace("a.b.c", ".", "/")`, "a/b/c"},
+		{`contains("scraper", "rap")`, true},
+		{`contains([1, 2], 2.0)`, true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(t, tt.input)
+		switch expected := tt.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case string:
+			testStringObject(t, evaluated, expected)
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		}
+	}
+}
+
+func TestPrint(t *testing.T) {
+	var out bytes.Buffer
+	saved := Stdout
+	Stdout = &out
+	defer func() { Stdout = saved }()
+
+	testEval(t, `print("total:", 3, [1, "a"])`)
+
+	if out.String() != "total: 3 [1, a]\n" {
+		t.Errorf("print output wrong. got=%q", out.String())
+	}
+}
@@ -0,0 +1,256 @@
+package eval
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+
+	"github.com/btrobot/mydsl/crawler/extract"
+	"github.com/btrobot/mydsl/errors"
+	"golang.org/x/net/html"
+)
+
+// sourceNode 返回内置函数参数对应的 HTML 节点及其所属文档
+// 元素返回其自身节点，文档、响应和字符串返回解析后的根节点；
+// 直接由节点构造的元素没有所属文档，此时返回的文档为 nil
+func sourceNode(name string, arg Object) (*html.Node, *extract.Document, *Error) {
+	var doc *HTMLDocument
+	switch arg := arg.(type) {
+	case *Element:
+		if n := arg.Result.Node(); n != nil {
+			return n, arg.Result.Document(), nil
+		}
+		return nil, nil, builtinError(errors.RuntimeError, "%s: element is not attached to a document", name)
+	case *HTMLDocument:
+		doc = arg
+	case *HTTPResponse:
+		doc = arg.Document()
+	case *String:
+		doc = &HTMLDocument{Content: arg.Value}
+	default:
+		return nil, nil, argTypeError(name, arg)
+	}
+
+	tree, err := doc.Tree()
+	if err != nil {
+		return nil, nil, builtinError(errors.RuntimeError, "%s: cannot parse HTML: %v", name, err)
+	}
+	return tree.Root, tree, nil
+}
+
+// builtinTable 将表格转换为以表头文本为键的记录数组
+// 参数不是 table 元素时使用其中的第一个表格
+func builtinTable(args ...Object) Object {
+	if err := checkArgs("table", args, 1, 1); err != nil {
+		return err
+	}
+
+	n, _, errObj := sourceNode("table", args[0])
+	if errObj != nil {
+		return errObj
+	}
+	t := extract.FindTable(n)
+	if t == nil {
+		return builtinError(errors.SelectorError, "table: no table element found")
+	}
+
+	records := extract.ParseTable(t).Records()
+	rows := make([]Object, len(records))
+	for i, record := range records {
+		rows[i] = stringMapToHash(record)
+	}
+	return &Array{Elements: rows}
+}
+
+// builtinMetadata 提取页面中的 JSON-LD、Microdata、OpenGraph 和 meta 标签
+func builtinMetadata(args ...Object) Object {
+	if err := checkArgs("metadata", args, 1, 1); err != nil {
+		return err
+	}
+
+	n, _, errObj := sourceNode("metadata", args[0])
+	if errObj != nil {
+		return errObj
+	}
+	return nativeToObject(extract.ExtractMetadata(n).Map())
+}
+
+// nativeToObject 将 encoding/json 风格的 Go 值转换为对象
+// 没有小数部分的数字转换为整数
+func nativeToObject(v interface{}) Object {
+	switch v := v.(type) {
+	case nil:
+		return NULL
+	case bool:
+		return nativeBoolToBooleanObject(v)
+	case string:
+		return &String{Value: v}
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return &Integer{Value: int64(v)}
+		}
+		return &Float{Value: v}
+	case []interface{}:
+		elements := make([]Object, len(v))
+		for i, elem := range v {
+			elements[i] = nativeToObject(elem)
+		}
+		return &Array{Elements: elements}
+	case map[string]interface{}:
+		pairs := make(map[HashKey]HashPair, len(v))
+		for k, val := range v {
+			key := &String{Value: k}
+			pairs[key.HashKey()] = HashPair{Key: key, Value: nativeToObject(val)}
+		}
+		return &Hash{Pairs: pairs}
+	}
+	return &String{Value: fmt.Sprint(v)}
+}
+
+// builtinArticle 识别页面正文，返回 title、byline、content_html、text 和 published
+// 未找到的字段为 null
+func builtinArticle(args ...Object) Object {
+	if err := checkArgs("article", args, 1, 1); err != nil {
+		return err
+	}
+
+	n, _, errObj := sourceNode("article", args[0])
+	if errObj != nil {
+		return errObj
+	}
+	a := extract.ExtractArticle(n)
+
+	fields := []struct{ name, value string }{
+		{"title", a.Title},
+		{"byline", a.Byline},
+		{"content_html", a.ContentHTML},
+		{"text", a.Text},
+		{"published", a.Published},
+	}
+	pairs := make(map[HashKey]HashPair, len(fields))
+	for _, field := range fields {
+		key := &String{Value: field.name}
+		var value Object = NULL
+		if field.value != "" {
+			value = &String{Value: field.value}
+		}
+		pairs[key.HashKey()] = HashPair{Key: key, Value: value}
+	}
+	return &Hash{Pairs: pairs}
+}
+
+// builtinMarkdown 将元素或文档转换为 Markdown，链接和图片地址按文档地址解析为绝对地址
+func builtinMarkdown(args ...Object) Object {
+	if err := checkArgs("markdown", args, 1, 1); err != nil {
+		return err
+	}
+
+	n, doc, errObj := sourceNode("markdown", args[0])
+	if errObj != nil {
+		return errObj
+	}
+	var base *url.URL
+	if doc != nil {
+		base = doc.BaseURL()
+	}
+	return &String{Value: extract.Markdown(n, base)}
+}
+
+// builtinLinks 返回文档或元素中的绝对链接，可以传入选择器限定范围和选项哈希
+// 选项 schemes 为保留的协议数组（默认 http 和 https），sort_query 为真时按参数名排序查询参数
+func builtinLinks(args ...Object) Object {
+	if err := checkArgs("links", args, 1, 3); err != nil {
+		return err
+	}
+
+	n, doc, errObj := sourceNode("links", args[0])
+	if errObj != nil {
+		return errObj
+	}
+
+	selector := ""
+	var opts extract.LinkOptions
+	for i, arg := range args[1:] {
+		switch arg := arg.(type) {
+		case *Selector:
+			selector = arg.Value
+		case *String:
+			selector = arg.Value
+		case *Hash:
+			if i != len(args)-2 {
+				return builtinError(errors.TypeError, "links: options must be the last argument")
+			}
+			if errObj := linkOptions(arg, &opts); errObj != nil {
+				return errObj
+			}
+			continue
+		default:
+			return argTypeError("links", arg)
+		}
+		if i != 0 {
+			return builtinError(errors.TypeError, "links: selector must be the second argument")
+		}
+	}
+
+	nodes := []*html.Node{n}
+	if selector != "" {
+		var results []*extract.Result
+		var err error
+		if elem, ok := args[0].(*Element); ok {
+			results, err = elem.Result.Extract(selector)
+		} else {
+			results, err = extract.Extract(doc, selector)
+		}
+		if err != nil {
+			return builtinError(errors.SelectorError, "links: %v", err)
+		}
+		nodes = nodes[:0]
+		for _, r := range results {
+			nodes = append(nodes, r.Node())
+		}
+	}
+
+	var base *url.URL
+	if doc != nil {
+		base = doc.BaseURL()
+	}
+	links := extract.Links(nodes, base, opts)
+	elements := make([]Object, len(links))
+	for i, link := range links {
+		elements[i] = &String{Value: link}
+	}
+	return &Array{Elements: elements}
+}
+
+// linkOptions 读取 links 的选项哈希
+func linkOptions(hash *Hash, opts *extract.LinkOptions) *Error {
+	for _, pair := range hash.Pairs {
+		key, ok := pair.Key.(*String)
+		if !ok {
+			return builtinError(errors.TypeError, "links: option key must be STRING, got %s", pair.Key.Type())
+		}
+		switch key.Value {
+		case "schemes":
+			list, ok := pair.Value.(*Array)
+			if !ok {
+				return builtinError(errors.TypeError, "links: schemes must be ARRAY, got %s", pair.Value.Type())
+			}
+			for _, elem := range list.Elements {
+				scheme, ok := elem.(*String)
+				if !ok {
+					return builtinError(errors.TypeError, "links: scheme must be STRING, got %s", elem.Type())
+				}
+				opts.Schemes = append(opts.Schemes, scheme.Value)
+			}
+		case "sort_query":
+			flag, ok := pair.Value.(*Boolean)
+			if !ok {
+				return builtinError(errors.TypeError, "links: sort_query must be BOOLEAN, got %s", pair.Value.Type())
+			}
+			opts.SortQuery = flag.Value
+		default:
+			return builtinError(errors.TypeError, "links: unknown option %q", key.Value)
+		}
+	}
+	return nil
+}
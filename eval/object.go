@@ -7,6 +7,8 @@ import (
     "strings"
     
     "github.com/btrobot/mydsl/ast"
+    "github.com/btrobot/mydsl/crawler/extract"
+    "github.com/btrobot/mydsl/errors"
 )
 
 // ObjectType 表示对象类型
@@ -29,6 +31,7 @@ const (
     HTML_DOC_OBJ     = "HTML_DOC"
     SELECTOR_OBJ     = "SELECTOR"
     HTTP_RESPONSE_OBJ = "HTTP_RESPONSE"
+    ELEMENT_OBJ      = "ELEMENT"
 )
 
 // Object 表示所有值类型的接口
@@ -116,6 +119,7 @@ type Error struct {
     Message string
     Line    int
     Column  int
+    Kind    errors.ErrorType // 错误类型，与 errors 包中的分类一致
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
@@ -124,8 +128,19 @@ func (e *Error) Inspect() string {
         e.Message, e.Line, e.Column) 
 }
 
+// ToError 将错误对象转换为 errors.Error
+func (e *Error) ToError() *errors.Error {
+    return &errors.Error{
+        Type:    e.Kind,
+        Message: e.Message,
+        Line:    e.Line,
+        Column:  e.Column,
+    }
+}
+
 // Function 表示函数对象
 type Function struct {
+    Name       string
     Parameters []*ast.Identifier
     Body       *ast.BlockStatement
     Env        *Environment
@@ -141,6 +156,9 @@ func (f *Function) Inspect() string {
     }
     
     out.WriteString("function")
+    if f.Name != "" {
+        out.WriteString(" " + f.Name)
+    }
     out.WriteString("(")
     out.WriteString(strings.Join(params, ", "))
     out.WriteString(") {\n")
@@ -155,12 +173,18 @@ type BuiltinFunction func(args ...Object) Object
 
 // Builtin 表示内置函数对象
 type Builtin struct {
-    Fn BuiltinFunction
+    Name string
+    Fn   BuiltinFunction
 }
 
 func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
 func (b *Builtin) Inspect() string { return "builtin function" }
 
+// MemberAccessor 表示可以通过 obj.name 访问成员的对象
+type MemberAccessor interface {
+    Member(name string) (Object, bool)
+}
+
 // Array 表示数组对象
 type Array struct {
     Elements []Object
@@ -214,6 +238,21 @@ func (h *Hash) Inspect() string {
 type HTMLDocument struct {
     Content string
     URL     string
+    
+    tree *extract.Document // 延迟解析的节点树，多次提取共享
+}
+
+// Tree 返回文档解析后的节点树，只在第一次调用时解析
+func (h *HTMLDocument) Tree() (*extract.Document, error) {
+    if h.tree == nil {
+        tree, err := extract.Parse(h.Content)
+        if err != nil {
+            return nil, err
+        }
+        tree.URL = h.URL
+        h.tree = tree
+    }
+    return h.tree, nil
 }
 
 func (h *HTMLDocument) Type() ObjectType { return HTML_DOC_OBJ }
@@ -221,6 +260,17 @@ func (h *HTMLDocument) Inspect() string {
     return fmt.Sprintf("HTMLDocument(%s)", h.URL) 
 }
 
+// Member 实现 MemberAccessor 接口
+func (h *HTMLDocument) Member(name string) (Object, bool) {
+    switch name {
+    case "url":
+        return &String{Value: h.URL}, true
+    case "html":
+        return &String{Value: h.Content}, true
+    }
+    return nil, false
+}
+
 // Selector 表示选择器对象
 type Selector struct {
     Value string
@@ -240,9 +290,67 @@ type HTTPResponse struct {
     Body       string
     Headers    map[string]string
     URL        string
+    
+    doc *HTMLDocument // 延迟创建的 HTML 视图
 }
 
 func (hr *HTTPResponse) Type() ObjectType { return HTTP_RESPONSE_OBJ }
 func (hr *HTTPResponse) Inspect() string { 
     return fmt.Sprintf("HTTPResponse(%d, %s)", hr.StatusCode, hr.URL) 
 }
+
+// Document 返回响应体的 HTML 文档视图，供 extract/collect 使用
+func (hr *HTTPResponse) Document() *HTMLDocument {
+    if hr.doc == nil {
+        hr.doc = &HTMLDocument{Content: hr.Body, URL: hr.URL}
+    }
+    return hr.doc
+}
+
+// Member 实现 MemberAccessor 接口
+func (hr *HTTPResponse) Member(name string) (Object, bool) {
+    switch name {
+    case "status":
+        return &Integer{Value: int64(hr.StatusCode)}, true
+    case "ok":
+        return nativeBoolToBooleanObject(hr.StatusCode >= 200 && hr.StatusCode < 300), true
+    case "body":
+        return &String{Value: hr.Body}, true
+    case "url":
+        return &String{Value: hr.URL}, true
+    case "headers":
+        return stringMapToHash(hr.Headers), true
+    case "doc":
+        return hr.Document(), true
+    }
+    return nil, false
+}
+
+// Element 表示从 HTML 中提取出的元素
+type Element struct {
+    Result *extract.Result
+}
+
+func (e *Element) Type() ObjectType { return ELEMENT_OBJ }
+func (e *Element) Inspect() string { return e.Result.Text }
+
+// Member 实现 MemberAccessor 接口，el.name 访问文本、HTML、属性和标签名
+func (e *Element) Member(name string) (Object, bool) {
+    switch name {
+    case "text":
+        return &String{Value: e.Result.Text}, true
+    case "html":
+        return &String{Value: e.Result.HTML}, true
+    case "tag":
+        return &String{Value: e.Result.Tag}, true
+    case "attrs":
+        return stringMapToHash(e.Result.Attr), true
+    }
+    return nil, false
+}
+
+// Attr 返回元素的属性值
+func (e *Element) Attr(name string) (string, bool) {
+    val, ok := e.Result.Attr[name]
+    return val, ok
+}
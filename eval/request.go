@@ -0,0 +1,323 @@
+package eval
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/btrobot/mydsl/crawler/extract"
+	"github.com/btrobot/mydsl/crawler/fetch"
+	"github.com/btrobot/mydsl/errors"
+)
+
+// requestOptions 将 open 的选项哈希写入请求
+// 选项：method、headers（哈希）、query（哈希）、form（哈希）、files（哈希）、
+// multipart（布尔值）、json（null 以外的任意值）和 body（字符串）；
+// 没有 method 时，设置了请求体的请求使用 POST，其余使用 GET
+func requestOptions(name string, options *Hash, req *fetch.Request) *Error {
+	for _, pair := range options.Pairs {
+		key, ok := pair.Key.(*String)
+		if !ok {
+			return builtinError(errors.TypeError, "%s: option key must be STRING, got %s", name, pair.Key.Type())
+		}
+		value := pair.Value
+
+		switch key.Value {
+		case "method":
+			s, ok := value.(*String)
+			if !ok || s.Value == "" {
+				return builtinError(errors.TypeError, "%s: method must be a non-empty STRING, got %s", name, value.Inspect())
+			}
+			req.Method = s.Value
+		case "headers":
+			values, errObj := formValues(name, key.Value, value)
+			if errObj != nil {
+				return errObj
+			}
+			req.Header = make(map[string]string, len(values))
+			for k, v := range values {
+				req.Header[k] = strings.Join(v, ", ")
+			}
+		case "query", "form":
+			values, errObj := formValues(name, key.Value, value)
+			if errObj != nil {
+				return errObj
+			}
+			if key.Value == "query" {
+				req.Query = values
+			} else {
+				req.Form = values
+			}
+		case "files":
+			hash, ok := value.(*Hash)
+			if !ok {
+				return builtinError(errors.TypeError, "%s: files must be HASH, got %s", name, value.Type())
+			}
+			for _, file := range hash.Pairs {
+				part, errObj := filePart(name, file.Key, file.Value)
+				if errObj != nil {
+					return errObj
+				}
+				req.Files = append(req.Files, part)
+			}
+			sort.Slice(req.Files, func(i, j int) bool { return req.Files[i].Field < req.Files[j].Field })
+		case "multipart":
+			b, ok := value.(*Boolean)
+			if !ok {
+				return builtinError(errors.TypeError, "%s: multipart must be BOOLEAN, got %s", name, value.Type())
+			}
+			req.Multipart = b.Value
+		case "json":
+			v, errObj := objectToNative(name, value)
+			if errObj != nil {
+				return errObj
+			}
+			req.JSON = v
+		case "body":
+			s, ok := value.(*String)
+			if !ok {
+				return builtinError(errors.TypeError, "%s: body must be STRING, got %s", name, value.Type())
+			}
+			req.Body = []byte(s.Value)
+		default:
+			return builtinError(errors.TypeError, "%s: unknown option %q", name, key.Value)
+		}
+	}
+
+	bodies := 0
+	for _, set := range []bool{req.Body != nil, req.Form != nil || req.Files != nil || req.Multipart, req.JSON != nil} {
+		if set {
+			bodies++
+		}
+	}
+	if bodies > 1 {
+		return builtinError(errors.TypeError, "%s: only one of body, form/files and json may be set", name)
+	}
+	return nil
+}
+
+// formValues 将哈希转换为表单值；数组表示同名的多个值，null 值被忽略
+func formValues(name, option string, obj Object) (url.Values, *Error) {
+	hash, ok := obj.(*Hash)
+	if !ok {
+		return nil, builtinError(errors.TypeError, "%s: %s must be HASH, got %s", name, option, obj.Type())
+	}
+	values := url.Values{}
+	for _, pair := range hash.Pairs {
+		key, ok := pair.Key.(*String)
+		if !ok {
+			return nil, builtinError(errors.TypeError, "%s: %s key must be STRING, got %s", name, option, pair.Key.Type())
+		}
+		vs, errObj := fieldValues(name, pair.Value)
+		if errObj != nil {
+			return nil, errObj
+		}
+		for _, v := range vs {
+			values.Add(key.Value, v)
+		}
+	}
+	return values, nil
+}
+
+// fieldValues 将字段值转换为字符串列表，null 返回空列表
+func fieldValues(name string, obj Object) ([]string, *Error) {
+	switch obj := obj.(type) {
+	case *String:
+		return []string{obj.Value}, nil
+	case *Integer, *Float, *Boolean:
+		return []string{obj.Inspect()}, nil
+	case *Null:
+		return nil, nil
+	case *Array:
+		var values []string
+		for _, elem := range obj.Elements {
+			vs, errObj := fieldValues(name, elem)
+			if errObj != nil {
+				return nil, errObj
+			}
+			values = append(values, vs...)
+		}
+		return values, nil
+	}
+	return nil, builtinError(errors.TypeError, "%s: field value must be STRING, number or BOOLEAN, got %s", name, obj.Type())
+}
+
+// filePart 将 {filename, content, content_type} 哈希或字符串内容转换为上传的文件
+// 内容为字符串时以字段名作为文件名
+func filePart(name string, key, value Object) (fetch.FilePart, *Error) {
+	field, ok := key.(*String)
+	if !ok {
+		return fetch.FilePart{}, builtinError(errors.TypeError, "%s: file field must be STRING, got %s", name, key.Type())
+	}
+	part := fetch.FilePart{Field: field.Value, Filename: field.Value}
+
+	switch value := value.(type) {
+	case *String:
+		part.Data = []byte(value.Value)
+	case *Hash:
+		for _, pair := range value.Pairs {
+			k, ok := pair.Key.(*String)
+			if !ok {
+				return part, builtinError(errors.TypeError, "%s: file option key must be STRING, got %s", name, pair.Key.Type())
+			}
+			s, ok := pair.Value.(*String)
+			if !ok {
+				return part, builtinError(errors.TypeError, "%s: file %s must be STRING, got %s", name, k.Value, pair.Value.Type())
+			}
+			switch k.Value {
+			case "filename":
+				part.Filename = s.Value
+			case "content":
+				part.Data = []byte(s.Value)
+			case "content_type":
+				part.ContentType = s.Value
+			default:
+				return part, builtinError(errors.TypeError, "%s: unknown file option %q", name, k.Value)
+			}
+		}
+	default:
+		return part, builtinError(errors.TypeError, "%s: file %q must be STRING or HASH, got %s", name, field.Value, value.Type())
+	}
+	return part, nil
+}
+
+// objectToNative 将对象转换为可以编码为 JSON 的 Go 值，是 nativeToObject 的逆操作
+func objectToNative(name string, obj Object) (interface{}, *Error) {
+	switch obj := obj.(type) {
+	case *Null:
+		return nil, nil
+	case *Boolean:
+		return obj.Value, nil
+	case *Integer:
+		return obj.Value, nil
+	case *Float:
+		return obj.Value, nil
+	case *String:
+		return obj.Value, nil
+	case *Array:
+		values := make([]interface{}, len(obj.Elements))
+		for i, elem := range obj.Elements {
+			v, errObj := objectToNative(name, elem)
+			if errObj != nil {
+				return nil, errObj
+			}
+			values[i] = v
+		}
+		return values, nil
+	case *Hash:
+		values := make(map[string]interface{}, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			key, ok := pair.Key.(*String)
+			if !ok {
+				return nil, builtinError(errors.TypeError, "%s: JSON object key must be STRING, got %s", name, pair.Key.Type())
+			}
+			v, errObj := objectToNative(name, pair.Value)
+			if errObj != nil {
+				return nil, errObj
+			}
+			values[key.Value] = v
+		}
+		return values, nil
+	}
+	return nil, builtinError(errors.TypeError, "%s: cannot encode %s as JSON", name, obj.Type())
+}
+
+// builtinSubmit 提交页面中的表单：submit(form, {field: value})
+// 第一个参数可以是 form 元素、表单内的元素，或包含表单的文档、响应；
+// 表单原有的字段（包括隐藏的 CSRF 令牌）保留，传入的值覆盖同名字段，null 删除字段，
+// 数组设置多个值；multipart 表单中哈希值 {filename, content, content_type} 表示上传的文件
+func builtinSubmit(rt *Runtime, args ...Object) Object {
+	if err := checkArgs("submit", args, 1, 2); err != nil {
+		return err
+	}
+	n, doc, errObj := sourceNode("submit", args[0])
+	if errObj != nil {
+		return errObj
+	}
+	formNode := extract.FindForm(n)
+	if formNode == nil {
+		return builtinError(errors.SelectorError, "submit: no form found")
+	}
+
+	var base *url.URL
+	if doc != nil {
+		base = doc.BaseURL()
+	}
+	form := extract.ParseForm(formNode, base)
+	action, err := url.Parse(form.Action)
+	if err != nil || !action.IsAbs() {
+		return builtinError(errors.RuntimeError, "submit: cannot resolve form action %q to an absolute URL", form.Action)
+	}
+
+	values := form.Values()
+	var files []fetch.FilePart
+	if len(args) == 2 {
+		hash, ok := args[1].(*Hash)
+		if !ok {
+			return argTypeError("submit", args[1])
+		}
+		for _, pair := range hash.Pairs {
+			key, ok := pair.Key.(*String)
+			if !ok {
+				return builtinError(errors.TypeError, "submit: field name must be STRING, got %s", pair.Key.Type())
+			}
+			if _, isFile := pair.Value.(*Hash); isFile {
+				part, errObj := filePart("submit", key, pair.Value)
+				if errObj != nil {
+					return errObj
+				}
+				files = append(files, part)
+				continue
+			}
+			vs, errObj := fieldValues("submit", pair.Value)
+			if errObj != nil {
+				return errObj
+			}
+			values.Del(key.Value)
+			for _, v := range vs {
+				values.Add(key.Value, v)
+			}
+		}
+		sort.Slice(files, func(i, j int) bool { return files[i].Field < files[j].Field })
+	}
+
+	req := &fetch.Request{Method: form.Method}
+	switch {
+	case form.Method == "GET":
+		// GET 表单的字段替换 action 中原有的查询串
+		action.RawQuery = ""
+		req.Query = values
+	case form.Enctype == "multipart/form-data":
+		req.Form, req.Files, req.Multipart = values, files, true
+	case form.Enctype == "text/plain":
+		var body strings.Builder
+		for _, k := range sortedKeys(values) {
+			for _, v := range values[k] {
+				body.WriteString(k + "=" + v + "\r\n")
+			}
+		}
+		req.Body = []byte(body.String())
+		req.Header = map[string]string{"Content-Type": "text/plain; charset=utf-8"}
+	default:
+		req.Form = values
+	}
+	if len(files) > 0 && !req.Multipart {
+		return builtinError(errors.RuntimeError, "submit: files require a multipart/form-data form")
+	}
+	req.URL = action.String()
+
+	resp, err := rt.Fetcher.Do(rt.Context, req)
+	if err != nil {
+		return builtinError(errors.NetworkError, "submit %s: %v", req.URL, err)
+	}
+	return newHTTPResponse(resp)
+}
+
+func sortedKeys(values url.Values) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,87 @@
+package eval
+
+import (
+	"context"
+	"sync"
+
+	"github.com/btrobot/mydsl/crawler/fetch"
+	"github.com/btrobot/mydsl/crawler/frontier"
+)
+
+// Runtime 保存解释器执行期间共享的状态，例如上下文和网页抓取器
+type Runtime struct {
+	Context context.Context
+	Fetcher *fetch.Fetcher
+
+	// Checkpoint 不为 nil 时 crawl 的状态写入该检查点，并从中恢复上次未完成的爬取
+	Checkpoint *frontier.Checkpoint
+
+	crawls int // 已开始的 crawl 数量，用作检查点中的任务编号
+
+	session  string                    // 当前会话名称，空字符串表示 DefaultSession
+	sessions map[string]*fetch.Fetcher // 已使用过的会话的抓取器
+}
+
+// DefaultSession 是初始会话的名称，使用创建运行时时传入的抓取器
+const DefaultSession = "default"
+
+// NewRuntime 创建新的运行时
+func NewRuntime(ctx context.Context, fetcher *fetch.Fetcher) *Runtime {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if fetcher == nil {
+		fetcher = fetch.NewFetcher(fetch.DefaultOptions())
+	}
+	return &Runtime{Context: ctx, Fetcher: fetcher}
+}
+
+var (
+	defaultRuntime     *Runtime
+	defaultRuntimeOnce sync.Once
+)
+
+// DefaultRuntime 返回未显式设置运行时的环境所使用的默认运行时
+func DefaultRuntime() *Runtime {
+	defaultRuntimeOnce.Do(func() {
+		defaultRuntime = NewRuntime(context.Background(), nil)
+	})
+	return defaultRuntime
+}
+
+// nextCrawlID 返回下一个 crawl 在检查点中的任务编号
+// 编号按执行顺序从 1 开始，脚本重新运行时同一个 crawl 得到相同的编号
+func (rt *Runtime) nextCrawlID() int {
+	rt.crawls++
+	return rt.crawls
+}
+
+// Session 返回当前会话的名称
+func (rt *Runtime) Session() string {
+	if rt.session == "" {
+		return DefaultSession
+	}
+	return rt.session
+}
+
+// UseSession 切换到命名会话，之后的 open 和 crawl 使用该会话的 cookie，返回之前的会话名称
+// 会话第一次使用时创建有独立 cookie 容器的抓取器，与其他会话共享 robots.txt 缓存和限速
+func (rt *Runtime) UseSession(name string) string {
+	prev := rt.Session()
+	if name == prev {
+		return prev
+	}
+	if rt.sessions == nil {
+		rt.sessions = make(map[string]*fetch.Fetcher)
+	}
+	rt.sessions[prev] = rt.Fetcher
+
+	fetcher, ok := rt.sessions[name]
+	if !ok {
+		fetcher = rt.Fetcher.WithJar(fetch.NewCookieJar())
+		rt.sessions[name] = fetcher
+	}
+	rt.Fetcher = fetcher
+	rt.session = name
+	return prev
+}
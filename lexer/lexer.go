@@ -0,0 +1,368 @@
+package lexer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/btrobot/mydsl/token"
+)
+
+// Lexer 表示词法分析器，将 UTF-8 源码转换为词法单元流
+type Lexer struct {
+	input        string
+	position     int  // 当前字符的字节偏移
+	readPosition int  // 下一个字符的字节偏移
+	ch           rune // 当前字符，0 表示输入结束
+	line         int  // 当前字符所在行号（从 1 开始）
+	column       int  // 当前字符所在列号（从 1 开始，按字符计数）
+}
+
+// New 创建新的词法分析器
+func New(input string) *Lexer {
+	l := &Lexer{input: input, line: 1}
+	l.readChar()
+	return l
+}
+
+// readChar 读取下一个字符并更新行列号
+func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+
+	if l.readPosition >= len(l.input) {
+		l.ch = 0
+		l.position = len(l.input)
+		l.readPosition = len(l.input) + 1
+		l.column++
+		return
+	}
+
+	r, size := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	l.ch = r
+	l.position = l.readPosition
+	l.readPosition += size
+	l.column++
+}
+
+// peekChar 查看下一个字符但不移动位置
+func (l *Lexer) peekChar() rune {
+	if l.readPosition >= len(l.input) {
+		return 0
+	}
+	r, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	return r
+}
+
+// atEOF 判断当前是否已到达输入末尾
+func (l *Lexer) atEOF() bool {
+	return l.position >= len(l.input)
+}
+
+// NextToken 返回下一个词法单元
+func (l *Lexer) NextToken() token.Token {
+	if tok, ok := l.skipWhitespaceAndComments(); !ok {
+		return tok
+	}
+
+	line, col := l.line, l.column
+
+	if l.atEOF() {
+		return token.NewToken(token.EOF, "", line, col)
+	}
+
+	var tok token.Token
+
+	switch l.ch {
+	case '=':
+		tok = l.twoCharToken(token.ASSIGN, map[rune]token.TokenType{'=': token.EQ, '>': token.ARROW}, line, col)
+	case '!':
+		tok = l.twoCharToken(token.BANG, map[rune]token.TokenType{'=': token.NOT_EQ}, line, col)
+	case '<':
+		tok = l.twoCharToken(token.LT, map[rune]token.TokenType{'=': token.LTE}, line, col)
+	case '>':
+		tok = l.twoCharToken(token.GT, map[rune]token.TokenType{'=': token.GTE}, line, col)
+	case '|':
+		tok = l.twoCharToken(token.PIPE, map[rune]token.TokenType{'|': token.OR}, line, col)
+	case '&':
+		tok = l.twoCharToken(token.ILLEGAL, map[rune]token.TokenType{'&': token.AND}, line, col)
+	case '?':
+		tok = l.twoCharToken(token.QUESTION, map[rune]token.TokenType{'?': token.NULL_COALESCE, '.': token.OPTIONAL_DOT}, line, col)
+	case '+':
+		tok = token.NewToken(token.PLUS, "+", line, col)
+	case '-':
+		tok = token.NewToken(token.MINUS, "-", line, col)
+	case '*':
+		tok = token.NewToken(token.ASTERISK, "*", line, col)
+	case '/':
+		tok = token.NewToken(token.SLASH, "/", line, col)
+	case '%':
+		tok = token.NewToken(token.PERCENT, "%", line, col)
+	case ',':
+		tok = token.NewToken(token.COMMA, ",", line, col)
+	case ';':
+		tok = token.NewToken(token.SEMICOLON, ";", line, col)
+	case ':':
+		tok = token.NewToken(token.COLON, ":", line, col)
+	case '(':
+		tok = token.NewToken(token.LPAREN, "(", line, col)
+	case ')':
+		tok = token.NewToken(token.RPAREN, ")", line, col)
+	case '{':
+		tok = token.NewToken(token.LBRACE, "{", line, col)
+	case '}':
+		tok = token.NewToken(token.RBRACE, "}", line, col)
+	case '[':
+		tok = token.NewToken(token.LBRACKET, "[", line, col)
+	case ']':
+		tok = token.NewToken(token.RBRACKET, "]", line, col)
+	case '@':
+		tok = token.NewToken(token.AT, "@", line, col)
+	case '.':
+		tok = token.NewToken(token.DOT, ".", line, col)
+	case '"', '\'':
+		return l.readString(line, col)
+	case '$':
+		if isLetter(l.peekChar()) {
+			l.readChar()
+			ident := l.readIdentifier()
+			return token.NewToken(token.SYSVAR, "$"+ident, line, col)
+		}
+		tok = token.NewToken(token.ILLEGAL, "$", line, col)
+	default:
+		if isLetter(l.ch) {
+			ident := l.readIdentifier()
+			return token.NewToken(token.LookupIdent(ident), ident, line, col)
+		}
+		if isDigit(l.ch) {
+			return l.readNumber(line, col)
+		}
+		tok = token.NewToken(token.ILLEGAL, string(l.ch), line, col)
+	}
+
+	l.readChar()
+	return tok
+}
+
+// Tokenize 返回输入中的全部词法单元（包含末尾的 EOF）
+func (l *Lexer) Tokenize() []token.Token {
+	var tokens []token.Token
+	for {
+		tok := l.NextToken()
+		tokens = append(tokens, tok)
+		if tok.Type == token.EOF {
+			return tokens
+		}
+	}
+}
+
+// twoCharToken 处理可能由两个字符组成的运算符
+// 若下一个字符出现在 next 中则生成双字符词法单元，否则生成 single 类型的单字符词法单元
+func (l *Lexer) twoCharToken(single token.TokenType, next map[rune]token.TokenType, line, col int) token.Token {
+	first := l.ch
+	if tt, ok := next[l.peekChar()]; ok {
+		l.readChar()
+		return token.NewToken(tt, string(first)+string(l.ch), line, col)
+	}
+	return token.NewToken(single, string(first), line, col)
+}
+
+// skipWhitespaceAndComments 跳过空白、// 和 # 行注释以及 /* */ 块注释
+// 块注释未闭合时返回位于 /* 处的 ILLEGAL 词法单元和 false
+func (l *Lexer) skipWhitespaceAndComments() (token.Token, bool) {
+	for !l.atEOF() {
+		switch {
+		case unicode.IsSpace(l.ch):
+			l.readChar()
+		case l.ch == '#', l.ch == '/' && l.peekChar() == '/':
+			for !l.atEOF() && l.ch != '\n' {
+				l.readChar()
+			}
+		case l.ch == '/' && l.peekChar() == '*':
+			line, col := l.line, l.column
+			l.readChar()
+			l.readChar()
+			for !l.atEOF() && !(l.ch == '*' && l.peekChar() == '/') {
+				l.readChar()
+			}
+			if l.atEOF() {
+				return token.NewToken(token.ILLEGAL, "unterminated block comment", line, col), false
+			}
+			l.readChar()
+			l.readChar()
+		default:
+			return token.Token{}, true
+		}
+	}
+	return token.Token{}, true
+}
+
+// readIdentifier 读取标识符
+func (l *Lexer) readIdentifier() string {
+	start := l.position
+	for isLetter(l.ch) || isDigit(l.ch) {
+		l.readChar()
+	}
+	return l.input[start:l.position]
+}
+
+// readNumber 读取整数或浮点数字面量
+func (l *Lexer) readNumber(line, col int) token.Token {
+	start := l.position
+	tokType := token.TokenType(token.INT)
+
+	for isDigit(l.ch) {
+		l.readChar()
+	}
+
+	// 小数部分：只有小数点后紧跟数字时才视为浮点数，避免与成员访问冲突
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		tokType = token.FLOAT
+		l.readChar()
+		for isDigit(l.ch) {
+			l.readChar()
+		}
+	}
+
+	// 指数部分
+	if l.ch == 'e' || l.ch == 'E' {
+		next := l.peekChar()
+		if isDigit(next) || ((next == '+' || next == '-') && l.peekDigitAfterSign()) {
+			tokType = token.FLOAT
+			l.readChar()
+			if l.ch == '+' || l.ch == '-' {
+				l.readChar()
+			}
+			for isDigit(l.ch) {
+				l.readChar()
+			}
+		}
+	}
+
+	literal := l.input[start:l.position]
+
+	// 数字后紧跟字母（如 123abc）是非法的
+	if isLetter(l.ch) {
+		for isLetter(l.ch) || isDigit(l.ch) {
+			l.readChar()
+		}
+		return token.NewToken(token.ILLEGAL, l.input[start:l.position], line, col)
+	}
+
+	return token.NewToken(tokType, literal, line, col)
+}
+
+// peekDigitAfterSign 判断指数符号后面是否跟着数字
+func (l *Lexer) peekDigitAfterSign() bool {
+	pos := l.readPosition
+	if pos >= len(l.input) {
+		return false
+	}
+	_, size := utf8.DecodeRuneInString(l.input[pos:])
+	pos += size
+	if pos >= len(l.input) {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(l.input[pos:])
+	return isDigit(r)
+}
+
+// readString 读取单引号或双引号字符串，并处理转义序列
+// 未闭合的字符串或非法转义会生成 ILLEGAL 词法单元
+func (l *Lexer) readString(line, col int) token.Token {
+	quote := l.ch
+	var out strings.Builder
+
+	l.readChar()
+	for {
+		if l.atEOF() || l.ch == '\n' {
+			return token.NewToken(token.ILLEGAL, "unterminated string", line, col)
+		}
+		if l.ch == quote {
+			l.readChar()
+			return token.NewToken(token.STRING, out.String(), line, col)
+		}
+		if l.ch == '\\' {
+			l.readChar()
+			r, ok := l.readEscape()
+			if !ok {
+				return l.skipBadString(quote, line, col)
+			}
+			out.WriteRune(r)
+			continue
+		}
+		out.WriteRune(l.ch)
+		l.readChar()
+	}
+}
+
+// readEscape 读取转义序列，调用时当前字符为反斜杠后的第一个字符
+func (l *Lexer) readEscape() (rune, bool) {
+	var r rune
+	switch l.ch {
+	case 'n':
+		r = '\n'
+	case 't':
+		r = '\t'
+	case 'r':
+		r = '\r'
+	case '0':
+		r = 0
+	case '\\':
+		r = '\\'
+	case '"', '\'':
+		r = l.ch
+	case 'u':
+		// \uXXXX
+		var code rune
+		for i := 0; i < 4; i++ {
+			l.readChar()
+			d, ok := hexValue(l.ch)
+			if !ok {
+				return 0, false
+			}
+			code = code*16 + d
+		}
+		r = code
+	default:
+		return 0, false
+	}
+	l.readChar()
+	return r, true
+}
+
+// skipBadString 跳过包含非法转义的字符串剩余部分，并返回 ILLEGAL 词法单元
+func (l *Lexer) skipBadString(quote rune, line, col int) token.Token {
+	for !l.atEOF() && l.ch != '\n' && l.ch != quote {
+		if l.ch == '\\' {
+			l.readChar()
+		}
+		l.readChar()
+	}
+	if l.ch == quote {
+		l.readChar()
+	}
+	return token.NewToken(token.ILLEGAL, "invalid escape sequence in string", line, col)
+}
+
+func isLetter(ch rune) bool {
+	return ch == '_' || unicode.IsLetter(ch)
+}
+
+func isDigit(ch rune) bool {
+	return '0' <= ch && ch <= '9'
+}
+
+func hexValue(ch rune) (rune, bool) {
+	switch {
+	case '0' <= ch && ch <= '9':
+		return ch - '0', true
+	case 'a' <= ch && ch <= 'f':
+		return ch - 'a' + 10, true
+	case 'A' <= ch && ch <= 'F':
+		return ch - 'A' + 10, true
+	}
+	return 0, false
+}
@@ -0,0 +1,265 @@
+package lexer
+
+import (
+	"testing"
+
+	"github.com/btrobot/mydsl/token"
+)
+
+func TestNextToken(t *testing.T) {
+	input := `let five = 5;
+let pi = 3.14;
+let add = function(x, y) { x + y; };
+a == b != c <= d >= e && f || g;
+x ?? y?.z | extract(@"div.item") => $url;
+[1, 2]; {"k": 'v'} % !
+`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "five"},
+		{token.ASSIGN, "="},
+		{token.INT, "5"},
+		{token.SEMICOLON, ";"},
+		{token.LET, "let"},
+		{token.IDENT, "pi"},
+		{token.ASSIGN, "="},
+		{token.FLOAT, "3.14"},
+		{token.SEMICOLON, ";"},
+		{token.LET, "let"},
+		{token.IDENT, "add"},
+		{token.ASSIGN, "="},
+		{token.FUNCTION, "function"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.COMMA, ","},
+		{token.IDENT, "y"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "x"},
+		{token.PLUS, "+"},
+		{token.IDENT, "y"},
+		{token.SEMICOLON, ";"},
+		{token.RBRACE, "}"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.EQ, "=="},
+		{token.IDENT, "b"},
+		{token.NOT_EQ, "!="},
+		{token.IDENT, "c"},
+		{token.LTE, "<="},
+		{token.IDENT, "d"},
+		{token.GTE, ">="},
+		{token.IDENT, "e"},
+		{token.AND, "&&"},
+		{token.IDENT, "f"},
+		{token.OR, "||"},
+		{token.IDENT, "g"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.NULL_COALESCE, "??"},
+		{token.IDENT, "y"},
+		{token.OPTIONAL_DOT, "?."},
+		{token.IDENT, "z"},
+		{token.PIPE, "|"},
+		{token.EXTRACT, "extract"},
+		{token.LPAREN, "("},
+		{token.AT, "@"},
+		{token.STRING, "div.item"},
+		{token.RPAREN, ")"},
+		{token.ARROW, "=>"},
+		{token.SYSVAR, "$url"},
+		{token.SEMICOLON, ";"},
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.COMMA, ","},
+		{token.INT, "2"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
+		{token.LBRACE, "{"},
+		{token.STRING, "k"},
+		{token.COLON, ":"},
+		{token.STRING, "v"},
+		{token.RBRACE, "}"},
+		{token.PERCENT, "%"},
+		{token.BANG, "!"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - token type wrong. expected=%q, got=%q (%s)",
+				i, tt.expectedType, tok.Type, tok)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestPositions(t *testing.T) {
+	input := "let x = 1;\n  // 注释\n  名字 = \"值\";\n\ty >= 2.5"
+
+	tests := []struct {
+		expectedType token.TokenType
+		line         int
+		column       int
+	}{
+		{token.LET, 1, 1},
+		{token.IDENT, 1, 5},
+		{token.ASSIGN, 1, 7},
+		{token.INT, 1, 9},
+		{token.SEMICOLON, 1, 10},
+		{token.IDENT, 3, 3},
+		{token.ASSIGN, 3, 6},
+		{token.STRING, 3, 8},
+		{token.SEMICOLON, 3, 11},
+		{token.IDENT, 4, 2},
+		{token.GTE, 4, 4},
+		{token.FLOAT, 4, 7},
+		{token.EOF, 4, 10},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - token type wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Line != tt.line || tok.Column != tt.column {
+			t.Errorf("tests[%d] - position wrong. expected=%d:%d, got=%d:%d",
+				i, tt.line, tt.column, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{"42", token.INT, "42"},
+		{"0.5", token.FLOAT, "0.5"},
+		{"1e10", token.FLOAT, "1e10"},
+		{"2.5E-3", token.FLOAT, "2.5E-3"},
+		{"12abc", token.ILLEGAL, "12abc"},
+	}
+
+	for i, tt := range tests {
+		tok := New(tt.input).NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Errorf("tests[%d] - token type wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Errorf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+
+	// 整数后的成员访问不应被当作浮点数
+	toks := New("arr.1.x").Tokenize()
+	if toks[1].Type != token.DOT || toks[2].Type != token.INT || toks[3].Type != token.DOT {
+		t.Errorf("unexpected tokens for member access: %v", toks)
+	}
+}
+
+func TestStrings(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{`"hello world"`, token.STRING, "hello world"},
+		{`'single'`, token.STRING, "single"},
+		{`"a\nb\t\"c\""`, token.STRING, "a\nb\t\"c\""},
+		{`'it\'s'`, token.STRING, "it's"},
+		{`"中文"`, token.STRING, "中文"},
+		{`"unterminated`, token.ILLEGAL, "unterminated string"},
+		{`"bad \q escape"`, token.ILLEGAL, "invalid escape sequence in string"},
+	}
+
+	for i, tt := range tests {
+		tok := New(tt.input).NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Errorf("tests[%d] - token type wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Errorf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestIllegal(t *testing.T) {
+	toks := New("a & b ~").Tokenize()
+
+	expected := []struct {
+		tokenType token.TokenType
+		literal   string
+		column    int
+	}{
+		{token.IDENT, "a", 1},
+		{token.ILLEGAL, "&", 3},
+		{token.IDENT, "b", 5},
+		{token.ILLEGAL, "~", 7},
+		{token.EOF, "", 8},
+	}
+
+	if len(toks) != len(expected) {
+		t.Fatalf("wrong number of tokens. expected=%d, got=%d", len(expected), len(toks))
+	}
+
+	for i, tt := range expected {
+		if toks[i].Type != tt.tokenType || toks[i].Literal != tt.literal || toks[i].Column != tt.column {
+			t.Errorf("tokens[%d] wrong. expected=(%s, %q, col %d), got=%s",
+				i, tt.tokenType, tt.literal, tt.column, toks[i])
+		}
+	}
+}
+
+func TestUnterminatedBlockComment(t *testing.T) {
+	toks := New("x /* ok */ y\n  /* never closed\nz").Tokenize()
+
+	expected := []struct {
+		tokenType token.TokenType
+		literal   string
+		line      int
+		column    int
+	}{
+		{token.IDENT, "x", 1, 1},
+		{token.IDENT, "y", 1, 12},
+		{token.ILLEGAL, "unterminated block comment", 2, 3},
+		{token.EOF, "", 3, 2},
+	}
+
+	if len(toks) != len(expected) {
+		t.Fatalf("wrong number of tokens. expected=%d, got=%d: %v", len(expected), len(toks), toks)
+	}
+
+	for i, tt := range expected {
+		if toks[i].Type != tt.tokenType || toks[i].Literal != tt.literal || toks[i].Line != tt.line || toks[i].Column != tt.column {
+			t.Errorf("tokens[%d] wrong. expected=(%s, %q, %d:%d), got=%s",
+				i, tt.tokenType, tt.literal, tt.line, tt.column, toks[i])
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/btrobot/mydsl/ast"
+	"github.com/btrobot/mydsl/crawler/selector"
+	"github.com/btrobot/mydsl/errors"
+	"github.com/btrobot/mydsl/token"
+)
+
+// parseCrawlerArguments 解析爬虫操作的参数列表，调用时 curToken 为操作关键字
+func (p *Parser) parseCrawlerArguments() []ast.Expression {
+	name := p.curToken.Literal
+	if !p.peekTokenIs(token.LPAREN) {
+		p.errorAt(p.peekToken, "expected '(' after %s, got %s instead", name, describe(p.peekToken))
+		return nil
+	}
+	p.nextToken()
+	return p.parseExpressionList(token.RPAREN)
+}
+
+// parseOpenExpression 解析 open(url) 或带请求选项的 open(url, {method: "POST", ...})
+// 在管道中可以省略 URL，此时使用管道左侧的值：url | open() 或 url | open(opts)；
+// 唯一的参数不是对象字面量时放在 URL 中，由求值器按值的类型区分 URL 与选项
+func (p *Parser) parseOpenExpression() ast.Expression {
+	exp := &ast.OpenExpression{Token: p.curToken}
+
+	args := p.parseCrawlerArguments()
+	if args == nil {
+		return nil
+	}
+
+	switch len(args) {
+	case 0:
+	case 1:
+		// 对象字面量一定是选项，其余表达式在求值时判断
+		if _, ok := args[0].(*ast.ObjectLiteral); ok {
+			exp.Options = args[0]
+		} else {
+			exp.URL = args[0]
+		}
+	case 2:
+		exp.URL, exp.Options = args[0], args[1]
+	default:
+		p.errorAt(exp.Token, "open expects at most 2 arguments, got %d", len(args))
+		return nil
+	}
+
+	return exp
+}
+
+// parseExtractExpression 解析 extract(source, @selector) 或管道形式的 extract(@selector)
+func (p *Parser) parseExtractExpression() ast.Expression {
+	exp := &ast.ExtractExpression{Token: p.curToken}
+
+	args := p.parseCrawlerArguments()
+	if args == nil {
+		return nil
+	}
+
+	switch len(args) {
+	case 1:
+		exp.Selector = args[0]
+	case 2:
+		exp.Source = args[0]
+		exp.Selector = args[1]
+	default:
+		p.errorAt(exp.Token, "extract expects 1 or 2 arguments, got %d", len(args))
+		return nil
+	}
+
+	return exp
+}
+
+// parseCollectExpression 解析 collect(source, @a, @b, ...)
+// 若第一个参数本身是选择器（@ 表达式或 {name: @sel} 形式的对象字面量），则视为省略了数据源的管道形式
+func (p *Parser) parseCollectExpression() ast.Expression {
+	exp := &ast.CollectExpression{Token: p.curToken}
+
+	args := p.parseCrawlerArguments()
+	if args == nil {
+		return nil
+	}
+
+	if len(args) > 0 && !isSelectorArgument(args[0]) {
+		exp.Source = args[0]
+		args = args[1:]
+	}
+
+	if len(args) == 0 {
+		p.errorAt(exp.Token, "collect expects at least one selector")
+		return nil
+	}
+
+	exp.Selectors = args
+	return exp
+}
+
+// parseCrawlExpression 解析 crawl(seeds, {options}, fn(page) {...})
+// 选项可以省略；第一个参数是对象字面量或只有回调时视为省略了种子的管道形式：seeds | crawl({...}, fn)
+// 两个参数且第一个不是对象字面量时放在 Seeds 中，由求值器按值的类型区分种子与选项
+func (p *Parser) parseCrawlExpression() ast.Expression {
+	exp := &ast.CrawlExpression{Token: p.curToken}
+
+	args := p.parseCrawlerArguments()
+	if args == nil {
+		return nil
+	}
+
+	switch len(args) {
+	case 1:
+		exp.Callback = args[0]
+	case 2:
+		if _, ok := args[0].(*ast.ObjectLiteral); ok {
+			exp.Options = args[0]
+		} else {
+			exp.Seeds = args[0]
+		}
+		exp.Callback = args[1]
+	case 3:
+		exp.Seeds, exp.Options, exp.Callback = args[0], args[1], args[2]
+	default:
+		p.errorAt(exp.Token, "crawl expects 1 to 3 arguments, got %d", len(args))
+		return nil
+	}
+
+	return exp
+}
+
+// isSelectorArgument 判断参数是否在语法上明确是选择器
+func isSelectorArgument(exp ast.Expression) bool {
+	switch exp.(type) {
+	case *ast.AtExpression, *ast.ObjectLiteral:
+		return true
+	}
+	return false
+}
+
+// parseAtExpression 解析选择器表达式：@"css"、@expr 或带语言前缀的 @xpath:"..."
+func (p *Parser) parseAtExpression() ast.Expression {
+	exp := &ast.AtExpression{Token: p.curToken}
+
+	p.nextToken()
+	if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.COLON) && isSelectorKind(p.curToken.Literal) {
+		exp.Kind = p.curToken.Literal
+		p.nextToken()
+		p.nextToken()
+	}
+
+	if p.curTokenIs(token.STRING) {
+		exp.Selector = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+		p.checkSelectorSuffix(exp)
+		return exp
+	}
+
+	exp.Selector = p.parseExpression(PREFIX)
+	if exp.Selector == nil {
+		return nil
+	}
+
+	return exp
+}
+
+// checkSelectorSuffix 检查字面量选择器末尾的 ::text、::attr(name) 等后缀，
+// 未知后缀在解析阶段报告为选择器错误
+func (p *Parser) checkSelectorSuffix(exp *ast.AtExpression) {
+	lit := exp.Selector.(*ast.StringLiteral)
+	value := lit.Value
+	if exp.Kind != "" {
+		value = exp.Kind + ":" + value
+	}
+
+	if _, _, err := selector.ParseSuffix(value); err != nil {
+		tok := lit.Token
+		p.errors = append(p.errors, errors.NewSelectorError(fmt.Sprint(err), tok.Line, tok.Column))
+	}
+}
+
+// isSelectorKind 判断标识符是否为选择器语言前缀，例如 @xpath:"//a"
+func isSelectorKind(name string) bool {
+	return name == "xpath" || name == "css"
+}
+
+// parsePipeExpression 解析左结合的管道表达式 left | right
+func (p *Parser) parsePipeExpression(left ast.Expression) ast.Expression {
+	exp := &ast.PipeExpression{Token: p.curToken, Left: left}
+
+	precedence := p.curPrecedence()
+	p.nextToken()
+	stageTok := p.curToken
+	exp.Right = p.parseExpression(precedence)
+	if exp.Right == nil {
+		return nil
+	}
+
+	switch exp.Right.(type) {
+	case *ast.CallExpression, *ast.OpenExpression, *ast.ExtractExpression, *ast.CollectExpression,
+		*ast.CrawlExpression, *ast.Identifier, *ast.MemberExpression, *ast.FunctionLiteral:
+	default:
+		p.errorAt(stageTok, "invalid pipeline stage %s", exp.Right.String())
+		return nil
+	}
+
+	return exp
+}
@@ -0,0 +1,896 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/btrobot/mydsl/ast"
+	"github.com/btrobot/mydsl/errors"
+	"github.com/btrobot/mydsl/lexer"
+	"github.com/btrobot/mydsl/token"
+)
+
+// 运算符优先级，从低到高
+const (
+	_ int = iota
+	LOWEST
+	ASSIGN      // =
+	PIPE        // |
+	TERNARY     // ?:
+	COALESCE    // ??
+	OR          // ||
+	AND         // &&
+	EQUALS      // == !=
+	LESSGREATER // < > <= >=
+	SUM         // + -
+	PRODUCT     // * / %
+	PREFIX      // -x !x
+	CALL        // f(x) a[i] a.b
+)
+
+// precedences 记录中缀运算符的优先级
+var precedences = map[token.TokenType]int{
+	token.ASSIGN:        ASSIGN,
+	token.PIPE:          PIPE,
+	token.QUESTION:      TERNARY,
+	token.NULL_COALESCE: COALESCE,
+	token.OR:            OR,
+	token.AND:           AND,
+	token.EQ:            EQUALS,
+	token.NOT_EQ:        EQUALS,
+	token.LT:            LESSGREATER,
+	token.GT:            LESSGREATER,
+	token.LTE:           LESSGREATER,
+	token.GTE:           LESSGREATER,
+	token.PLUS:          SUM,
+	token.MINUS:         SUM,
+	token.ASTERISK:      PRODUCT,
+	token.SLASH:         PRODUCT,
+	token.PERCENT:       PRODUCT,
+	token.LPAREN:        CALL,
+	token.LBRACKET:      CALL,
+	token.DOT:           CALL,
+	token.OPTIONAL_DOT:  CALL,
+}
+
+// builtinKeywords 是可以作为普通标识符使用的关键字（内置函数名）
+var builtinKeywords = []token.TokenType{
+	token.KEYS, token.VALUES, token.LENGTH, token.DELETE,
+	token.LOG, token.DEBUG, token.INFO, token.WARN, token.ERROR,
+}
+
+type (
+	prefixParseFn func() ast.Expression
+	infixParseFn  func(ast.Expression) ast.Expression
+)
+
+// Parser 表示语法分析器，使用 Pratt 算法将词法单元流转换为 AST
+type Parser struct {
+	tokens []token.Token
+	pos    int
+
+	curToken  token.Token
+	peekToken token.Token
+
+	errors []*errors.Error
+
+	prefixParseFns map[token.TokenType]prefixParseFn
+	infixParseFns  map[token.TokenType]infixParseFn
+}
+
+// New 创建新的语法分析器
+func New(l *lexer.Lexer) *Parser {
+	p := &Parser{
+		tokens:         l.Tokenize(),
+		errors:         []*errors.Error{},
+		prefixParseFns: make(map[token.TokenType]prefixParseFn),
+		infixParseFns:  make(map[token.TokenType]infixParseFn),
+	}
+
+	p.registerPrefix(token.IDENT, p.parseIdentifier)
+	p.registerPrefix(token.SYSVAR, p.parseIdentifier)
+	for _, tt := range builtinKeywords {
+		p.registerPrefix(tt, p.parseIdentifier)
+	}
+	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
+	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.TRUE, p.parseBooleanLiteral)
+	p.registerPrefix(token.FALSE, p.parseBooleanLiteral)
+	p.registerPrefix(token.NULL, p.parseNullLiteral)
+	p.registerPrefix(token.BANG, p.parsePrefixExpression)
+	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
+	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
+	p.registerPrefix(token.LBRACE, p.parseObjectLiteral)
+	p.registerPrefix(token.IF, p.parseIfExpression)
+	p.registerPrefix(token.WHILE, p.parseWhileExpression)
+	p.registerPrefix(token.FOR, p.parseForExpression)
+	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(token.ILLEGAL, p.parseIllegal)
+	p.registerPrefix(token.OPEN, p.parseOpenExpression)
+	p.registerPrefix(token.EXTRACT, p.parseExtractExpression)
+	p.registerPrefix(token.COLLECT, p.parseCollectExpression)
+	p.registerPrefix(token.CRAWL, p.parseCrawlExpression)
+	p.registerPrefix(token.AT, p.parseAtExpression)
+
+	for _, tt := range []token.TokenType{
+		token.PLUS, token.MINUS, token.ASTERISK, token.SLASH, token.PERCENT,
+		token.EQ, token.NOT_EQ, token.LT, token.GT, token.LTE, token.GTE,
+		token.AND, token.OR, token.NULL_COALESCE,
+	} {
+		p.registerInfix(tt, p.parseInfixExpression)
+	}
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.QUESTION, p.parseConditionalExpression)
+	p.registerInfix(token.PIPE, p.parsePipeExpression)
+	p.registerInfix(token.LPAREN, p.parseCallExpression)
+	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.DOT, p.parseMemberExpression)
+	p.registerInfix(token.OPTIONAL_DOT, p.parseMemberExpression)
+
+	// 读取两个词法单元，设置 curToken 和 peekToken
+	p.nextToken()
+	p.nextToken()
+
+	return p
+}
+
+// Errors 返回解析过程中收集到的语法错误
+func (p *Parser) Errors() []*errors.Error {
+	return p.errors
+}
+
+func (p *Parser) registerPrefix(tokenType token.TokenType, fn prefixParseFn) {
+	p.prefixParseFns[tokenType] = fn
+}
+
+func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
+	p.infixParseFns[tokenType] = fn
+}
+
+// nextToken 前进一个词法单元
+func (p *Parser) nextToken() {
+	p.curToken = p.peekToken
+	p.peekToken = p.tokenAt(p.pos)
+	p.pos++
+}
+
+// tokenAt 返回缓冲区中指定下标的词法单元，越界时返回最后的 EOF
+func (p *Parser) tokenAt(i int) token.Token {
+	if i < len(p.tokens) {
+		return p.tokens[i]
+	}
+	return p.tokens[len(p.tokens)-1]
+}
+
+func (p *Parser) curTokenIs(t token.TokenType) bool {
+	return p.curToken.Type == t
+}
+
+func (p *Parser) peekTokenIs(t token.TokenType) bool {
+	return p.peekToken.Type == t
+}
+
+// expectPeek 若下一个词法单元类型符合预期则前进，否则记录错误
+func (p *Parser) expectPeek(t token.TokenType) bool {
+	if p.peekTokenIs(t) {
+		p.nextToken()
+		return true
+	}
+	p.peekError(t)
+	return false
+}
+
+func (p *Parser) peekPrecedence() int {
+	if p, ok := precedences[p.peekToken.Type]; ok {
+		return p
+	}
+	return LOWEST
+}
+
+func (p *Parser) curPrecedence() int {
+	if p, ok := precedences[p.curToken.Type]; ok {
+		return p
+	}
+	return LOWEST
+}
+
+// errorAt 在指定词法单元的位置记录语法错误
+func (p *Parser) errorAt(tok token.Token, format string, args ...interface{}) {
+	p.errors = append(p.errors, errors.NewSyntaxError(fmt.Sprintf(format, args...), tok.Line, tok.Column))
+}
+
+func (p *Parser) peekError(t token.TokenType) {
+	p.errorAt(p.peekToken, "expected next token to be %s, got %s instead", t, describe(p.peekToken))
+}
+
+// describe 返回便于阅读的词法单元描述
+func describe(tok token.Token) string {
+	switch tok.Type {
+	case token.EOF:
+		return "end of input"
+	case token.IDENT, token.INT, token.FLOAT, token.STRING, token.SYSVAR:
+		return fmt.Sprintf("%s %q", tok.Type, tok.Literal)
+	}
+	return fmt.Sprintf("%q", tok.Literal)
+}
+
+// ParseProgram 解析整个程序
+func (p *Parser) ParseProgram() *ast.Program {
+	program := &ast.Program{Statements: []ast.Statement{}}
+
+	for !p.curTokenIs(token.EOF) {
+		errCount := len(p.errors)
+		stmt := p.parseStatement()
+		if len(p.errors) > errCount {
+			p.synchronize()
+			continue
+		}
+		if stmt != nil {
+			program.Statements = append(program.Statements, stmt)
+		}
+		p.nextToken()
+	}
+
+	return program
+}
+
+// synchronize 在出现语法错误后跳过当前语句的剩余部分，避免产生连锁错误
+func (p *Parser) synchronize() {
+	line := p.curToken.Line
+	for !p.curTokenIs(token.EOF) {
+		if p.curTokenIs(token.SEMICOLON) {
+			p.nextToken()
+			return
+		}
+		if p.curToken.Line > line {
+			return
+		}
+		p.nextToken()
+	}
+}
+
+// parseStatement 解析单条语句
+func (p *Parser) parseStatement() ast.Statement {
+	switch p.curToken.Type {
+	case token.LET, token.CONST:
+		return p.parseLetStatement()
+	case token.RETURN:
+		return p.parseReturnStatement()
+	case token.BREAK:
+		stmt := &ast.BreakStatement{Token: p.curToken}
+		p.skipSemicolon()
+		return stmt
+	case token.CONTINUE:
+		stmt := &ast.ContinueStatement{Token: p.curToken}
+		p.skipSemicolon()
+		return stmt
+	case token.SEMICOLON:
+		return nil
+	default:
+		return p.parseExpressionStatement()
+	}
+}
+
+// skipSemicolon 跳过可选的语句结尾分号
+func (p *Parser) skipSemicolon() {
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+}
+
+// parseLetStatement 解析 let/const 声明语句
+func (p *Parser) parseLetStatement() ast.Statement {
+	stmt := &ast.LetStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Value = p.parseExpression(LOWEST)
+
+	p.skipSemicolon()
+	return stmt
+}
+
+// parseReturnStatement 解析 return 语句，返回值可省略
+func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
+	stmt := &ast.ReturnStatement{Token: p.curToken}
+
+	if p.peekTokenIs(token.SEMICOLON) || p.peekTokenIs(token.RBRACE) || p.peekTokenIs(token.EOF) {
+		p.skipSemicolon()
+		return stmt
+	}
+
+	p.nextToken()
+	stmt.ReturnValue = p.parseExpression(LOWEST)
+
+	p.skipSemicolon()
+	return stmt
+}
+
+// parseExpressionStatement 解析表达式语句
+func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
+	stmt := &ast.ExpressionStatement{Token: p.curToken}
+
+	stmt.Expression = p.parseExpression(LOWEST)
+
+	p.skipSemicolon()
+	return stmt
+}
+
+// parseBlockStatement 解析 { ... } 代码块，结束时 curToken 为 }
+func (p *Parser) parseBlockStatement() *ast.BlockStatement {
+	block := &ast.BlockStatement{Token: p.curToken, Statements: []ast.Statement{}}
+
+	p.nextToken()
+
+	for !p.curTokenIs(token.RBRACE) {
+		if p.curTokenIs(token.EOF) {
+			p.errorAt(block.Token, "unterminated block, missing '}'")
+			return block
+		}
+		stmt := p.parseStatement()
+		if stmt != nil {
+			block.Statements = append(block.Statements, stmt)
+		}
+		p.nextToken()
+	}
+
+	return block
+}
+
+// parseExpression 按优先级解析表达式
+func (p *Parser) parseExpression(precedence int) ast.Expression {
+	prefix := p.prefixParseFns[p.curToken.Type]
+	if prefix == nil {
+		p.noPrefixParseFnError(p.curToken)
+		return nil
+	}
+	leftExp := prefix()
+
+	for !p.peekTokenIs(token.SEMICOLON) && precedence < p.peekPrecedence() {
+		if leftExp == nil {
+			return nil
+		}
+		infix := p.infixParseFns[p.peekToken.Type]
+		if infix == nil {
+			return leftExp
+		}
+
+		p.nextToken()
+		leftExp = infix(leftExp)
+	}
+
+	return leftExp
+}
+
+func (p *Parser) noPrefixParseFnError(tok token.Token) {
+	if tok.Type == token.EOF {
+		p.errorAt(tok, "unexpected end of input")
+		return
+	}
+	p.errorAt(tok, "unexpected %s", describe(tok))
+}
+
+func (p *Parser) parseIllegal() ast.Expression {
+	p.errorAt(p.curToken, "illegal token: %s", p.curToken.Literal)
+	return nil
+}
+
+// parseIdentifier 解析标识符，若后面紧跟 => 则解析为单参数箭头函数
+func (p *Parser) parseIdentifier() ast.Expression {
+	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.ARROW) {
+		return p.parseArrowFunction(ident.Token, []*ast.Identifier{ident})
+	}
+
+	return ident
+}
+
+func (p *Parser) parseIntegerLiteral() ast.Expression {
+	lit := &ast.IntegerLiteral{Token: p.curToken}
+
+	value, err := strconv.ParseInt(p.curToken.Literal, 10, 64)
+	if err != nil {
+		p.errorAt(p.curToken, "could not parse %q as integer", p.curToken.Literal)
+		return nil
+	}
+
+	lit.Value = value
+	return lit
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken}
+
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if err != nil {
+		p.errorAt(p.curToken, "could not parse %q as float", p.curToken.Literal)
+		return nil
+	}
+
+	lit.Value = value
+	return lit
+}
+
+func (p *Parser) parseStringLiteral() ast.Expression {
+	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+}
+
+func (p *Parser) parseBooleanLiteral() ast.Expression {
+	return &ast.BooleanLiteral{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
+}
+
+func (p *Parser) parseNullLiteral() ast.Expression {
+	return &ast.NullLiteral{Token: p.curToken}
+}
+
+func (p *Parser) parsePrefixExpression() ast.Expression {
+	expression := &ast.PrefixExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+	}
+
+	p.nextToken()
+	expression.Right = p.parseExpression(PREFIX)
+	if expression.Right == nil {
+		return nil
+	}
+
+	return expression
+}
+
+func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
+	expression := &ast.InfixExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+		Left:     left,
+	}
+
+	precedence := p.curPrecedence()
+	p.nextToken()
+	expression.Right = p.parseExpression(precedence)
+	if expression.Right == nil {
+		return nil
+	}
+
+	return expression
+}
+
+// parseAssignExpression 解析右结合的赋值表达式
+func (p *Parser) parseAssignExpression(left ast.Expression) ast.Expression {
+	expression := &ast.AssignExpression{Token: p.curToken, Target: left}
+
+	switch left.(type) {
+	case *ast.Identifier, *ast.IndexExpression, *ast.MemberExpression:
+	default:
+		p.errorAt(p.curToken, "invalid assignment target %s", left.String())
+		return nil
+	}
+
+	p.nextToken()
+	expression.Value = p.parseExpression(ASSIGN - 1)
+	if expression.Value == nil {
+		return nil
+	}
+
+	return expression
+}
+
+// parseConditionalExpression 解析三元表达式 cond ? a : b
+func (p *Parser) parseConditionalExpression(condition ast.Expression) ast.Expression {
+	expression := &ast.ConditionalExpression{Token: p.curToken, Condition: condition}
+
+	p.nextToken()
+	expression.Consequence = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+
+	p.nextToken()
+	expression.Alternative = p.parseExpression(TERNARY - 1)
+	if expression.Consequence == nil || expression.Alternative == nil {
+		return nil
+	}
+
+	return expression
+}
+
+// parseGroupedExpression 解析括号表达式或括号形式的箭头函数参数列表
+func (p *Parser) parseGroupedExpression() ast.Expression {
+	if p.isArrowFunctionAhead() {
+		start := p.curToken
+		params := p.parseFunctionParameters()
+		if params == nil {
+			return nil
+		}
+		return p.parseArrowFunction(start, params)
+	}
+
+	p.nextToken()
+
+	exp := p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	return exp
+}
+
+// isArrowFunctionAhead 判断当前 ( 对应的 ) 之后是否紧跟 =>
+func (p *Parser) isArrowFunctionAhead() bool {
+	depth := 0
+	// curToken 位于缓冲区下标 p.pos-2 处
+	for i := p.pos - 2; i < len(p.tokens); i++ {
+		switch p.tokens[i].Type {
+		case token.LPAREN:
+			depth++
+		case token.RPAREN:
+			depth--
+			if depth == 0 {
+				return p.tokenAt(i+1).Type == token.ARROW
+			}
+		case token.EOF:
+			return false
+		}
+	}
+	return false
+}
+
+// parseArrowFunction 解析箭头函数主体，调用时 peekToken 为 =>
+func (p *Parser) parseArrowFunction(start token.Token, params []*ast.Identifier) ast.Expression {
+	fn := &ast.FunctionLiteral{
+		Token:      token.NewToken(token.FUNCTION, "function", start.Line, start.Column),
+		Parameters: params,
+	}
+
+	if !p.expectPeek(token.ARROW) {
+		return nil
+	}
+
+	if p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		fn.Body = p.parseBlockStatement()
+		return fn
+	}
+
+	p.nextToken()
+	bodyTok := p.curToken
+	value := p.parseExpression(ASSIGN)
+	if value == nil {
+		return nil
+	}
+
+	fn.Body = &ast.BlockStatement{
+		Token: bodyTok,
+		Statements: []ast.Statement{
+			&ast.ReturnStatement{
+				Token:       token.NewToken(token.RETURN, "return", bodyTok.Line, bodyTok.Column),
+				ReturnValue: value,
+			},
+		},
+	}
+
+	return fn
+}
+
+// parseArrayLiteral 解析数组字面量，允许末尾逗号
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	array := &ast.ArrayLiteral{Token: p.curToken}
+
+	array.Elements = p.parseExpressionList(token.RBRACKET)
+	if array.Elements == nil {
+		return nil
+	}
+
+	return array
+}
+
+// parseExpressionList 解析以逗号分隔、以 end 结尾的表达式列表
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	list := []ast.Expression{}
+
+	if p.peekTokenIs(end) {
+		p.nextToken()
+		return list
+	}
+
+	p.nextToken()
+	exp := p.parseExpression(LOWEST)
+	if exp == nil {
+		return nil
+	}
+	list = append(list, exp)
+
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if p.peekTokenIs(end) {
+			break
+		}
+		p.nextToken()
+		exp := p.parseExpression(LOWEST)
+		if exp == nil {
+			return nil
+		}
+		list = append(list, exp)
+	}
+
+	if !p.expectPeek(end) {
+		return nil
+	}
+
+	return list
+}
+
+// parseObjectLiteral 解析对象字面量，标识符形式的键被视为字符串
+func (p *Parser) parseObjectLiteral() ast.Expression {
+	object := &ast.ObjectLiteral{Token: p.curToken, Pairs: make(map[ast.Expression]ast.Expression)}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+
+		var key ast.Expression
+		if isIdentLike(p.curToken) && !isLiteralKeyword(p.curToken) && p.peekTokenIs(token.COLON) {
+			key = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+		} else {
+			key = p.parseExpression(LOWEST)
+			if key == nil {
+				return nil
+			}
+		}
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+
+		p.nextToken()
+		value := p.parseExpression(LOWEST)
+		if value == nil {
+			return nil
+		}
+
+		object.Pairs[key] = value
+		object.Keys = append(object.Keys, key)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return object
+}
+
+// isIdentLike 判断词法单元是否为标识符或关键字（可用作对象键和成员名）
+func isIdentLike(tok token.Token) bool {
+	if tok.Type == token.IDENT {
+		return true
+	}
+	return tok.Literal != "" && token.LookupIdent(tok.Literal) == tok.Type
+}
+
+// isLiteralKeyword 判断词法单元是否为 true、false、null 字面量
+func isLiteralKeyword(tok token.Token) bool {
+	switch tok.Type {
+	case token.TRUE, token.FALSE, token.NULL:
+		return true
+	}
+	return false
+}
+
+// parseIfExpression 解析 if/else if/else 表达式
+func (p *Parser) parseIfExpression() ast.Expression {
+	expression := &ast.IfExpression{Token: p.curToken}
+
+	p.nextToken()
+	expression.Condition = p.parseExpression(LOWEST)
+	if expression.Condition == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Consequence = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.ELSE) {
+		p.nextToken()
+
+		if p.peekTokenIs(token.IF) {
+			// else if 被表示为只包含一个 if 表达式的代码块
+			p.nextToken()
+			elseTok := p.curToken
+			nested := p.parseIfExpression()
+			if nested == nil {
+				return nil
+			}
+			expression.Alternative = &ast.BlockStatement{
+				Token: elseTok,
+				Statements: []ast.Statement{
+					&ast.ExpressionStatement{Token: elseTok, Expression: nested},
+				},
+			}
+			return expression
+		}
+
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+
+		expression.Alternative = p.parseBlockStatement()
+	}
+
+	return expression
+}
+
+// parseWhileExpression 解析 while 循环
+func (p *Parser) parseWhileExpression() ast.Expression {
+	expression := &ast.WhileExpression{Token: p.curToken}
+
+	p.nextToken()
+	expression.Condition = p.parseExpression(LOWEST)
+	if expression.Condition == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Body = p.parseBlockStatement()
+	return expression
+}
+
+// parseForExpression 解析 for x in iterable { ... }，循环头可以用括号包裹
+func (p *Parser) parseForExpression() ast.Expression {
+	expression := &ast.ForExpression{Token: p.curToken}
+
+	parenthesized := false
+	if p.peekTokenIs(token.LPAREN) && p.tokenAt(p.pos).Type == token.IDENT && p.tokenAt(p.pos+1).Type == token.IN {
+		parenthesized = true
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	expression.Identifier = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	p.nextToken()
+	expression.Iterable = p.parseExpression(LOWEST)
+	if expression.Iterable == nil {
+		return nil
+	}
+
+	if parenthesized && !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Body = p.parseBlockStatement()
+	return expression
+}
+
+// parseFunctionLiteral 解析 function [name](params) { body }
+func (p *Parser) parseFunctionLiteral() ast.Expression {
+	lit := &ast.FunctionLiteral{Token: p.curToken}
+
+	if p.peekTokenIs(token.IDENT) {
+		p.nextToken()
+		lit.Name = p.curToken.Literal
+	}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	lit.Parameters = p.parseFunctionParameters()
+	if lit.Parameters == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	lit.Body = p.parseBlockStatement()
+	return lit
+}
+
+// parseFunctionParameters 解析参数列表，调用时 curToken 为 (，结束时为 )
+func (p *Parser) parseFunctionParameters() []*ast.Identifier {
+	identifiers := []*ast.Identifier{}
+
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		return identifiers
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	identifiers = append(identifiers, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		identifiers = append(identifiers, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	return identifiers
+}
+
+func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
+	exp := &ast.CallExpression{Token: p.curToken, Function: function}
+
+	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	if exp.Arguments == nil {
+		return nil
+	}
+
+	return exp
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+
+	p.nextToken()
+	exp.Index = p.parseExpression(LOWEST)
+	if exp.Index == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return exp
+}
+
+// parseMemberExpression 解析 obj.name 与 obj?.name，成员名可以是关键字
+func (p *Parser) parseMemberExpression(object ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{
+		Token:    p.curToken,
+		Object:   object,
+		Optional: p.curTokenIs(token.OPTIONAL_DOT),
+	}
+
+	if !isIdentLike(p.peekToken) {
+		p.errorAt(p.peekToken, "expected property name after %q, got %s", p.curToken.Literal, describe(p.peekToken))
+		return nil
+	}
+
+	p.nextToken()
+	exp.Property = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	return exp
+}
@@ -0,0 +1,551 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/btrobot/mydsl/ast"
+	"github.com/btrobot/mydsl/errors"
+	"github.com/btrobot/mydsl/lexer"
+)
+
+func parseProgram(t *testing.T, input string) *ast.Program {
+	t.Helper()
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	return program
+}
+
+func checkParserErrors(t *testing.T, p *Parser) {
+	t.Helper()
+
+	errs := p.Errors()
+	if len(errs) == 0 {
+		return
+	}
+
+	t.Errorf("parser has %d errors", len(errs))
+	for _, err := range errs {
+		t.Errorf("parser error: %s", err.Error())
+	}
+	t.FailNow()
+}
+
+func TestLetStatements(t *testing.T) {
+	tests := []struct {
+		input              string
+		expectedIdentifier string
+		expectedValue      string
+	}{
+		{"let x = 5;", "x", "5"},
+		{"let y = true", "y", "true"},
+		{"const foobar = y;", "foobar", "y"},
+		{"let s = \"hi\";", "s", `"hi"`},
+	}
+
+	for _, tt := range tests {
+		program := parseProgram(t, tt.input)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d",
+				len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("stmt is not *ast.LetStatement. got=%T", program.Statements[0])
+		}
+
+		if stmt.Name.Value != tt.expectedIdentifier {
+			t.Errorf("stmt.Name.Value wrong. got=%q, want=%q", stmt.Name.Value, tt.expectedIdentifier)
+		}
+
+		if stmt.Value.String() != tt.expectedValue {
+			t.Errorf("stmt.Value wrong. got=%q, want=%q", stmt.Value.String(), tt.expectedValue)
+		}
+	}
+}
+
+func TestReturnStatements(t *testing.T) {
+	program := parseProgram(t, "return 5; return; return a + b;")
+
+	if len(program.Statements) != 3 {
+		t.Fatalf("program.Statements does not contain 3 statements. got=%d",
+			len(program.Statements))
+	}
+
+	expected := []string{"return 5;", "return ;", "return (a + b);"}
+	for i, stmt := range program.Statements {
+		if _, ok := stmt.(*ast.ReturnStatement); !ok {
+			t.Fatalf("stmt is not *ast.ReturnStatement. got=%T", stmt)
+		}
+		if stmt.String() != expected[i] {
+			t.Errorf("statements[%d] wrong. got=%q, want=%q", i, stmt.String(), expected[i])
+		}
+	}
+}
+
+func TestOperatorPrecedenceParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"-a * b", "((-a) * b);"},
+		{"!-a", "(!(-a));"},
+		{"a + b + c", "((a + b) + c);"},
+		{"a + b * c % d", "(a + ((b * c) % d));"},
+		{"a + b / c - d", "((a + (b / c)) - d);"},
+		{"5 > 4 == 3 < 4", "((5 > 4) == (3 < 4));"},
+		{"a <= b != c >= d", "((a <= b) != (c >= d));"},
+		{"a || b && c", "(a || (b && c));"},
+		{"a == b && c != d || e", "(((a == b) && (c != d)) || e);"},
+		{"a ?? b || c", "(a ?? (b || c));"},
+		{"(a + b) * c", "((a + b) * c);"},
+		{"a + add(b * c) + d", "((a + add((b * c))) + d);"},
+		{"add(a, b, 1, 2 * 3, add(6, 7 * 8))", "add(a, b, 1, (2 * 3), add(6, (7 * 8)));"},
+		{"a * [1, 2, 3, 4][b * c] * d", "((a * ([1, 2, 3, 4][(b * c)])) * d);"},
+		{"add(a * b[2], b[1], 2 * [1, 2][1])", "add((a * (b[2])), (b[1]), (2 * ([1, 2][1])));"},
+		{"a.b.c(1)", "a.b.c(1);"},
+		{"a?.b ?? c", "(a?.b ?? c);"},
+		{"x = y = 1 + 2", "x = y = (1 + 2);"},
+		{"a[0] = b.c", "(a[0]) = b.c;"},
+		{"a ? b : c ? d : e", "(a ? b : (c ? d : e));"},
+		{"a || b ? 1 : 2", "((a || b) ? 1 : 2);"},
+		{"length(keys(h))", "length(keys(h));"},
+		{"$url + 1", "($url + 1);"},
+	}
+
+	for _, tt := range tests {
+		program := parseProgram(t, tt.input)
+
+		actual := program.String()
+		if actual != tt.expected {
+			t.Errorf("input %q: expected=%q, got=%q", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestLiterals(t *testing.T) {
+	program := parseProgram(t, `5; 2.5; "str"; true; false; null;`)
+
+	if len(program.Statements) != 6 {
+		t.Fatalf("program.Statements does not contain 6 statements. got=%d",
+			len(program.Statements))
+	}
+
+	checks := []func(ast.Expression) bool{
+		func(e ast.Expression) bool { l, ok := e.(*ast.IntegerLiteral); return ok && l.Value == 5 },
+		func(e ast.Expression) bool { l, ok := e.(*ast.FloatLiteral); return ok && l.Value == 2.5 },
+		func(e ast.Expression) bool { l, ok := e.(*ast.StringLiteral); return ok && l.Value == "str" },
+		func(e ast.Expression) bool { l, ok := e.(*ast.BooleanLiteral); return ok && l.Value },
+		func(e ast.Expression) bool { l, ok := e.(*ast.BooleanLiteral); return ok && !l.Value },
+		func(e ast.Expression) bool { _, ok := e.(*ast.NullLiteral); return ok },
+	}
+
+	for i, check := range checks {
+		exp := program.Statements[i].(*ast.ExpressionStatement).Expression
+		if !check(exp) {
+			t.Errorf("statements[%d] has unexpected expression %T (%s)", i, exp, exp)
+		}
+	}
+}
+
+func TestIfExpression(t *testing.T) {
+	program := parseProgram(t, `if (x < y) { x } else if x > y { y } else { z }`)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not *ast.IfExpression. got=%T", stmt.Expression)
+	}
+
+	if exp.Condition.String() != "(x < y)" {
+		t.Errorf("condition wrong. got=%q", exp.Condition.String())
+	}
+
+	if len(exp.Consequence.Statements) != 1 {
+		t.Errorf("consequence is not 1 statement. got=%d", len(exp.Consequence.Statements))
+	}
+
+	nested, ok := exp.Alternative.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("else if is not *ast.IfExpression")
+	}
+
+	if nested.Alternative == nil {
+		t.Errorf("nested else block missing")
+	}
+}
+
+func TestLoops(t *testing.T) {
+	program := parseProgram(t, `
+while i < 10 { i = i + 1; if i == 5 { break; } continue }
+for item in items { log(item) }
+for (k in keys(h)) { k }
+`)
+
+	if len(program.Statements) != 3 {
+		t.Fatalf("program.Statements does not contain 3 statements. got=%d",
+			len(program.Statements))
+	}
+
+	while, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.WhileExpression)
+	if !ok {
+		t.Fatalf("statements[0] is not *ast.WhileExpression")
+	}
+	if len(while.Body.Statements) != 3 {
+		t.Errorf("while body wrong. got=%d statements", len(while.Body.Statements))
+	}
+	if _, ok := while.Body.Statements[2].(*ast.ContinueStatement); !ok {
+		t.Errorf("expected continue statement. got=%T", while.Body.Statements[2])
+	}
+
+	expected := []string{
+		"for item in items { log(item); }",
+		"for k in keys(h) { k; }",
+	}
+	for i, want := range expected {
+		fe, ok := program.Statements[i+1].(*ast.ExpressionStatement).Expression.(*ast.ForExpression)
+		if !ok {
+			t.Fatalf("statements[%d] is not *ast.ForExpression", i+1)
+		}
+		if fe.String() != want {
+			t.Errorf("for expression wrong. got=%q, want=%q", fe.String(), want)
+		}
+	}
+}
+
+func TestFunctionLiterals(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedName   string
+		expectedParams []string
+		expected       string
+	}{
+		{"function(x, y) { x + y; }", "", []string{"x", "y"}, "function(x, y) { (x + y); }"},
+		{"function add() { return 1 }", "add", []string{}, "function add() { return 1; }"},
+		{"(a, b) => a * b", "", []string{"a", "b"}, "function(a, b) { return (a * b); }"},
+		{"() => { 1 }", "", []string{}, "function() { 1; }"},
+		{"x => x + 1", "", []string{"x"}, "function(x) { return (x + 1); }"},
+	}
+
+	for _, tt := range tests {
+		program := parseProgram(t, tt.input)
+
+		fn, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+		if !ok {
+			t.Fatalf("input %q: expression is not *ast.FunctionLiteral", tt.input)
+		}
+
+		if fn.Name != tt.expectedName {
+			t.Errorf("input %q: name wrong. got=%q, want=%q", tt.input, fn.Name, tt.expectedName)
+		}
+
+		if len(fn.Parameters) != len(tt.expectedParams) {
+			t.Fatalf("input %q: parameter count wrong. got=%d, want=%d",
+				tt.input, len(fn.Parameters), len(tt.expectedParams))
+		}
+
+		for i, name := range tt.expectedParams {
+			if fn.Parameters[i].Value != name {
+				t.Errorf("input %q: parameter %d wrong. got=%q", tt.input, i, fn.Parameters[i].Value)
+			}
+		}
+
+		if fn.String() != tt.expected {
+			t.Errorf("input %q: String() wrong. got=%q, want=%q", tt.input, fn.String(), tt.expected)
+		}
+	}
+}
+
+func TestObjectLiteral(t *testing.T) {
+	program := parseProgram(t, `{name: "a", "age": 1 + 2, 3: true,}`)
+
+	obj, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.ObjectLiteral)
+	if !ok {
+		t.Fatalf("expression is not *ast.ObjectLiteral")
+	}
+
+	expected := map[string]string{
+		`"name"`: `"a"`,
+		`"age"`:  "(1 + 2)",
+		"3":      "true",
+	}
+
+	if len(obj.Pairs) != len(expected) {
+		t.Fatalf("wrong number of pairs. got=%d", len(obj.Pairs))
+	}
+
+	for key, value := range obj.Pairs {
+		want, ok := expected[key.String()]
+		if !ok {
+			t.Errorf("unexpected key %s", key.String())
+			continue
+		}
+		if value.String() != want {
+			t.Errorf("value for %s wrong. got=%q, want=%q", key.String(), value.String(), want)
+		}
+	}
+
+	// 键保持源码中的顺序
+	if want := `{"name": "a", "age": (1 + 2), 3: true}`; obj.String() != want {
+		t.Errorf("String() wrong. got=%q, want=%q", obj.String(), want)
+	}
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		input   string
+		message string
+		line    int
+		column  int
+	}{
+		{"let = 5;", "expected next token to be IDENT, got \"=\" instead", 1, 5},
+		{"let x 5;", "expected next token to be =, got INT \"5\" instead", 1, 7},
+		{"x +;", "unexpected \";\"", 1, 4},
+		{"\n  a & b", "illegal token: &", 2, 5},
+		{"f(1, 2", "expected next token to be ), got end of input instead", 1, 7},
+		{"1 = 2", "invalid assignment target 1", 1, 3},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errs := p.Errors()
+		if len(errs) == 0 {
+			t.Errorf("input %q: expected syntax error", tt.input)
+			continue
+		}
+
+		err := errs[0]
+		if err.Message != tt.message {
+			t.Errorf("input %q: message wrong. got=%q, want=%q", tt.input, err.Message, tt.message)
+		}
+		if err.Line != tt.line || err.Column != tt.column {
+			t.Errorf("input %q: position wrong. got=%d:%d, want=%d:%d",
+				tt.input, err.Line, err.Column, tt.line, tt.column)
+		}
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	p := New(lexer.New("let = 1;\nlet y = 2;\nlet z 3;\nlet w = 4;"))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 2 {
+		t.Fatalf("expected 2 errors, got=%d", len(p.Errors()))
+	}
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("expected 2 valid statements, got=%d", len(program.Statements))
+	}
+
+	if program.String() != "let y = 2;let w = 4;" {
+		t.Errorf("program wrong. got=%q", program.String())
+	}
+}
+
+func TestCrawlerExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`open("http://example.com")`, `open("http://example.com");`},
+		{`open(url, {method: "POST", form: f})`, `open(url, {"method": "POST", "form": f});`},
+		{`url | open({method: "POST"})`, `(url | open({"method": "POST"}));`},
+		{`extract(doc, @"div.item")`, `extract(doc, @"div.item");`},
+		{`extract(@sel)`, `extract(@sel);`},
+		{`collect(doc, @"h2", @"a")`, `collect(doc, @"h2", @"a");`},
+		{`collect(@"h2", @("a" + suffix))`, `collect(@"h2", @("a" + suffix));`},
+		{`collect({title: @"h2"}, @"a")`, `collect({"title": @"h2"}, @"a");`},
+		{`@prefix + "a"`, `(@prefix + "a");`},
+		{`extract(doc, @xpath:"//table[@id='x']/tr")`, `extract(doc, @xpath:"//table[@id='x']/tr");`},
+		{`@css:sel`, `@css:sel;`},
+		{`@xpath`, `@xpath;`},
+		{
+			`open(url) | extract(@"div.item") | collect(@"h2", @"a")`,
+			`((open(url) | extract(@"div.item")) | collect(@"h2", @"a"));`,
+		},
+		{`let items = url | open() | extract(@"li")`, `let items = ((url | open()) | extract(@"li"));`},
+		{`a || b | f`, `((a || b) | f);`},
+		{`x | format("%s") | print`, `((x | format("%s")) | print);`},
+		{`crawl(seeds, {depth: 3, follow: @"a.next"}, handle)`, `crawl(seeds, {"depth": 3, "follow": @"a.next"}, handle);`},
+		{`crawl("http://a.com", handle)`, `crawl("http://a.com", handle);`},
+		{`seeds | crawl({depth: 1}, handle)`, `(seeds | crawl({"depth": 1}, handle));`},
+		{`seeds | crawl(handle)`, `(seeds | crawl(handle));`},
+	}
+
+	for _, tt := range tests {
+		program := parseProgram(t, tt.input)
+
+		actual := program.String()
+		if actual != tt.expected {
+			t.Errorf("input %q: expected=%q, got=%q", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestCrawlerExpressionNodes(t *testing.T) {
+	program := parseProgram(t, `open(u) | extract(@"div.item") | collect(page, @"h2", @"a")`)
+
+	pipe, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.PipeExpression)
+	if !ok {
+		t.Fatalf("expression is not *ast.PipeExpression")
+	}
+
+	collect, ok := pipe.Right.(*ast.CollectExpression)
+	if !ok {
+		t.Fatalf("pipe.Right is not *ast.CollectExpression. got=%T", pipe.Right)
+	}
+	if collect.Source == nil || collect.Source.String() != "page" || len(collect.Selectors) != 2 {
+		t.Errorf("collect parsed wrong: %s", collect.String())
+	}
+
+	inner, ok := pipe.Left.(*ast.PipeExpression)
+	if !ok {
+		t.Fatalf("pipe.Left is not *ast.PipeExpression. got=%T", pipe.Left)
+	}
+
+	if _, ok := inner.Left.(*ast.OpenExpression); !ok {
+		t.Errorf("inner.Left is not *ast.OpenExpression. got=%T", inner.Left)
+	}
+
+	extract, ok := inner.Right.(*ast.ExtractExpression)
+	if !ok {
+		t.Fatalf("inner.Right is not *ast.ExtractExpression. got=%T", inner.Right)
+	}
+	if extract.Source != nil {
+		t.Errorf("extract in pipeline should have no source. got=%s", extract.Source)
+	}
+
+	at, ok := extract.Selector.(*ast.AtExpression)
+	if !ok {
+		t.Fatalf("selector is not *ast.AtExpression. got=%T", extract.Selector)
+	}
+	if lit, ok := at.Selector.(*ast.StringLiteral); !ok || lit.Value != "div.item" {
+		t.Errorf("selector literal wrong. got=%s", at.Selector)
+	}
+}
+
+func TestOpenExpressionNodes(t *testing.T) {
+	tests := []struct {
+		input   string
+		url     string // 空表示没有 URL
+		options []string
+	}{
+		{`open(url, {method: "POST", form: f})`, "url", []string{`"method": "POST"`, `"form": f`}},
+		{`url | open({form: f, method: "POST"})`, "", []string{`"form": f`, `"method": "POST"`}},
+		{`open(url, opts)`, "url", nil},
+	}
+
+	for _, tt := range tests {
+		program := parseProgram(t, tt.input)
+		exp := program.Statements[0].(*ast.ExpressionStatement).Expression
+		if pipe, ok := exp.(*ast.PipeExpression); ok {
+			exp = pipe.Right
+		}
+		open, ok := exp.(*ast.OpenExpression)
+		if !ok {
+			t.Fatalf("input %q: expression is not *ast.OpenExpression. got=%T", tt.input, exp)
+		}
+
+		url := ""
+		if open.URL != nil {
+			url = open.URL.String()
+		}
+		if url != tt.url {
+			t.Errorf("input %q: URL wrong. got=%q, want=%q", tt.input, url, tt.url)
+		}
+
+		obj, ok := open.Options.(*ast.ObjectLiteral)
+		if tt.options == nil {
+			if ok || open.Options == nil {
+				t.Errorf("input %q: options should be a non-literal expression. got=%v", tt.input, open.Options)
+			}
+			continue
+		}
+		if !ok {
+			t.Fatalf("input %q: options is not *ast.ObjectLiteral. got=%T", tt.input, open.Options)
+		}
+		if len(obj.Keys) != len(tt.options) {
+			t.Fatalf("input %q: wrong number of options. got=%d", tt.input, len(obj.Keys))
+		}
+		for i, key := range obj.Keys {
+			if got := key.String() + ": " + obj.Pairs[key].String(); got != tt.options[i] {
+				t.Errorf("input %q: option %d wrong. got=%q, want=%q", tt.input, i, got, tt.options[i])
+			}
+		}
+	}
+}
+
+func TestCrawlerSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		input   string
+		message string
+	}{
+		{`open`, "expected '(' after open, got end of input instead"},
+		{`open(a, b, c)`, "open expects at most 2 arguments, got 3"},
+		{`extract()`, "extract expects 1 or 2 arguments, got 0"},
+		{`collect(doc)`, "collect expects at least one selector"},
+		{`crawl()`, "crawl expects 1 to 3 arguments, got 0"},
+		{`crawl(a, b, c, d)`, "crawl expects 1 to 3 arguments, got 4"},
+		{`x | 5`, "invalid pipeline stage 5"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errs := p.Errors()
+		if len(errs) == 0 {
+			t.Errorf("input %q: expected syntax error", tt.input)
+			continue
+		}
+
+		if errs[0].Message != tt.message {
+			t.Errorf("input %q: message wrong. got=%q, want=%q", tt.input, errs[0].Message, tt.message)
+		}
+	}
+}
+
+func TestSelectorSuffixErrors(t *testing.T) {
+	tests := []struct {
+		input   string
+		message string
+	}{
+		{`extract(doc, @"a::link")`, "unknown pseudo-element ::link"},
+		{`extract(doc, @"a::attr()")`, "::attr requires an attribute name"},
+		{`let x = 1; @"p::text(1)"`, "::text does not take arguments"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errs := p.Errors()
+		if len(errs) != 1 {
+			t.Errorf("input %q: expected 1 error, got %d", tt.input, len(errs))
+			continue
+		}
+
+		if errs[0].Type != errors.SelectorError {
+			t.Errorf("input %q: error type wrong. got=%s", tt.input, errs[0].TypeString())
+		}
+		if !strings.Contains(errs[0].Message, tt.message) {
+			t.Errorf("input %q: message wrong. got=%q, want to contain %q", tt.input, errs[0].Message, tt.message)
+		}
+	}
+
+	for _, input := range []string{`@"a::attr(href)"`, `@"h1::text"`, `@xpath:"//p::own-text"`, `@"div::html"`, `@sel`,
+		`@xpath:"//p/child::text()"`, `@xpath:"//p/self::node()"`, `@xpath:"descendant::text()"`} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Errorf("input %q: unexpected error %v", input, p.Errors()[0])
+		}
+	}
+}
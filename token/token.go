@@ -92,6 +92,7 @@ const (
     OPEN    = "OPEN"
     EXTRACT = "EXTRACT"
     COLLECT = "COLLECT"
+    CRAWL   = "CRAWL"
     KEYS    = "KEYS"
     VALUES  = "VALUES"
     LENGTH  = "LENGTH"
@@ -124,6 +125,7 @@ var keywords = map[string]TokenType{
     "open":     OPEN,
     "extract":  EXTRACT,
     "collect":  COLLECT,
+    "crawl":    CRAWL,
     "keys":     KEYS,
     "values":   VALUES,
     "length":   LENGTH,